@@ -5,6 +5,7 @@ import (
 	_ "embed"
 	"go-auth-admin/internal/cmd"
 	"go-auth-admin/internal/config"
+	"os"
 
 	"go-auth-admin/internal/config/consts"
 	xlog "go-auth-admin/internal/util/utillog"
@@ -32,4 +33,8 @@ func main() {
 	x := cmd.Command{}
 
 	x.Exec()
+
+	if x.ExitCode != 0 {
+		os.Exit(x.ExitCode)
+	}
 }
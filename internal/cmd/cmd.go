@@ -2,27 +2,41 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"go-auth-admin/internal/config"
+	"go-auth-admin/internal/config/consts"
+	"go-auth-admin/internal/i18n"
+	"go-auth-admin/internal/messenger"
 	"go-auth-admin/internal/middleware"
+	"go-auth-admin/internal/policy"
 	"go-auth-admin/internal/service"
+	"io"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
 	"time"
 
 	xlog "go-auth-admin/internal/util/utillog"
+	"go-auth-admin/internal/util/utiltls"
 
 	"go-auth-admin/internal/router"
 	webfs "go-auth-admin/web"
 
 	"github.com/labstack/echo/v4"
 	elog "github.com/labstack/gommon/log"
+	"golang.org/x/crypto/acme/autocert"
 )
 
 type Command struct {
 	AppService service.AppService
 	WebDriver  *echo.Echo
+	SysDriver  *echo.Echo // sys api on own listener, nil if served by WebDriver
+	ExitCode   int
 	stop       context.CancelFunc
 }
 
@@ -34,13 +48,29 @@ func (x *Command) Exec() {
 
 	defer xlog.Sync()
 
+	if strings.Join(config.CmdLine.Args, " ") == "config validate" {
+		if err := ConfigValidate(os.Stdout); err != nil {
+			xlog.Error("%v", err)
+			x.ExitCode = 1
+		}
+		return
+	}
+
+	if strings.Join(config.CmdLine.Args, " ") == "policy check" {
+		if err := PolicyCheck(os.Stdout); err != nil {
+			xlog.Error("%v", err)
+			x.ExitCode = 1
+		}
+		return
+	}
+
 	x.AppService = service.MustNewAppServiceProd()
 
 	x.WebDriver = echo.New()
 	x.WebDriver.Logger.SetLevel(elog.INFO) // has "file":"cmd.go","line":"85"
 
-	middleware.Init(x.WebDriver, x.AppService) // 1
-	router.Init(x.WebDriver, x.AppService)     // 2
+	middleware.Init(x.WebDriver, x.AppService)           // 1
+	x.SysDriver = router.Init(x.WebDriver, x.AppService) // 2
 
 	middleware.AssetsContentsMiddleware(x.WebDriver, x.AppService,
 		webfs.MustAuthAdminAssetsFS(),
@@ -49,14 +79,141 @@ func (x *Command) Exec() {
 	defer func() {
 		xlog.Info("closing repository")
 		_ = x.AppService.Repository().Close()
+		_ = x.AppService.Store().Close()
 		xlog.Info("bye")
 	}()
 
+	x.mustCheckPolicy()
+
+	x.AppService.ConfigSource().OnReload(x.checkPolicyOnReload)
+
 	x.startWithGracefulShutdown()
 
 	time.Sleep(400 * time.Millisecond)
 }
 
+// mustCheckPolicy every admin route must have policy entry
+func (x *Command) mustCheckPolicy() {
+
+	err := x.AppService.Policy().Check(x.WebDriver.Routes(), consts.PathAuthAdmin)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// checkPolicyOnReload rejects config whose policy misses any admin route
+func (x *Command) checkPolicyOnReload(next *config.AppConfig) (func(), error) {
+
+	appPolicy, err := policy.Load(next.ConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, appPolicy.Check(x.WebDriver.Routes(), consts.PathAuthAdmin)
+}
+
+// reloadOnHangup reloads config on each SIGHUP until ctx is done
+func (x *Command) reloadOnHangup(ctx context.Context) {
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			xlog.Info("hangup signal, reloading config")
+			if err := x.AppService.ConfigSource().Reload(); err != nil {
+				xlog.Error("%v", err) // current config stays
+			}
+		}
+	}
+}
+
+// ConfigValidate prints every config error with its JSON path, no db connection is made
+func ConfigValidate(w io.Writer) error {
+
+	appConfig, err := (&config.AppConfigSource{}).Validate()
+
+	errs := config.ValidationError{}
+
+	if err != nil && !errors.As(err, &errs) {
+		return err // files or env can not be read
+	}
+
+	if serviceErrs := (config.ValidationError{}); errors.As(service.CheckConfig(appConfig), &serviceErrs) {
+		errs = append(errs, serviceErrs...)
+	}
+
+	if len(errs) == 0 { // files of valid config are parsed as on start
+		if _, err = i18n.LoadAppLang(appConfig); err != nil {
+			errs.Add("lang", "%v", err)
+		}
+		if _, err = messenger.LoadAppMessenger(appConfig); err != nil {
+			errs.Add("messenger", "%v", err)
+		}
+		if _, err = policy.Load(appConfig.ConfigPath); err != nil {
+			errs.Add("policy", "%v", err)
+		}
+	}
+
+	for _, v := range errs {
+		_, _ = fmt.Fprintln(w, v.Error())
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("error config is invalid: %d errors", len(errs))
+	}
+
+	_, _ = fmt.Fprintln(w, "config is valid")
+
+	return nil
+}
+
+// PolicyCheck prints roles which can reach each admin route, error if any route has no policy,
+// no db connection is made
+func PolicyCheck(w io.Writer) error {
+
+	appConfig, err := (&config.AppConfigSource{}).Validate()
+	if err != nil {
+		return err
+	}
+
+	appPolicy, err := policy.Load(appConfig.ConfigPath)
+	if err != nil {
+		return err
+	}
+
+	e := echo.New()
+
+	router.InitPolicyRoutes(e)
+	middleware.AssetsContentsMiddleware(e, nil, webfs.MustAuthAdminAssetsFS())
+
+	routes := e.Routes()
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	_, _ = fmt.Fprintln(tw, "METHOD\tPATH\tROLES")
+
+	for _, r := range policy.Routes(routes, consts.PathAuthAdmin) {
+
+		roles := "MISSING"
+		if rule := appPolicy.Rule(r.Method, r.Path); rule != nil {
+			roles = rule.String()
+		}
+
+		_, _ = fmt.Fprintf(tw, "%v\t%v\t%v\n", r.Method, r.Path, roles)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	return appPolicy.Check(routes, consts.PathAuthAdmin)
+}
+
 func applyServer(s *http.Server, c *config.AppConfig) {
 
 	s.ReadTimeout = time.Duration(c.HTTPServer.ReadTimeout) * time.Second
@@ -66,11 +223,55 @@ func applyServer(s *http.Server, c *config.AppConfig) {
 
 }
 
+// tlsStart starts TLS listener by ACME autocert or with certificates of cert dir
+func (x *Command) tlsStart(cfg config.AppConfigHTTPServer) (start func() error, err error) {
+
+	webDriver := x.WebDriver
+
+	if cfg.AutoTLS {
+		webDriver.AutoTLSManager.Cache = autocert.DirCache(cfg.AutoTLSCacheDir)
+		webDriver.AutoTLSManager.HostPolicy = autocert.HostWhitelist(cfg.AutoTLSHosts...)
+		webDriver.AutoTLSManager.Email = cfg.AutoTLSEmail
+
+		return func() error { return webDriver.StartAutoTLS(cfg.ListenTLS) }, nil
+	}
+
+	certs, err := utiltls.NewCertReloader(cfg.CertDir)
+	if err != nil {
+		return nil, fmt.Errorf("error on tls certificate: %v", err)
+	}
+
+	webDriver.TLSServer.Addr = cfg.ListenTLS
+	webDriver.TLSServer.TLSConfig = certs.TLSConfig()
+
+	return func() error { return webDriver.StartServer(webDriver.TLSServer) }, nil
+}
+
+// serve runs server until shutdown, unexpected error (e.g. port in use) stops app
+func (x *Command) serve(servers *sync.WaitGroup, name string, listen string, start func() error) {
+
+	xlog.Info("%v starting: %v", name, listen)
+
+	servers.Go(func() {
+		defer xlog.Info("%v exiting", name)
+
+		if err := start(); err != nil {
+			if err != http.ErrServerClosed {
+				xlog.Error("error on %v %v: %v", name, listen, err)
+				x.ExitCode = 1
+				x.stop()
+			} else {
+				xlog.Info("shutting down the %v", name)
+			}
+		}
+	})
+}
+
 func (x *Command) startWithGracefulShutdown() {
 
 	appConfig := x.AppService.Config()
 
-	listen := appConfig.HTTPServer.Listen
+	cfg := appConfig.HTTPServer
 	// Graceful shutdown
 
 	webDriver := x.WebDriver
@@ -79,44 +280,73 @@ func (x *Command) startWithGracefulShutdown() {
 	defer stop()
 	x.stop = stop
 
-	// Start server
+	// Start servers
 
-	{
-		applyServer(webDriver.Server, appConfig)
-		applyServer(webDriver.TLSServer, appConfig)
+	applyServer(webDriver.Server, appConfig)
+	applyServer(webDriver.TLSServer, appConfig)
 
-		xlog.Info("server starting: %v", listen)
+	var tlsStart func() error
 
-		go func() {
+	if cfg.ListenTLS != "" {
+		var err error
+		if tlsStart, err = x.tlsStart(cfg); err != nil {
+			xlog.Error("%v", err)
+			x.ExitCode = 1
+			return
+		}
+	}
 
-			defer func() {
-				xlog.Info("server exiting")
+	var servers sync.WaitGroup
 
-				if r := recover(); r != nil {
-					// Log or handle the panic
-					panic(fmt.Errorf("error panic: %v", r))
-				}
-			}()
+	if cfg.Listen != "" {
+		x.serve(&servers, "server", cfg.Listen, func() error { return webDriver.Start(cfg.Listen) })
+	}
 
-			if err := webDriver.Start(listen); err != nil {
-				if err != http.ErrServerClosed {
-					xlog.Error("%v", err)
-				} else {
-					xlog.Info("shutting down the server")
-				}
-			}
+	if tlsStart != nil {
+		x.serve(&servers, "tls server", cfg.ListenTLS, tlsStart)
+	}
 
-		}()
+	if x.SysDriver != nil {
+		applyServer(x.SysDriver.Server, appConfig)
+		x.serve(&servers, "sys server", cfg.ListenSys, func() error { return x.SysDriver.Start(cfg.ListenSys) })
+	}
+
+	// Start outbox delivery
+
+	var outbox sync.WaitGroup
+
+	if appConfig.Outbox.Enabled {
+		outboxCtx := ctx
+		outbox.Go(func() { x.AppService.AuthAdmin().Outbox().Run(outboxCtx) })
+	}
 
+	// Reload config on SIGHUP, changed files
+
+	go x.reloadOnHangup(ctx)
+
+	if appConfig.Reload.Watch {
+		go x.AppService.ConfigSource().Watch(ctx, time.Duration(appConfig.Reload.Interval)*time.Second)
 	}
 
+	// Vault keys changed by other replica
+
+	go x.AppService.Vault().WatchKeys(ctx)
+
 	// Wait for interrupt signal to gracefully shutdown the server with a timeout of 10 seconds.
 	<-ctx.Done()
 	xlog.Info("interrupt signal")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	xlog.Info("shutdown web driver")
-	if err := webDriver.Shutdown(ctx); err != nil {
+	if err := webDriver.Shutdown(ctx); err != nil { // plain and tls
 		xlog.Error("error on shutdown server: %v", err)
 	}
+	if x.SysDriver != nil {
+		if err := x.SysDriver.Shutdown(ctx); err != nil {
+			xlog.Error("error on shutdown sys server: %v", err)
+		}
+	}
+	servers.Wait()
+	xlog.Info("shutdown outbox")
+	outbox.Wait() // current deliveries finish before repository is closed
 }
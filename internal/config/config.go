@@ -1,16 +1,23 @@
 package config
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"go-auth-admin/internal/config/consts"
 	"go-auth-admin/internal/util/utilconfig"
 	xlog "go-auth-admin/internal/util/utillog"
+	"net/http"
 	"os"
+	"path/filepath"
+	"reflect"
 	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 var (
@@ -46,6 +53,8 @@ type CmdLineConfig struct {
 	ListenSys string
 
 	DumpConfig bool
+
+	Args []string // command, e.g. "policy check"
 }
 
 const (
@@ -80,6 +89,8 @@ func ReadFlags() {
 
 	flag.Parse() // dont use from init()
 
+	CmdLine.Args = flag.Args()
+
 	dumpVersionAndExitIf()
 }
 
@@ -91,65 +102,117 @@ type envReader struct {
 func NewEnvReader() envReader {
 	return envReader{prefix: "app_"}
 }
-func (x *envReader) String(p *string, name string, cmdValue *string) {
+
+// lookup env value, or content of file named by env <name>_FILE (docker, k8s secrets),
+// setting both is an error
+func (x *envReader) lookup(name string) (value string, source string, ok bool) {
+
 	envName := strings.ToUpper(x.prefix + name) // *nix case-sensitive
+	fileEnvName := envName + "_FILE"
+
+	envValue := os.Getenv(envName)
+	filePath := os.Getenv(fileEnvName)
+
+	switch {
+	case envValue != "" && filePath != "":
+		x.envError = fmt.Errorf("error both %v and %v are set", envName, fileEnvName)
+		return "", "", false
+	case envValue != "":
+		return envValue, envName, true
+	case filePath != "":
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			x.envError = fmt.Errorf("error on %v: %v", fileEnvName, err)
+			return "", "", false
+		}
+		value = strings.TrimRight(string(data), "\r\n") // editors add final newline
+		if value == "" {
+			x.envError = fmt.Errorf("error on %v: file is empty: %v", fileEnvName, filePath)
+			return "", "", false
+		}
+		return value, fileEnvName + " = " + filePath, true
+	}
+
+	return "", "", false
+}
+
+func (x *envReader) String(p *string, name string, cmdValue *string) {
+	x.readString(p, name, cmdValue, false)
+}
+
+// Secret is String which never logs value
+func (x *envReader) Secret(p *string, name string, cmdValue *string) {
+	x.readString(p, name, cmdValue, true)
+}
+
+func (x *envReader) readString(p *string, name string, cmdValue *string, secret bool) {
+
+	logValue := func(v string) string {
+		if secret {
+			return redactedValue
+		}
+		return v
+	}
+
 	if cmdValue != nil && *cmdValue != "" {
-		xlog.Info("reading %q value from cmd: %v", name, *cmdValue)
+		xlog.Info("reading %q value from cmd: %v", name, logValue(*cmdValue))
 		*p = *cmdValue
 		return
 	}
 
-	if envName != "" {
-		envValue := os.Getenv(envName)
-		if envValue != "" {
-			xlog.Info("reading %q value from env: %v = %v", name, envName, envValue)
-			*p = envValue
-			return
-		}
+	if envValue, source, ok := x.lookup(name); ok {
+		xlog.Info("reading %q value from env: %v = %v", name, source, logValue(envValue))
+		*p = envValue
+		return
 	}
 
 }
 
 func (x *envReader) Bool(p *bool, name string, cmdValue *bool) {
 
-	envName := strings.ToUpper(x.prefix + name) // *nix case-sensitive
-
 	if cmdValue != nil && *cmdValue {
 		xlog.Info("reading %q value from cmd: %v", name, *cmdValue)
 		*p = *cmdValue
 		return
 	}
-	if envName != "" {
-		envValue := os.Getenv(envName)
-		if envValue != "" {
-			xlog.Info("reading %q value from env: %v = %v", name, envName, envValue)
-			*p = envValue == "1" || envValue == "true"
-			return
-		}
+
+	if envValue, source, ok := x.lookup(name); ok {
+		xlog.Info("reading %q value from env: %v = %v", name, source, envValue)
+		*p = envValue == "1" || envValue == "true"
+		return
 	}
 
 }
 
 func (x *envReader) Int(p *int, name string, cmdValue *int) {
 
-	envName := strings.ToUpper(x.prefix + name) // *nix case-sensitive
-
 	if cmdValue != nil && *cmdValue != 0 {
 		xlog.Info("reading %q value from cmd: %v", name, *cmdValue)
 		*p = *cmdValue
 		return
 	}
-	if envName != "" {
-		envValue := os.Getenv(envName)
-		if envValue != "" {
-			xlog.Info("reading %q value from env: %v = %v", name, envName, envValue)
 
-			if v, err := strconv.Atoi(envValue); err == nil {
-				*p = v
-			} else {
-				x.envError = err
-			}
+	if envValue, source, ok := x.lookup(name); ok {
+		xlog.Info("reading %q value from env: %v = %v", name, source, envValue)
 
+		if v, err := strconv.Atoi(envValue); err == nil {
+			*p = v
+		} else {
+			x.envError = err
+		}
+
+	}
+
+}
+
+// JSON reads secret JSON value, e.g. vault keys array, value is never logged
+func (x *envReader) JSON(p any, name string) {
+
+	if envValue, source, ok := x.lookup(name); ok {
+		xlog.Info("reading %q value from env: %v", name, source)
+
+		if err := json.Unmarshal([]byte(envValue), p); err != nil {
+			x.envError = fmt.Errorf("error on %q value: %v", name, err)
 		}
 	}
 
@@ -162,7 +225,7 @@ type Database struct {
 	Name      string `json:"name"`
 	Schema    string `json:"schema"`
 	User      string `json:"user"`
-	Password  string `json:"password"`
+	Password  string `json:"password" secret:"true"`
 	MaxOpen   int    `json:"max_open"`
 	MaxIdle   int    `json:"max_idle"`
 	IdleTime  int    `json:"idle_time"`
@@ -178,18 +241,63 @@ type Database struct {
 type AppConfigMessenger struct {
 	ServiceURL string `json:"service_url"`
 	Stdout     bool   `json:"stdout"`
+
+	// Routes message kind to transport: service smtp file webhook, "*" is any kind, default service
+	Routes map[string]string `json:"routes"`
+
+	SMTP    AppConfigSMTP    `json:"smtp"`
+	File    AppConfigFile    `json:"file"`
+	Webhook AppConfigWebhook `json:"webhook"`
+}
+
+type AppConfigSMTP struct {
+	Host     string `json:"host"`
+	Port     string `json:"port"` // default 587
+	User     string `json:"user"` // no auth if empty
+	Password string `json:"password" secret:"true"`
+	From     string `json:"from"`
+	StartTLS bool   `json:"starttls"` // required, fails if server has no STARTTLS
+	Timeout  int    `json:"timeout"`  // seconds of dial and whole session
+}
+
+// AppConfigFile maildir to drop messages, for development and tests
+type AppConfigFile struct {
+	Dir string `json:"dir"`
+}
+
+// AppConfigWebhook JSON of rendered message is posted to URL
+type AppConfigWebhook struct {
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers" secret:"true"` // e.g. Authorization
+}
+
+// AppConfigOutbox messages are stored before delivery, failed ones are retried with backoff
+type AppConfigOutbox struct {
+	Enabled      bool `json:"enabled"`       // false sends synchronously
+	Workers      int  `json:"workers"`       // parallel deliveries
+	BatchSize    int  `json:"batch_size"`    // messages claimed per poll
+	PollInterval int  `json:"poll_interval"` // seconds
+	MaxAttempts  int  `json:"max_attempts"`  // then message is failed (dead letter)
+	Backoff      int  `json:"backoff"`       // seconds, doubled on each attempt
+	BackoffMax   int  `json:"backoff_max"`   // seconds
+	Lease        int  `json:"lease"`         // seconds, claimed message is retried after if worker dies
 }
 type AppConfigVaultKey struct {
 	ID      string `json:"id"`
-	AuthKey string `json:"auth_key"` // for user auth (used by cluster apps)
-	OtpKey  string `json:"otp_key"`
-	HashKey string `json:"sign_key"` // for user signup (used by this app only)
+	AuthKey string `json:"auth_key" secret:"true"` // for user auth (used by cluster apps)
+	OtpKey  string `json:"otp_key" secret:"true"`
+	HashKey string `json:"sign_key" secret:"true"` // for user signup (used by this app only)
 }
 
 func (x AppConfigVaultKey) IsEmpty() bool {
 	return x.ID == "" || x.AuthKey == "" || x.OtpKey == "" || x.HashKey == ""
 }
 
+// HasAuthKey key can sign and check auth tokens, db keys of auth app have auth key only (no otp and sign keys)
+func (x AppConfigVaultKey) HasAuthKey() bool {
+	return x.ID != "" && x.AuthKey != ""
+}
+
 type AppConfigVault struct {
 	Keys []AppConfigVaultKey `json:"keys"` // keychain
 }
@@ -202,11 +310,28 @@ type AppConfigIdentity struct {
 	TokenMaxAge       int    `json:"token_max_age"`     // minutes int
 	AuthTokenIssuer   string `json:"auth_token_issuer"` // default "auth"
 	AuthTokenAudience string `json:"auth_token_audience"`
+
+	InviteMaxAge int    `json:"invite_max_age"` // seconds
+	InviteURL    string `json:"invite_url"`     // signup link, {token} is replaced
+
+	ImpersonateMaxAge int `json:"impersonate_max_age"` // seconds, impersonation token is not rotated
 }
 
-func (x AppConfigIdentity) Validate() error {
+// AppConfigApproval four-eyes mode, listed action types wait for approval of a second admin
+type AppConfigApproval struct {
+	Actions []string `json:"actions"` // e.g. account.grant_admin account.delete
+	MaxAge  int      `json:"max_age"` // seconds, pending request expires after
+}
 
-	return nil
+// AppConfigNotify security event notifications to affected account
+type AppConfigNotify struct {
+	Disabled []string `json:"disabled"` // opt-out events: password_reset roles_changed account_locked mfa_enrolled
+}
+
+// AppConfigReload config files are polled, changed files are reloaded like SIGHUP does
+type AppConfigReload struct {
+	Watch    bool `json:"watch"`
+	Interval int  `json:"interval"` // seconds
 }
 
 type AppConfigLang struct {
@@ -232,10 +357,29 @@ type AppConfigHTTPTransport struct {
 	IdleConnTimeout     int `json:"idle_conn_timeout,omitempty"`
 	MaxConnsPerHost     int `json:"max_conns_per_host,omitempty"`
 }
+
+// AppConfigRateLimit requests per second and burst, 0 rate no limit
+type AppConfigRateLimit struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+// AppConfigCORS cross-origin access to /auth-admin/api, empty allow_origins disables
+type AppConfigCORS struct {
+	AllowOrigins     []string `json:"allow_origins"` // exact or https://*.example.com, * any origin without credentials
+	AllowMethods     []string `json:"allow_methods"`
+	AllowHeaders     []string `json:"allow_headers"`
+	ExposeHeaders    []string `json:"expose_headers"` // readable by SPA, e.g. X-CSRF-Token
+	AllowCredentials bool     `json:"allow_credentials"`
+	MaxAge           int      `json:"max_age"` // seconds of preflight cache, 0 not sent
+}
+
 type AppConfigHTTPServer struct {
 	AccessLog bool `json:"access_log"`
 
-	RateLimit     float64 `json:"rate_limit"`
+	CORS AppConfigCORS `json:"cors"`
+
+	RateLimit     float64 `json:"rate_limit"` // requests per second per user or ip on /auth-admin/api, 0 no limit
 	RateBurst     int     `json:"rate_burst"`
 	Listen        string  `json:"listen"`
 	ListenTLS     string  `json:"listen_tls"`
@@ -243,7 +387,13 @@ type AppConfigHTTPServer struct {
 	RedirectHTTPS bool    `json:"redirect_https"`
 	RedirectWWW   bool    `json:"redirect_www"`
 
-	CertDir string `json:"cert_dir"`
+	CertDir string `json:"cert_dir"` // tls.crt, tls.key, reloaded on change
+
+	RateLimitRoutes map[string]AppConfigRateLimit `json:"rate_limit_routes"` // "METHOD /path" of route, instead of rate_limit
+
+	AutoTLSHosts    []string `json:"auto_tls_hosts"`     // ACME certificates are issued for these hosts only
+	AutoTLSCacheDir string   `json:"auto_tls_cache_dir"` // issued certificates and account key
+	AutoTLSEmail    string   `json:"auto_tls_email"`     // ACME account contact, optional
 
 	ReadTimeout       int `json:"read_timeout,omitempty"`        // 5 to 30 seconds
 	WriteTimeout      int `json:"write_timeout,omitempty"`       // 10 to 30 seconds, WriteTimeout > ReadTimeout
@@ -251,7 +401,10 @@ type AppConfigHTTPServer struct {
 	ReadHeaderTimeout int `json:"read_header_timeout,omitempty"` // default get from ReadTimeout
 
 	SysMetrics bool   `json:"sys_metrics"` //
-	SysAPIKey  string `json:"sys_api_key"`
+	SysReload  bool   `json:"sys_reload"`  // POST /sys/api/config/reload
+	SysInvites bool   `json:"sys_invites"` // POST /sys/api/invites/redeem, called by auth app on signup
+	SysNotify  bool   `json:"sys_notify"`  // POST /sys/api/notify, called by auth app on mfa enrollment
+	SysAPIKey  string `json:"sys_api_key" secret:"true"`
 	ListenSys  string `json:"listen_sys"`
 }
 type AppConfig struct {
@@ -265,13 +418,19 @@ type AppConfig struct {
 
 	Identity AppConfigIdentity `json:"identity"`
 
+	Approval AppConfigApproval `json:"approval"`
+
 	DB    Database `json:"database"`
 	Redis Database `json:"redis"`
 
 	Messenger AppConfigMessenger `json:"messenger"`
+	Outbox    AppConfigOutbox    `json:"outbox"`
+	Notify    AppConfigNotify    `json:"notify"`
 
 	Lang AppConfigLang `json:"lang"`
 
+	Reload AppConfigReload `json:"reload"`
+
 	Assets AppConfigAssets `json:"assets"`
 
 	HTTPTransport AppConfigHTTPTransport `json:"http_transport"`
@@ -279,6 +438,57 @@ type AppConfig struct {
 	HTTPServer AppConfigHTTPServer `json:"http_server"`
 }
 
+// redactedValue replaces secret values in dump and logs
+const redactedValue = "******"
+
+// Redacted deep copy of config, non-empty fields tagged secret are replaced
+func (x *AppConfig) Redacted() *AppConfig {
+
+	res := redact(reflect.ValueOf(*x), false).Interface().(AppConfig)
+
+	return &res
+}
+
+// redact copies v, strings under secret field are replaced, also map values and slice items
+func redact(v reflect.Value, secret bool) reflect.Value {
+
+	res := reflect.New(v.Type()).Elem()
+
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := range v.NumField() {
+			f := v.Type().Field(i)
+			res.Field(i).Set(redact(v.Field(i), secret || f.Tag.Get("secret") == "true"))
+		}
+	case reflect.Slice:
+		if v.IsNil() {
+			return res
+		}
+		res.Set(reflect.MakeSlice(v.Type(), v.Len(), v.Len()))
+		for i := range v.Len() {
+			res.Index(i).Set(redact(v.Index(i), secret))
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			return res
+		}
+		res.Set(reflect.MakeMapWithSize(v.Type(), v.Len()))
+		for iter := v.MapRange(); iter.Next(); {
+			res.SetMapIndex(iter.Key(), redact(iter.Value(), secret))
+		}
+	case reflect.String:
+		if secret && v.String() != "" {
+			res.SetString(redactedValue)
+		} else {
+			res.Set(v)
+		}
+	default:
+		res.Set(v)
+	}
+
+	return res
+}
+
 func NewAppConfig() *AppConfig {
 
 	res := &AppConfig{
@@ -299,10 +509,38 @@ func NewAppConfig() *AppConfig {
 			TokenMaxAge: 2592000, //  30day*24hour*60min*60sec ~ 30 days
 
 			AuthTokenIssuer: "auth",
+
+			InviteMaxAge: 604800, // 7day*24hour*60min*60sec ~ 7 days
+			InviteURL:    consts.PathAuthSignup + "?token={token}",
+
+			ImpersonateMaxAge: 900, // 15min*60sec ~ 15 min
+		},
+		Approval: AppConfigApproval{
+			Actions: []string{},
+			MaxAge:  86400, // 24hour*60min*60sec ~ 1 day
 		},
 		Messenger: AppConfigMessenger{
 
 			ServiceURL: "http://127.0.0.1:30780/sys/api/messenger/{code}", // prefix of url
+			Routes:     map[string]string{},
+			SMTP: AppConfigSMTP{
+				Port:     "587",
+				StartTLS: true,
+				Timeout:  30,
+			},
+		},
+		Notify: AppConfigNotify{
+			Disabled: []string{},
+		},
+		Outbox: AppConfigOutbox{
+			Enabled:      true,
+			Workers:      4,
+			BatchSize:    20,
+			PollInterval: 5,
+			MaxAttempts:  8,
+			Backoff:      10,
+			BackoffMax:   3600, // 60min*60sec ~ 1 hour
+			Lease:        60,
 		},
 		DB: Database{
 			Dialect:   "postgres",
@@ -317,11 +555,12 @@ func NewAppConfig() *AppConfig {
 			Migration: true,
 		},
 		Redis: Database{
+			Dialect:  "", // memory of this instance, "redis" shared by replicas
 			Host:     "127.0.0.1",
 			Port:     "6379",
-			Name:     "redis",
-			User:     "redis",
-			Password: "redis",
+			Name:     "0", // db number
+			User:     "",
+			Password: "",
 		},
 
 		AppConfigMod: AppConfigMod{
@@ -332,6 +571,11 @@ func NewAppConfig() *AppConfig {
 			Debug:      false,
 		},
 
+		Reload: AppConfigReload{
+			Watch:    false,
+			Interval: 10,
+		},
+
 		Assets: AppConfigAssets{
 			GlobalVersion:   "v1",
 			AssetsPublicURL: "",
@@ -347,11 +591,36 @@ func NewAppConfig() *AppConfig {
 			RateLimit: 0,
 			RateBurst: 0,
 
+			// expensive, send messages or hash passwords
+			RateLimitRoutes: map[string]AppConfigRateLimit{
+				"POST " + consts.PathAuthAdminAccountsEntityPasswordAPI:         {Rate: 0.2, Burst: 5},
+				"POST " + consts.PathAuthAdminAccountsEntityPasswordGenerateAPI: {Rate: 0.2, Burst: 5},
+				"POST " + consts.PathAuthAdminAccountsEntityVerifyPasscodeAPI:   {Rate: 0.2, Burst: 5},
+				"POST " + consts.PathAuthAdminInvitesEntityResendAPI:            {Rate: 0.2, Burst: 5},
+			},
+
+			CORS: AppConfigCORS{
+				AllowOrigins: []string{},
+				AllowMethods: []string{
+					http.MethodGet, http.MethodHead, http.MethodPost,
+					http.MethodPut, http.MethodPatch, http.MethodDelete,
+				},
+				AllowHeaders: []string{
+					"Content-Type", "Authorization", "X-CSRF-Token",
+					"X-Sys-Client", "X-Sys-Version", "X-Sys-Device-Id",
+				},
+				ExposeHeaders: []string{"X-CSRF-Token", "Retry-After"},
+				MaxAge:        3600,
+			},
+
 			Listen: ":10280",
 			// ListenTLS: ":10283",
 
 			CertDir: "",
 
+			AutoTLSHosts:    []string{},
+			AutoTLSCacheDir: "autocert",
+
 			SysAPIKey: "",
 		},
 	}
@@ -412,13 +681,32 @@ func (x *AppConfig) readEnvVar() error {
 	reader.String(&x.DB.Port, "db_port", nil)
 	reader.String(&x.DB.Name, "db_name", nil)
 	reader.String(&x.DB.User, "db_user", nil)
-	reader.String(&x.DB.Password, "db_password", nil)
+	reader.Secret(&x.DB.Password, "db_password", nil)
 	reader.Int(&x.DB.MaxOpen, "db_max_open", nil)
 	reader.Int(&x.DB.MaxIdle, "db_max_idle", nil)
 	reader.Int(&x.DB.IdleTime, "db_idle_time", nil)
 	reader.Bool(&x.DB.Migration, "db_migration", nil)
 	reader.Bool(&x.DB.SSL, "db_ssl", nil)
 
+	// Redis configuration
+	reader.String(&x.Redis.Dialect, "redis_dialect", nil)
+	reader.String(&x.Redis.Host, "redis_host", nil)
+	reader.String(&x.Redis.Port, "redis_port", nil)
+	reader.String(&x.Redis.Name, "redis_name", nil)
+	reader.String(&x.Redis.User, "redis_user", nil)
+	reader.Secret(&x.Redis.Password, "redis_password", nil)
+	reader.Bool(&x.Redis.SSL, "redis_ssl", nil)
+
+	// Messenger configuration
+	reader.String(&x.Messenger.ServiceURL, "messenger_service_url", nil)
+	reader.String(&x.Messenger.SMTP.Host, "smtp_host", nil)
+	reader.String(&x.Messenger.SMTP.Port, "smtp_port", nil)
+	reader.String(&x.Messenger.SMTP.User, "smtp_user", nil)
+	reader.Secret(&x.Messenger.SMTP.Password, "smtp_password", nil)
+	reader.String(&x.Messenger.SMTP.From, "smtp_from", nil)
+	reader.String(&x.Messenger.File.Dir, "messenger_file_dir", nil)
+	reader.Bool(&x.Outbox.Enabled, "outbox_enabled", nil)
+
 	// General configuration
 	reader.String(&x.Title, "title", nil)
 
@@ -428,7 +716,13 @@ func (x *AppConfig) readEnvVar() error {
 	reader.String(&x.HTTPServer.ListenTLS, "listen_tls", &CmdLine.ListenTLS)
 	reader.String(&x.HTTPServer.ListenSys, "listen_sys", &CmdLine.ListenSys)
 
-	reader.String(&x.HTTPServer.SysAPIKey, "sys_api_key", &CmdLine.SysAPIKey)
+	reader.Secret(&x.HTTPServer.SysAPIKey, "sys_api_key", &CmdLine.SysAPIKey)
+
+	// CORS origins, JSON array, e.g. ["https://admin.staging.example.com"]
+	reader.JSON(&x.HTTPServer.CORS.AllowOrigins, "cors_allow_origins")
+
+	// Vault configuration, JSON array of keys, replaces config keys
+	reader.JSON(&x.Vault.Keys, "vault_keys")
 
 	if reader.envError != nil {
 		return reader.envError
@@ -452,20 +746,16 @@ func (x *AppConfig) validateEnv() error {
 	return nil
 
 }
-func (x AppConfig) validate() error {
 
-	if x.HTTPServer.Listen == "" && x.HTTPServer.ListenTLS == "" {
-		return fmt.Errorf("socket Listen and ListenTLS are empty")
-	}
-
-	if err := x.Identity.Validate(); err != nil {
-		return fmt.Errorf("error on Identity validate: %v", err)
-	}
-	return nil
-}
+// Reloader checks next config and prepares subsystem for it,
+// commit applies prepared state after all reloaders succeed
+type Reloader func(next *AppConfig) (commit func(), err error)
 
 type AppConfigSource struct {
-	config *AppConfig
+	config atomic.Pointer[AppConfig]
+
+	mu        sync.Mutex // one reload at a time
+	reloaders []Reloader
 }
 
 func MustNewAppConfigSource() *AppConfigSource {
@@ -482,14 +772,15 @@ func MustNewAppConfigSource() *AppConfigSource {
 
 }
 
-func (x *AppConfigSource) Load() error {
+// load reads env, config files and env vars into new config
+func (x *AppConfigSource) load() (*AppConfig, error) {
 
 	res := NewAppConfig()
 
 	{
 		err := res.readEnvName()
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
@@ -498,14 +789,14 @@ func (x *AppConfigSource) Load() error {
 		for i := 0; i < len(res.ConfigPath); i++ {
 
 			dir := res.ConfigPath[i]
-			fileName := fmt.Sprintf("config.%s.json", res.Env)
+			name := fmt.Sprintf("config.%s", res.Env) // .json .yaml .toml
 
 			xlog.Info("loading config from: %v", dir)
 
-			err := utilconfig.LoadConfig(res /*pointer*/, dir, fileName)
+			err := utilconfig.LoadConfigFormats(res /*pointer*/, dir, name)
 
 			if err != nil {
-				return err
+				return nil, err
 			}
 
 		}
@@ -515,7 +806,7 @@ func (x *AppConfigSource) Load() error {
 	{
 		err := res.readEnvVar()
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 	}
@@ -523,24 +814,179 @@ func (x *AppConfigSource) Load() error {
 	{
 		err := res.validate()
 		if err != nil {
-			return err
+			return res, err // for config validate command
 		}
 	}
 
+	return res, nil
+}
+
+// Validate loads config without applying it, config is nil if files or env can not be read,
+// error is ValidationError if config is read but invalid
+func (x *AppConfigSource) Validate() (*AppConfig, error) {
+	return x.load()
+}
+
+func (x *AppConfigSource) Load() error {
+
+	res, err := x.load()
+	if err != nil {
+		return err
+	}
+
 	xlog.Info("config loaded: Name=%v Env=%v Debug=%v ", res.Name, res.Env, res.Debug)
 
-	x.config = res
+	x.config.Store(res)
 
 	if CmdLine.DumpConfig {
-		data, _ := json.MarshalIndent(res, "", " ")
+		data, _ := json.MarshalIndent(res.Redacted(), "", " ")
 		fmt.Println(string(data))
 	}
 
 	return nil
 }
 
+// OnReload adds subsystem rebuilt on each reload, in order of adding
+func (x *AppConfigSource) OnReload(fn Reloader) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.reloaders = append(x.reloaders, fn)
+}
+
+// Reload loads and validates config again, then swaps it if every reloader accepts it,
+// on error current config and subsystems stay unchanged
+func (x *AppConfigSource) Reload() error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	next, err := x.load()
+	if err != nil {
+		return fmt.Errorf("error on config reload: %v", err)
+	}
+
+	commits := make([]func(), 0, len(x.reloaders))
+
+	for _, fn := range x.reloaders {
+		commit, err := fn(next)
+		if err != nil {
+			return fmt.Errorf("error on config reload: %v", err)
+		}
+		if commit != nil {
+			commits = append(commits, commit)
+		}
+	}
+
+	prev := x.config.Swap(next)
+
+	for _, commit := range commits {
+		commit()
+	}
+
+	for _, name := range restartOnlyChanges(prev, next) {
+		xlog.Warn("config %v changed, restart is required to apply it", name)
+	}
+
+	xlog.Info("config reloaded: Name=%v Env=%v Debug=%v ", next.Name, next.Env, next.Debug)
+
+	return nil
+}
+
+// restartOnly settings of listeners, rate limits are rebuilt on reload
+func (x AppConfigHTTPServer) restartOnly() AppConfigHTTPServer {
+	x.RateLimit, x.RateBurst, x.RateLimitRoutes = 0, 0, nil
+	return x
+}
+
+// restartOnlyChanges sections read once on start: listeners, connections, workers
+func restartOnlyChanges(prev *AppConfig, next *AppConfig) []string {
+
+	sections := []struct {
+		name       string
+		prev, next any
+	}{
+		{"database", prev.DB, next.DB},
+		{"redis", prev.Redis, next.Redis},
+		{"outbox", prev.Outbox, next.Outbox},
+		{"reload", prev.Reload, next.Reload},
+		{"http_server", prev.HTTPServer.restartOnly(), next.HTTPServer.restartOnly()},
+		{"http_transport", prev.HTTPTransport, next.HTTPTransport},
+	}
+
+	res := []string{}
+
+	for _, v := range sections {
+		if !reflect.DeepEqual(v.prev, v.next) {
+			res = append(res, v.name)
+		}
+	}
+
+	return res
+}
+
+// Watch polls config dirs every interval and reloads on change of any config file, until ctx is done
+func (x *AppConfigSource) Watch(ctx context.Context, interval time.Duration) {
+
+	last := configFilesStamp(x.Config().ConfigPath)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		stamp := configFilesStamp(x.Config().ConfigPath)
+		if stamp == last {
+			continue
+		}
+		last = stamp
+
+		xlog.Info("config files changed")
+
+		if err := x.Reload(); err != nil {
+			xlog.Error("%v", err) // fixed file is picked up on next change
+		}
+	}
+}
+
+// configFilesStamp names, sizes and mod times of config files in local config dirs
+func configFilesStamp(configPath []string) string {
+
+	var b strings.Builder
+
+	for _, dir := range configPath {
+
+		if strings.HasPrefix(dir, "http") {
+			continue // remote dir, reload by signal or sys api
+		}
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			fmt.Fprintf(&b, "%v:%v;", dir, err)
+			continue
+		}
+
+		for _, v := range entries {
+			if v.IsDir() || !slices.Contains(utilconfig.Formats, filepath.Ext(v.Name())) {
+				continue
+			}
+			info, err := v.Info()
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(&b, "%v/%v:%v:%v;", dir, v.Name(), info.Size(), info.ModTime().UnixNano())
+		}
+	}
+
+	return b.String()
+}
+
 func (x *AppConfigSource) Config() *AppConfig {
 
-	return x.config
+	return x.config.Load()
 
 }
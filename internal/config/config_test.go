@@ -0,0 +1,231 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-auth-admin/internal/config/consts"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeTestConfig(t *testing.T, dir string, data string) {
+	t.Helper()
+
+	if err := os.WriteFile(filepath.Join(dir, consts.AppName, "config.testing.json"), []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestConfigSource(t *testing.T, data string) (*AppConfigSource, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, consts.AppName), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	writeTestConfig(t, dir, data)
+	if err := os.WriteFile(filepath.Join(dir, consts.AppName, "lang.en.json"), []byte(`{}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("APP_CONFIG", dir)
+	t.Setenv("APP_ENV", envTesting)
+
+	res := &AppConfigSource{}
+	if err := res.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	return res, dir
+}
+
+func TestAppConfigSourceReload(t *testing.T) {
+
+	source, dir := newTestConfigSource(t, `{"title":"one"}`)
+	prev := source.Config()
+
+	committed := ""
+	source.OnReload(func(next *AppConfig) (func(), error) {
+		if next.Title == "bad" {
+			return nil, errors.New("rejected by subsystem")
+		}
+		return func() { committed = source.Config().Title }, nil // commit runs after swap
+	})
+
+	writeTestConfig(t, dir, `{"title":"two"}`)
+	if err := source.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := source.Config().Title; got != "two" {
+		t.Errorf("title = %v, want two", got)
+	}
+	if committed != "two" {
+		t.Errorf("committed = %v, want two", committed)
+	}
+	if prev.Title != "one" {
+		t.Errorf("previous config changed: %v", prev.Title)
+	}
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "invalid json", data: `{"title":`},
+		{name: "validate error", data: `{"http_server":{"listen":""}}`},
+		{name: "rejected by reloader", data: `{"title":"bad"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeTestConfig(t, dir, tt.data)
+
+			if err := source.Reload(); err == nil {
+				t.Fatal("Reload() error = nil, want error")
+			}
+			if got := source.Config().Title; got != "two" {
+				t.Errorf("title = %v, want two kept", got)
+			}
+			if committed != "two" {
+				t.Errorf("committed = %v, want no commit", committed)
+			}
+		})
+	}
+}
+
+func TestAppConfigSourceWatch(t *testing.T) {
+
+	source, dir := newTestConfigSource(t, `{"title":"one"}`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		source.Watch(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+
+	for i := 0; i < 200 && source.Config().Title != "watched"; i++ {
+		writeTestConfig(t, dir, `{"title":"watched"}`) // again, watcher may stamp files after first write
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	if got := source.Config().Title; got != "watched" {
+		t.Errorf("title = %v, want watched", got)
+	}
+
+	cancel()
+	<-done
+}
+
+func TestAppConfigVaultKeyHasAuthKey(t *testing.T) {
+
+	for _, tt := range []struct {
+		key  AppConfigVaultKey
+		want bool
+	}{
+		{AppConfigVaultKey{ID: "1", AuthKey: "a", OtpKey: "o", HashKey: "h"}, true},
+		{AppConfigVaultKey{ID: "1", AuthKey: "a"}, true}, // db key of auth app
+		{AppConfigVaultKey{ID: "1"}, false},
+		{AppConfigVaultKey{AuthKey: "a"}, false},
+	} {
+		if got := tt.key.HasAuthKey(); got != tt.want {
+			t.Errorf("HasAuthKey(%+v) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func Test_restartOnlyChanges(t *testing.T) {
+
+	prev := NewAppConfig()
+	next := NewAppConfig()
+	next.Title = "changed"
+	next.HTTPServer.Listen = ":1"
+
+	got := restartOnlyChanges(prev, next)
+	if len(got) != 1 || got[0] != "http_server" {
+		t.Errorf("restartOnlyChanges() = %v, want [http_server]", got)
+	}
+
+	next = NewAppConfig()
+	next.HTTPServer.RateLimit = 1
+	next.HTTPServer.RateLimitRoutes = map[string]AppConfigRateLimit{"GET /x": {Rate: 1}}
+
+	if got = restartOnlyChanges(prev, next); len(got) != 0 {
+		t.Errorf("restartOnlyChanges() = %v, want none for rate limits", got)
+	}
+}
+
+func TestEnvReaderFile(t *testing.T) {
+
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "db_password")
+	if err := os.WriteFile(secretFile, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	keysFile := filepath.Join(dir, "vault_keys")
+	if err := os.WriteFile(keysFile, []byte(`[{"id":"k1","auth_key":"YQ==","otp_key":"Yg==","sign_key":"Yw=="}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("APP_DB_PASSWORD_FILE", secretFile)
+	t.Setenv("APP_VAULT_KEYS_FILE", keysFile)
+
+	res := NewAppConfig()
+	if err := res.readEnvVar(); err != nil {
+		t.Fatal(err)
+	}
+
+	if res.DB.Password != "from-file" {
+		t.Errorf("db password = %q, want from-file", res.DB.Password)
+	}
+	if len(res.Vault.Keys) != 1 || res.Vault.Keys[0].ID != "k1" || res.Vault.Keys[0].AuthKey != "YQ==" {
+		t.Errorf("vault keys = %+v", res.Vault.Keys)
+	}
+
+	t.Setenv("APP_DB_PASSWORD", "literal")
+	if err := NewAppConfig().readEnvVar(); err == nil {
+		t.Error("readEnvVar() error = nil, want error for both env and file")
+	}
+
+	t.Setenv("APP_DB_PASSWORD", "")
+	t.Setenv("APP_DB_PASSWORD_FILE", filepath.Join(dir, "missing"))
+	if err := NewAppConfig().readEnvVar(); err == nil {
+		t.Error("readEnvVar() error = nil, want error for missing file")
+	}
+}
+
+func TestAppConfigRedacted(t *testing.T) {
+
+	cfg := NewAppConfig()
+	cfg.HTTPServer.SysAPIKey = "sys-key"
+	cfg.Messenger.SMTP.Password = "smtp-pass"
+	cfg.Messenger.Webhook.Headers = map[string]string{"Authorization": "Bearer token"}
+	cfg.Vault.Keys = []AppConfigVaultKey{{ID: "k1", AuthKey: "a", OtpKey: "o", HashKey: "h"}}
+	cfg.Redis.Password = ""
+
+	res := cfg.Redacted()
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, secret := range []string{"sys-key", "smtp-pass", "Bearer token", `"a"`, `"o"`, `"h"`, `"password":"postgres"`} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("dump has secret %v", secret)
+		}
+	}
+
+	if res.Vault.Keys[0].ID != "k1" || res.DB.Host != cfg.DB.Host || res.Redis.Password != "" {
+		t.Errorf("redacted non-secret or empty fields: %+v %+v", res.Vault.Keys, res.Redis)
+	}
+
+	if cfg.Vault.Keys[0].AuthKey != "a" || cfg.Messenger.Webhook.Headers["Authorization"] != "Bearer token" || cfg.DB.Password != "postgres" {
+		t.Error("source config changed")
+	}
+}
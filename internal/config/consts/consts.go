@@ -17,6 +17,8 @@ const (
 	PasswordMinLength = 8
 	PasswordMaxLength = 50 // bcrypt 72
 
+	PasswordGenerateLength = 16
+
 	PasscodeLength        = 8
 	LongTextLength        = 32767 //  int(int16(^uint16(0) >> 1)) // equivalent of short.MaxValue
 	DefaultTextLength     = 100
@@ -28,6 +30,8 @@ const (
 	TitleTextLengthMedium = 50
 	TitleTextLengthLarge  = 100
 
+	RoleCodeMaxLength = 50
+
 	// WF_STATUS_NEW       = 0
 	// WF_STATUS_PROGRESS  = 6
 	// WF_STATUS_DELETE    = 7
@@ -54,6 +58,7 @@ const (
 	// PathAPI represents the group of PathAPI.
 	PathAPI           = "/api"
 	PathAuthSignin    = "/auth/signin"
+	PathAuthSignup    = "/auth/signup"
 	PathAuthStatusAPI = "/auth/api/status"
 	PathHome          = "/"
 )
@@ -68,11 +73,18 @@ const (
 	AuthRoleView    = "auth_view"
 	AuthRoleDelete  = "auth_delete"
 	AuthRolePublish = "auth_publish"
+	AuthRoleRoles   = "auth_roles"   // grant and revoke roles
+	AuthRoleApprove = "auth_approve" // approve change requests of other admins
+
+	AuthRoleImpersonate = "auth_impersonate" // sign in as another account
 )
 
 //nolint:gosec
 const (
-	PathSysMetricsAPI = "/sys/api/metrics"
+	PathSysMetricsAPI       = "/sys/api/metrics"
+	PathSysConfigReloadAPI  = "/sys/api/config/reload"
+	PathSysInvitesRedeemAPI = "/sys/api/invites/redeem"
+	PathSysNotifyAPI        = "/sys/api/notify"
 )
 
 //nolint:gosec
@@ -81,6 +93,7 @@ const (
 
 	PathAuthAdmin               = "/auth-admin"
 	PathAuthAdminAssets         = "/auth-admin/assets"
+	PathAuthAdminAPI            = "/auth-admin/api"
 	PathAuthAdminAccounts       = "/auth-admin/accounts"
 	PathAuthAdminAccountsEntity = "/auth-admin/accounts/:code" // GET
 	PathAuthAdminStatusAPI      = "/auth-admin/api/status"     // get _csrf, user related, no-cache
@@ -90,5 +103,39 @@ const (
 	PathAuthAdminAccountsEntityAPI       = "/auth-admin/api/accounts/:id"        // GET PUT DELETE
 	PathAuthAdminAccountsEntityByCodeAPI = "/auth-admin/api/accounts/:code/code" // GET
 
-	PathAuthAdminAccountsEntityPasswordAPI = "/auth-admin/api/accounts/:id/password" // GET
+	PathAuthAdminAccountsEntityPasswordAPI         = "/auth-admin/api/accounts/:id/password"          // GET
+	PathAuthAdminAccountsEntityPasswordGenerateAPI = "/auth-admin/api/accounts/:id/password/generate" // POST
+
+	PathAuthAdminAccountsEntityVerifyAPI         = "/auth-admin/api/accounts/:id/verify/:contact"          // POST DELETE
+	PathAuthAdminAccountsEntityVerifyPasscodeAPI = "/auth-admin/api/accounts/:id/verify/:contact/passcode" // POST
+
+	PathAuthAdminAccountsEntityImpersonateAPI = "/auth-admin/api/accounts/:id/impersonate" // POST
+	PathAuthAdminImpersonateAPI               = "/auth-admin/api/impersonate"              // DELETE, end impersonation
+
+	PathAuthAdminInvitesAPI             = "/auth-admin/api/invites"            // LIST POST
+	PathAuthAdminInvitesEntityAPI       = "/auth-admin/api/invites/:id"        // GET DELETE
+	PathAuthAdminInvitesEntityResendAPI = "/auth-admin/api/invites/:id/resend" // POST
+
+	PathAuthAdminRolesAPI       = "/auth-admin/api/roles"       // LIST POST
+	PathAuthAdminRolesEntityAPI = "/auth-admin/api/roles/:code" // GET PUT DELETE
+
+	PathAuthAdminRolesEntityAccountsAPI = "/auth-admin/api/roles/:code/accounts" // LIST
+
+	PathAuthAdminGroupsAPI       = "/auth-admin/api/groups"     // LIST POST
+	PathAuthAdminGroupsEntityAPI = "/auth-admin/api/groups/:id" // GET PUT DELETE
+
+	PathAuthAdminGroupsEntityMembersAPI       = "/auth-admin/api/groups/:id/members"             // LIST POST
+	PathAuthAdminGroupsEntityMembersEntityAPI = "/auth-admin/api/groups/:id/members/:account_id" // DELETE
+
+	PathAuthAdminApprovalsAPI              = "/auth-admin/api/approvals"             // LIST
+	PathAuthAdminApprovalsExpireAPI        = "/auth-admin/api/approvals/expire"      // POST
+	PathAuthAdminApprovalsEntityAPI        = "/auth-admin/api/approvals/:id"         // GET
+	PathAuthAdminApprovalsEntityApproveAPI = "/auth-admin/api/approvals/:id/approve" // POST
+	PathAuthAdminApprovalsEntityRejectAPI  = "/auth-admin/api/approvals/:id/reject"  // POST
+
+	PathAuthAdminMessagesPreviewAPI = "/auth-admin/api/messages/preview" // POST, render without dispatch
+
+	PathAuthAdminOutboxAPI            = "/auth-admin/api/outbox"           // LIST
+	PathAuthAdminOutboxEntityAPI      = "/auth-admin/api/outbox/:id"       // GET
+	PathAuthAdminOutboxEntityRetryAPI = "/auth-admin/api/outbox/:id/retry" // POST
 )
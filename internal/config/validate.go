@@ -0,0 +1,373 @@
+package config
+
+import (
+	"encoding/base64"
+	"fmt"
+	"go-auth-admin/internal/config/consts"
+	"go-auth-admin/internal/util/utilconfig"
+	"go-auth-admin/internal/util/utilhttp"
+	"go-auth-admin/internal/util/utiltls"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// vaultKeyMinSize bytes of decoded vault key, 256 bit
+const vaultKeyMinSize = 32
+
+var dbDialects = []string{"postgres", "sqlite"}
+
+var rateLimitMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+var corsMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions}
+
+var (
+	telPrefixPattern = regexp.MustCompile(`^\+[0-9]{1,4}$`)
+	langCodePattern  = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z0-9]{2,8})?$`)
+)
+
+// FieldError config value error, Path is JSON path, e.g. http_server.listen
+type FieldError struct {
+	Path    string
+	Message string
+}
+
+func (x FieldError) Error() string {
+	return x.Path + ": " + x.Message
+}
+
+// ValidationError every error found in config, one per line
+type ValidationError []FieldError
+
+func (x ValidationError) Error() string {
+
+	lines := make([]string, 0, len(x)+1)
+	lines = append(lines, "error config is invalid:")
+
+	for _, v := range x {
+		lines = append(lines, "  "+v.Error())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (x *ValidationError) Add(path string, format string, args ...any) {
+	*x = append(*x, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// Err nil if there is no error
+func (x ValidationError) Err() error {
+	if len(x) == 0 {
+		return nil
+	}
+	return x
+}
+
+func (x AppConfig) validate() error {
+
+	errs := ValidationError{}
+
+	x.HTTPServer.validate(&errs)
+	x.DB.validate(&errs, "database")
+	x.Redis.validateRedis(&errs, "redis")
+	x.Vault.validate(&errs)
+	x.Identity.validate(&errs)
+	x.Lang.validate(&errs, x.ConfigPath)
+
+	if x.Reload.Watch && x.Reload.Interval <= 0 {
+		errs.Add("reload.interval", "must be positive")
+	}
+
+	if x.Messenger.SMTP.Timeout <= 0 {
+		errs.Add("messenger.smtp.timeout", "must be positive")
+	}
+
+	return errs.Err()
+}
+
+func (x AppConfigHTTPServer) validate(errs *ValidationError) {
+
+	if x.Listen == "" && x.ListenTLS == "" {
+		errs.Add("http_server.listen", "listen and listen_tls are empty")
+	}
+
+	for _, v := range []struct{ path, addr string }{
+		{"http_server.listen", x.Listen},
+		{"http_server.listen_tls", x.ListenTLS},
+		{"http_server.listen_sys", x.ListenSys},
+	} {
+		if v.addr == "" {
+			continue
+		}
+		if err := checkListen(v.addr); err != nil {
+			errs.Add(v.path, "%v", err)
+		}
+	}
+
+	if x.ListenTLS != "" && !x.AutoTLS {
+		if x.CertDir == "" {
+			errs.Add("http_server.cert_dir", "required for listen_tls without auto_tls")
+		} else if info, err := os.Stat(x.CertDir); err != nil || !info.IsDir() {
+			errs.Add("http_server.cert_dir", "dir not exists: %v", x.CertDir)
+		} else {
+			for _, name := range []string{utiltls.CertFile, utiltls.KeyFile} {
+				if !utilconfig.HasFile(x.CertDir, name) {
+					errs.Add("http_server.cert_dir", "file %v not exists in: %v", name, x.CertDir)
+				}
+			}
+		}
+	}
+
+	if x.AutoTLS {
+		if x.ListenTLS == "" {
+			errs.Add("http_server.auto_tls", "requires listen_tls")
+		}
+		if len(x.AutoTLSHosts) == 0 {
+			errs.Add("http_server.auto_tls_hosts", "required for auto_tls")
+		}
+		if x.AutoTLSCacheDir == "" {
+			errs.Add("http_server.auto_tls_cache_dir", "required for auto_tls")
+		}
+	}
+
+	if x.RedirectHTTPS && x.ListenTLS == "" {
+		errs.Add("http_server.redirect_https", "requires listen_tls")
+	}
+
+	if x.ListenSys != "" && (x.SysMetrics || x.SysReload || x.SysInvites || x.SysNotify) && x.SysAPIKey == "" {
+		errs.Add("http_server.sys_api_key", "required for sys api")
+	}
+
+	if x.RateLimit < 0 {
+		errs.Add("http_server.rate_limit", "must not be negative")
+	}
+	if x.RateBurst < 0 {
+		errs.Add("http_server.rate_burst", "must not be negative")
+	}
+	if x.RateBurst > 0 && x.RateLimit == 0 {
+		errs.Add("http_server.rate_burst", "requires rate_limit")
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(x.RateLimitRoutes)) {
+		path := "http_server.rate_limit_routes." + key
+
+		method, route, ok := strings.Cut(key, " ")
+		if !ok || !slices.Contains(rateLimitMethods, method) || !strings.HasPrefix(route, consts.PathAuthAdminAPI+"/") {
+			errs.Add(path, "must be \"METHOD %v/...\" of route", consts.PathAuthAdminAPI)
+		}
+
+		x.RateLimitRoutes[key].validate(errs, path)
+	}
+
+	x.CORS.validate(errs, "http_server.cors")
+
+	for _, v := range []struct {
+		path  string
+		value int
+	}{
+		{"http_server.read_timeout", x.ReadTimeout},
+		{"http_server.write_timeout", x.WriteTimeout},
+		{"http_server.idle_timeout", x.IdleTimeout},
+		{"http_server.read_header_timeout", x.ReadHeaderTimeout},
+	} {
+		if v.value < 0 {
+			errs.Add(v.path, "must not be negative")
+		}
+	}
+}
+
+func (x AppConfigRateLimit) validate(errs *ValidationError, path string) {
+
+	if x.Rate < 0 {
+		errs.Add(path+".rate", "must not be negative")
+	}
+	if x.Burst < 0 {
+		errs.Add(path+".burst", "must not be negative")
+	}
+	if x.Burst > 0 && x.Rate == 0 {
+		errs.Add(path+".burst", "requires rate")
+	}
+}
+
+func (x AppConfigCORS) validate(errs *ValidationError, path string) {
+
+	for i, origin := range x.AllowOrigins {
+		if origin == utilhttp.OriginAny && x.AllowCredentials {
+			errs.Add(fmt.Sprintf("%v.allow_origins[%v]", path, i), "* is not allowed with allow_credentials")
+		} else if err := utilhttp.CheckOrigin(origin); err != nil {
+			errs.Add(fmt.Sprintf("%v.allow_origins[%v]", path, i), "%v", err)
+		}
+	}
+
+	for i, method := range x.AllowMethods {
+		if !slices.Contains(corsMethods, method) {
+			errs.Add(fmt.Sprintf("%v.allow_methods[%v]", path, i), "unknown method: %v", method)
+		}
+	}
+
+	if x.MaxAge < 0 {
+		errs.Add(path+".max_age", "must not be negative")
+	}
+}
+
+// checkListen host:port, host may be empty, port is number or service name
+func checkListen(addr string) error {
+
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+
+	if _, err = net.LookupPort("tcp", port); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (x Database) validate(errs *ValidationError, path string) {
+
+	if !slices.Contains(dbDialects, x.Dialect) {
+		errs.Add(path+".dialect", "unknown dialect %q, known: %v", x.Dialect, strings.Join(dbDialects, ", "))
+	}
+
+	if x.Host == "" {
+		errs.Add(path+".host", "required") // file name for sqlite
+	}
+
+	if x.MaxOpen < 0 || x.MaxIdle < 0 || x.IdleTime < 0 {
+		errs.Add(path, "max_open, max_idle, idle_time must not be negative")
+	}
+}
+
+// validateRedis empty dialect is memory store, settings are not used
+func (x Database) validateRedis(errs *ValidationError, path string) {
+
+	switch x.Dialect {
+	case "":
+		return
+	case "redis":
+	default:
+		errs.Add(path+".dialect", "unknown dialect %q, known: redis or empty for memory", x.Dialect)
+		return
+	}
+
+	if err := checkListen(net.JoinHostPort(x.Host, x.Port)); err != nil || x.Host == "" {
+		errs.Add(path+".host", "host and port required: %v:%v", x.Host, x.Port)
+	}
+
+	if db, err := strconv.Atoi(x.Name); x.Name != "" && (err != nil || db < 0) {
+		errs.Add(path+".name", "must be db number")
+	}
+
+	if x.MaxOpen < 0 || x.MaxIdle < 0 || x.IdleTime < 0 {
+		errs.Add(path, "max_open, max_idle, idle_time must not be negative")
+	}
+}
+
+func (x AppConfigVault) validate(errs *ValidationError) {
+
+	ids := map[string]bool{}
+
+	for i, key := range x.Keys {
+
+		path := fmt.Sprintf("vault.keys[%d]", i)
+
+		if key.ID == "" {
+			errs.Add(path+".id", "required")
+		} else if ids[key.ID] {
+			errs.Add(path+".id", "duplicate id %q", key.ID)
+		}
+		ids[key.ID] = true
+
+		if key.AuthKey == "" {
+			errs.Add(path+".auth_key", "required")
+		}
+
+		for _, v := range []struct{ name, value string }{
+			{"auth_key", key.AuthKey},
+			{"otp_key", key.OtpKey},
+			{"sign_key", key.HashKey},
+		} {
+			if v.value == "" {
+				continue // otp and sign keys are optional
+			}
+			if err := checkVaultKey(v.value); err != nil {
+				errs.Add(path+"."+v.name, "%v", err) // value is secret, not printed
+			}
+		}
+	}
+}
+
+func checkVaultKey(value string) error {
+
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return fmt.Errorf("not base64")
+	}
+
+	if len(data) < vaultKeyMinSize {
+		return fmt.Errorf("too short, %d bytes, want at least %d", len(data), vaultKeyMinSize)
+	}
+
+	return nil
+}
+
+func (x AppConfigIdentity) validate(errs *ValidationError) {
+
+	if !telPrefixPattern.MatchString(x.TelPrefix) {
+		errs.Add("identity.tel_prefix", "%q must be + and 1 to 4 digits", x.TelPrefix)
+	}
+
+	if x.AuthTokenIssuer == "" {
+		errs.Add("identity.auth_token_issuer", "required")
+	}
+
+	for _, v := range []struct {
+		path  string
+		value int
+	}{
+		{"identity.token_max_age", x.TokenMaxAge},
+		{"identity.invite_max_age", x.InviteMaxAge},
+		{"identity.impersonate_max_age", x.ImpersonateMaxAge},
+	} {
+		if v.value <= 0 {
+			errs.Add(v.path, "must be positive")
+		}
+	}
+}
+
+// validate langs have lang file in each local config dir, as i18n loader requires
+func (x AppConfigLang) validate(errs *ValidationError, configPath []string) {
+
+	if len(x.Langs) == 0 {
+		errs.Add("lang.langs", "at least one lang is required")
+	}
+
+	for i, code := range x.Langs {
+
+		path := fmt.Sprintf("lang.langs[%d]", i)
+
+		if !langCodePattern.MatchString(code) {
+			errs.Add(path, "invalid lang code %q", code)
+			continue
+		}
+
+		if slices.Index(x.Langs, code) != i {
+			errs.Add(path, "duplicate lang %q", code)
+			continue
+		}
+
+		for _, dir := range configPath {
+			fileName := fmt.Sprintf("lang.%s.json", code)
+			if !utilconfig.HasFile(dir, fileName) {
+				errs.Add(path, "file %v not exists in: %v", fileName, dir)
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAppConfigValidate(t *testing.T) {
+
+	{
+		cfg := NewAppConfig()
+		cfg.ConfigPath = nil // no lang files
+		if err := cfg.validate(); err != nil {
+			t.Fatalf("default config: %v", err)
+		}
+	}
+
+	key := base64.StdEncoding.EncodeToString(make([]byte, vaultKeyMinSize))
+	short := base64.StdEncoding.EncodeToString(make([]byte, vaultKeyMinSize-1))
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "lang.en.json"), []byte(`{}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := NewAppConfig()
+	cfg.ConfigPath = []string{dir}
+	cfg.Lang.Langs = []string{"en", "es", "EN"}
+	cfg.DB.Dialect = "mysql"
+	cfg.Redis.Dialect = "redis"
+	cfg.Redis.Name = "cache"
+	cfg.HTTPServer.Listen = "10280"
+	cfg.HTTPServer.ListenTLS = ":10283"
+	cfg.HTTPServer.CertDir = filepath.Join(dir, "missing")
+	cfg.HTTPServer.RateLimit = -1
+	cfg.HTTPServer.RateLimitRoutes = map[string]AppConfigRateLimit{
+		"POST /auth-admin/api/accounts": {Rate: 1, Burst: 2},
+		"post /auth-admin/api/accounts": {Rate: 1},
+		"GET /auth-admin/api/status":    {Burst: 2},
+	}
+	cfg.HTTPServer.CORS.AllowOrigins = []string{"https://*.example.com", "*", "https://example.com/"}
+	cfg.HTTPServer.CORS.AllowCredentials = true
+	cfg.HTTPServer.CORS.AllowMethods = []string{"GET", "TRACE"}
+	cfg.Identity.TelPrefix = "123"
+	cfg.Vault.Keys = []AppConfigVaultKey{
+		{ID: "k1", AuthKey: key, OtpKey: "not base64!"},
+		{ID: "k1", AuthKey: short},
+	}
+
+	err := cfg.validate()
+
+	errs := ValidationError{}
+	if !errors.As(err, &errs) {
+		t.Fatalf("validate() error = %v, want ValidationError", err)
+	}
+
+	got := []string{}
+	for _, v := range errs {
+		got = append(got, v.Path)
+	}
+
+	want := []string{
+		"http_server.listen",
+		"http_server.cert_dir",
+		"http_server.rate_limit",
+		"http_server.rate_limit_routes.GET /auth-admin/api/status.burst",
+		"http_server.rate_limit_routes.post /auth-admin/api/accounts",
+		"http_server.cors.allow_origins[1]",
+		"http_server.cors.allow_origins[2]",
+		"http_server.cors.allow_methods[1]",
+		"database.dialect",
+		"redis.name",
+		"vault.keys[0].otp_key",
+		"vault.keys[1].id",
+		"vault.keys[1].auth_key",
+		"identity.tel_prefix",
+		"lang.langs[1]",
+		"lang.langs[2]",
+	}
+
+	if !slices.Equal(got, want) {
+		t.Errorf("paths = %v, want %v", got, want)
+	}
+
+	if strings.Contains(err.Error(), "not base64!") {
+		t.Error("error shows vault key value")
+	}
+}
+
+func Test_checkListen(t *testing.T) {
+
+	for addr, ok := range map[string]bool{
+		":10280":         true,
+		"127.0.0.1:http": true,
+		"[::1]:443":      true,
+		"10280":          false,
+		":port":          false,
+		":70000":         false,
+	} {
+		if err := checkListen(addr); (err == nil) != ok {
+			t.Errorf("checkListen(%q) error = %v, want ok %v", addr, err, ok)
+		}
+	}
+}
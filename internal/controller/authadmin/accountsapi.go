@@ -1,6 +1,7 @@
 package authadmin
 
 import (
+	"cmp"
 	"go-auth-admin/internal/config"
 	controller "go-auth-admin/internal/controller"
 	"go-auth-admin/internal/util/utilaccess"
@@ -21,6 +22,11 @@ var userAccountOmit = []string{
 type AccountsDTO struct {
 	Input struct {
 		utilpaging.PagingInputDTO
+		EmailVerified string `query:"email_verified"` // true false
+		TelVerified   string `query:"tel_verified"`   // true false
+		Role          string `query:"role"`
+		RoleCode      string `param:"code"` // /roles/:code/accounts
+		GroupID       string `param:"id"`   // /groups/:id/members
 	}
 	Meta struct {
 		Status int
@@ -94,6 +100,21 @@ func (x *AccountsAPIController) validateDTO() error {
 
 	// input.Filter = c.QueryParams() //
 
+	input.Filters = utilpaging.Filters{}
+
+	if v := input.EmailVerified; v != "" {
+		input.Filters["email_verified"] = v
+	}
+	if v := input.TelVerified; v != "" {
+		input.Filters["tel_verified"] = v
+	}
+	if v := cmp.Or(input.RoleCode, input.Role); v != "" {
+		input.Filters["role"] = v
+	}
+	if v := input.GroupID; v != "" {
+		input.Filters["group"] = v
+	}
+
 	return nil
 }
 
@@ -121,6 +142,10 @@ func (x *AccountsAPIController) handleDTO() error {
 			return err
 		}
 
+		for _, v := range output.Data {
+			v.MaskFields(&output.Permissions)
+		}
+
 	} else {
 		meta.Status = http.StatusMethodNotAllowed
 		output.Message = "method action undef"
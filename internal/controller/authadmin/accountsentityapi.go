@@ -5,7 +5,10 @@ import (
 	"go-auth-admin/internal/config/consts"
 	controller "go-auth-admin/internal/controller"
 	"go-auth-admin/internal/mvc"
+	"go-auth-admin/internal/util/utilaccess"
+	"go-auth-admin/internal/util/utilstring"
 
+	"slices"
 	"strings"
 
 	"go-auth-admin/internal/i18n"
@@ -29,7 +32,9 @@ type AccountsEntityDTO struct {
 	}
 	Output struct {
 		mvc.ModelBaseDTO
-		Data UserAccountDTO `json:"data,omitempty"`
+		Data          UserAccountDTO            `json:"data,omitempty"`
+		Permissions   utilaccess.PermissionsDTO `json:"permissions,omitempty"`
+		ChangeRequest *service.ChangeRequest    `json:"change_request,omitempty"` // 202, waits for approval
 	}
 }
 type AccountsEntityAPIController struct {
@@ -44,6 +49,9 @@ type AccountsEntityAPIController struct {
 
 	webCtxt echo.Context // webCtxt
 
+	userAccount *service.UserAccount // actor
+	account     *service.UserAccount // target, stored
+
 	DTO AccountsEntityDTO
 }
 
@@ -74,14 +82,15 @@ func NewAccountsEntityAPIController(appService service.AppService, c echo.Contex
 	appConfig := appService.Config()
 
 	return &AccountsEntityAPIController{
-		appService: appService,
-		appConfig:  appConfig,
-		userLang:   controller.UserLang(c, appService),
-		IsGET:      controller.IsGET(c),
-		IsPOST:     controller.IsPOST(c),
-		IsPUT:      controller.IsPUT(c),
-		IsDELETE:   controller.IsDELETE(c),
-		webCtxt:    c,
+		appService:  appService,
+		appConfig:   appConfig,
+		userLang:    controller.UserLang(c, appService),
+		IsGET:       controller.IsGET(c),
+		IsPOST:      controller.IsPOST(c),
+		IsPUT:       controller.IsPUT(c),
+		IsDELETE:    controller.IsDELETE(c),
+		userAccount: controller.GetAccount(c),
+		webCtxt:     c,
 	}
 }
 
@@ -93,13 +102,15 @@ func (x *AccountsEntityAPIController) validateDTOFields() (err error) {
 	meta := &dto.Meta
 	srv := x.appService.AuthAdmin()
 
+	srv.UserAccounts().Permissions(x.userAccount, &output.Permissions)
+
 	if x.IsPOST || x.IsPUT {
 
 		// validate input: add update
 
 		{
 			input.Data.Username = strings.TrimSpace(input.Data.Username)
-			input.Data.Roles = strings.TrimSpace(input.Data.Roles)
+			input.Data.Roles = utilaccess.NormalizeRoles(input.Data.Roles)
 			// input.Data.ContentHTML = "" // reset
 		}
 
@@ -108,7 +119,7 @@ func (x *AccountsEntityAPIController) validateDTOFields() (err error) {
 			// v.Required()
 		}
 		{
-			_ = output.NewModelValidatorStr(x.userLang, "roles", "Roles" /*Lang*/, input.Data.Roles, consts.DefaultTextLength)
+			_ = output.NewModelValidatorStr(x.userLang, "roles", "Roles" /*Lang*/, input.Data.Roles, consts.LongTextLength)
 			// v.Required()
 		}
 		{
@@ -122,6 +133,12 @@ func (x *AccountsEntityAPIController) validateDTOFields() (err error) {
 
 	}
 
+	if x.IsPOST || x.IsPUT {
+		if err := validateRoles(x.appService, x.userLang, &output.ModelBaseDTO, input.Data.Roles); err != nil {
+			return err
+		}
+	}
+
 	if !output.IsModelValid() {
 		meta.Status = http.StatusUnprocessableEntity // 422 validation
 		return nil
@@ -130,18 +147,36 @@ func (x *AccountsEntityAPIController) validateDTOFields() (err error) {
 	if x.IsDELETE || x.IsPUT {
 		// exists: delete, update
 
-		id, err := srv.UserAccounts().ID(input.ID)
+		x.account, err = srv.UserAccounts().FindByID(input.ID)
 		if err != nil {
 			return err
 		}
 
-		if id == "" {
+		if x.account == nil {
 			meta.Status = http.StatusNotFound // 404
 			return nil
 		}
 
 	}
 
+	if x.IsDELETE {
+		// delete revokes every role of account
+		validateRolesChange(x.userLang, &output.ModelBaseDTO, x.userAccount, x.account.Roles, "")
+
+		if !output.IsModelValid() {
+			meta.Status = http.StatusForbidden // 403
+			return nil
+		}
+	}
+
+	if x.IsPOST || x.IsPUT {
+		x.validateFieldsEdit()
+
+		if meta.Status > 0 {
+			return nil
+		}
+	}
+
 	if x.IsPOST || x.IsPUT {
 		// code dupl: add, update
 		{
@@ -194,6 +229,53 @@ func (x *AccountsEntityAPIController) validateDTOFields() (err error) {
 
 }
 
+// validateFieldsEdit fields caller may not edit keep stored value,
+// stored or masked value (as returned by GET) is accepted, other value is refused
+func (x *AccountsEntityAPIController) validateFieldsEdit() {
+
+	dto := &x.DTO
+	input := &dto.Input
+	output := &dto.Output
+	meta := &dto.Meta
+
+	stored := &service.UserAccount{} // POST: nothing stored
+	if x.account != nil {
+		stored = x.account
+	}
+
+	fields := []struct {
+		name   string
+		value  *string
+		stored string
+		masked string
+	}{
+		{service.FieldEmail, &input.Data.Email, stored.Email, utilstring.MaskEmail(stored.Email)},
+		{service.FieldTel, &input.Data.Tel, stored.Tel, utilstring.MaskTel(stored.Tel)},
+		{service.FieldRoles, &input.Data.Roles, stored.Roles, stored.Roles},
+	}
+
+	for _, v := range fields {
+
+		if output.Permissions.CanEditField(v.name) {
+			continue
+		}
+
+		if *v.value == v.stored || *v.value == v.masked {
+			*v.value = v.stored // keep
+			continue
+		}
+
+		meta.Status = http.StatusForbidden // 403
+		output.AddError(v.name, x.userLang.Lang("Field {0} cannot be changed.", v.name))
+	}
+
+	validateRolesChange(x.userLang, &output.ModelBaseDTO, x.userAccount, stored.Roles, input.Data.Roles)
+
+	if !output.IsModelValid() {
+		meta.Status = http.StatusForbidden // 403
+	}
+}
+
 func (x *AccountsEntityAPIController) validateDTO() error {
 
 	dto := &x.DTO
@@ -246,7 +328,22 @@ func (x *AccountsEntityAPIController) handlePOST() (err error) {
 	output.Data = input.Data
 	output.Data.ID = "" // reset ID
 
-	return srv.UserAccounts().Create(&output.Data.UserAccount)
+	// admin is granted on approve, account is created without it
+	withheld := x.grantsAdmin() && srv.ChangeRequests().IsRequired(service.ChangeActionAccountGrantAdmin)
+	if withheld {
+		output.Data.Roles = withoutAdmin(output.Data.Roles)
+	}
+
+	if err = srv.UserAccounts().Create(&output.Data.UserAccount); err != nil {
+		return err
+	}
+
+	if withheld {
+		output.ChangeRequest, err = requestApproval(x.appService, x.userLang, &output.ModelBaseDTO, &dto.Meta.Status,
+			x.userAccount, service.ChangeActionAccountGrantAdmin, output.Data.ID, grantAdminPayload())
+	}
+
+	return err
 
 }
 func (x *AccountsEntityAPIController) handlePUT() (err error) {
@@ -257,7 +354,28 @@ func (x *AccountsEntityAPIController) handlePUT() (err error) {
 
 	output.Data = input.Data
 	output.Data.ID = input.Data.ID // reset ID
-	return srv.UserAccounts().Update(&output.Data.UserAccount)
+
+	if x.grantsAdmin() {
+
+		output.ChangeRequest, err = requestApproval(x.appService, x.userLang, &output.ModelBaseDTO, &dto.Meta.Status,
+			x.userAccount, service.ChangeActionAccountGrantAdmin, x.account.ID, grantAdminPayload())
+
+		if err != nil || dto.Meta.Status == http.StatusConflict {
+			output.Data.UserAccount = *x.account // unchanged, request is pending already
+			return err
+		}
+
+		if output.ChangeRequest != nil {
+			output.Data.Roles = withoutAdmin(output.Data.Roles) // admin is granted on approve, rest of change now
+		}
+	}
+
+	err = srv.UserAccounts().Update(&output.Data.UserAccount)
+	if isLastAdmin(err, x.userLang, &output.ModelBaseDTO, &dto.Meta.Status) {
+		return nil
+	}
+
+	return err
 
 }
 func (x *AccountsEntityAPIController) handleDELETE() error {
@@ -268,7 +386,21 @@ func (x *AccountsEntityAPIController) handleDELETE() error {
 	srv := x.appService.AuthAdmin()
 
 	output.Data.ID = input.ID // reset ID
-	return srv.UserAccounts().Delete(output.Data.ID)
+
+	changeRequest, err := requestApproval(x.appService, x.userLang, &output.ModelBaseDTO, &dto.Meta.Status,
+		x.userAccount, service.ChangeActionAccountDelete, input.ID, nil)
+
+	if err != nil || changeRequest != nil {
+		output.ChangeRequest = changeRequest
+		return err
+	}
+
+	err = srv.UserAccounts().Delete(output.Data.ID)
+	if isLastAdmin(err, x.userLang, &output.ModelBaseDTO, &dto.Meta.Status) {
+		return nil
+	}
+
+	return err
 
 }
 func (x *AccountsEntityAPIController) handleDTO() error {
@@ -315,6 +447,8 @@ func (x *AccountsEntityAPIController) responseDTOAsAPI() (err error) {
 		output.Data.PasswordHash = "" // stop direct update
 	}
 
+	output.Data.MaskFields(&output.Permissions)
+
 	return c.JSON(meta.Status, output)
 
 }
@@ -324,3 +458,9 @@ func (x *AccountsEntityAPIController) responseDTO() (err error) {
 	return x.responseDTOAsAPI()
 
 }
+
+// grantsAdmin checks if update adds direct admin role
+func (x *AccountsEntityAPIController) grantsAdmin() bool {
+	return slices.Contains(x.DTO.Input.Data.RoleCodes(), consts.RoleAdmin) &&
+		(x.account == nil || !slices.Contains(x.account.RoleCodes(), consts.RoleAdmin))
+}
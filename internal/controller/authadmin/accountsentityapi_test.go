@@ -0,0 +1,265 @@
+package authadmin
+
+import (
+	"fmt"
+	"go-auth-admin/internal/service"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// newTestAppService app service over in-memory sqlite, db is kept open until test end
+func newTestAppService(t *testing.T) service.AppService {
+	t.Helper()
+
+	dsn := fmt.Sprintf("file:%v_%v?mode=memory&cache=shared", t.Name(), time.Now().UnixNano())
+
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	// vault keys are owned by auth app
+	key, err := service.NewVaultKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AutoMigrate(&service.VaultKey{}); err != nil {
+		t.Fatal(err)
+	}
+	if err = db.Create(key).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("APP_DB_DIALECT", "sqlite")
+	t.Setenv("APP_DB_HOST", dsn)
+	t.Setenv("APP_DB_MIGRATION", "true")
+
+	return service.MustNewAppServiceTesting()
+}
+
+func newTestAccount(t *testing.T, appService service.AppService, username string, roles string) *service.UserAccount {
+	t.Helper()
+
+	srv := appService.AuthAdmin().UserAccounts()
+
+	data := &service.UserAccount{Username: username, Roles: roles}
+	if err := srv.Create(data); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := srv.FindByID(data.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res
+}
+
+func serveAccountsEntity(t *testing.T, appService service.AppService, actor *service.UserAccount, method string, id string, body string) int {
+	t.Helper()
+
+	e := echo.New()
+
+	req := httptest.NewRequest(method, "/", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(id)
+	c.Set("user_account", actor)
+
+	if err := NewAccountsEntityAPIController(appService, c).Handler(); err != nil {
+		t.Fatal(err)
+	}
+
+	return rec.Code
+}
+
+func accountBody(x *service.UserAccount, roles string) string {
+	return fmt.Sprintf(`{"data":{"id":%q,"username":%q,"roles":%q}}`, x.ID, x.Username, roles)
+}
+
+func TestAccountsEntityRolesNotHeld(t *testing.T) {
+
+	appService := newTestAppService(t)
+
+	admin := newTestAccount(t, appService, "admin1", "admin")
+	editor := newTestAccount(t, appService, "editor1", "auth_access auth_edit auth_roles")
+
+	tests := []struct {
+		name   string
+		actor  *service.UserAccount
+		before string
+		roles  string
+		status int
+	}{
+		{"Grant admin", editor, "auth_access", "auth_access admin", http.StatusForbidden},
+		{"Grant not held", editor, "auth_access", "auth_access auth_delete", http.StatusForbidden},
+		{"Grant held", editor, "auth_access", "auth_access auth_edit", http.StatusOK},
+		{"Revoke held", editor, "auth_access auth_edit", "auth_access", http.StatusOK},
+		{"Admin grants any", admin, "auth_access", "auth_access auth_delete", http.StatusOK},
+		{"Revoke not held", editor, "auth_access auth_delete", "auth_access", http.StatusForbidden},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := newTestAccount(t, appService, fmt.Sprintf("target%d", i), tt.before)
+
+			status := serveAccountsEntity(t, appService, tt.actor, http.MethodPut, target.ID, accountBody(target, tt.roles))
+			if status != tt.status {
+				t.Errorf("PUT roles %q over %q status = %v, want %v", tt.roles, tt.before, status, tt.status)
+			}
+		})
+	}
+}
+
+func TestAccountsEntityDeleteRolesNotHeld(t *testing.T) {
+
+	appService := newTestAppService(t)
+
+	newTestAccount(t, appService, "admin1", "admin")
+	editor := newTestAccount(t, appService, "editor1", "auth_access auth_edit")
+
+	tests := []struct {
+		name   string
+		roles  string
+		status int
+	}{
+		{"Delete admin", "admin", http.StatusForbidden},
+		{"Delete not held", "auth_access auth_delete", http.StatusForbidden},
+		{"Delete held", "auth_access", http.StatusOK},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := newTestAccount(t, appService, fmt.Sprintf("target%d", i), tt.roles)
+
+			if status := serveAccountsEntity(t, appService, editor, http.MethodDelete, target.ID, ""); status != tt.status {
+				t.Errorf("DELETE account with roles %q status = %v, want %v", tt.roles, status, tt.status)
+			}
+
+			stored, err := appService.AuthAdmin().UserAccounts().FindByID(target.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (stored == nil) != (tt.status == http.StatusOK) {
+				t.Errorf("account deleted = %v, want %v", stored == nil, tt.status == http.StatusOK)
+			}
+		})
+	}
+}
+
+func TestAccountsEntityRolesOrder(t *testing.T) {
+
+	appService := newTestAppService(t)
+
+	editor := newTestAccount(t, appService, "editor1", "auth_access auth_edit") // roles field is read-only
+	target := newTestAccount(t, appService, "target1", "auth_access auth_view")
+
+	if status := serveAccountsEntity(t, appService, editor, http.MethodPut, target.ID, accountBody(target, "auth_view  auth_access")); status != http.StatusOK {
+		t.Errorf("PUT same roles reordered status = %v, want %v", status, http.StatusOK)
+	}
+}
+
+func TestAccountsEntityLastAdmin(t *testing.T) {
+
+	appService := newTestAppService(t)
+
+	admin := newTestAccount(t, appService, "admin1", "admin")
+
+	if status := serveAccountsEntity(t, appService, admin, http.MethodPut, admin.ID, accountBody(admin, "auth_access")); status != http.StatusConflict {
+		t.Errorf("demote last admin status = %v, want %v", status, http.StatusConflict)
+	}
+
+	if status := serveAccountsEntity(t, appService, admin, http.MethodDelete, admin.ID, ""); status != http.StatusConflict {
+		t.Errorf("delete last admin status = %v, want %v", status, http.StatusConflict)
+	}
+
+	other := newTestAccount(t, appService, "admin2", "admin")
+
+	if status := serveAccountsEntity(t, appService, admin, http.MethodDelete, other.ID, ""); status != http.StatusOK {
+		t.Errorf("delete admin status = %v, want %v", status, http.StatusOK)
+	}
+
+	stored, err := appService.AuthAdmin().UserAccounts().FindByID(admin.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored == nil || stored.Roles != "admin" {
+		t.Errorf("last admin = %v, want kept", stored)
+	}
+}
+
+func TestAccountsLegacyRolesColumn(t *testing.T) {
+
+	appService := newTestAppService(t)
+	repo := appService.Repository()
+
+	// column of auth app
+	if err := repo.Exec("alter table user_accounts add column roles varchar(255)").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	legacy := newTestAccount(t, appService, "legacy1", "")
+	if err := repo.Exec("update user_accounts set roles = ? where id = ?", "auth_view admin", legacy.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	legacyRoles := func(id string) string {
+		t.Helper()
+		res := ""
+		if err := repo.Raw("select roles from user_accounts where id = ?", id).Scan(&res).Error; err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	target := newTestAccount(t, appService, "target1", "auth_edit auth_access")
+	if got := legacyRoles(target.ID); got != "auth_access auth_edit" {
+		t.Errorf("legacy roles on create = %q, want in sync", got)
+	}
+
+	target.Roles = ""
+	if err := appService.AuthAdmin().UserAccounts().Update(target); err != nil {
+		t.Fatal(err)
+	}
+	if got := legacyRoles(target.ID); got != "" {
+		t.Errorf("legacy roles on update = %q, want in sync", got)
+	}
+
+	restarted := service.MustNewAppServiceTesting() // migrates db of first
+	t.Cleanup(func() { _ = restarted.Store().Close() })
+
+	for _, v := range []struct {
+		id    string
+		roles string
+	}{
+		{legacy.ID, "admin auth_view"},
+		{target.ID, ""},
+	} {
+		stored, err := restarted.AuthAdmin().UserAccounts().FindByID(v.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.Roles != v.roles {
+			t.Errorf("roles after migration = %q, want %q", stored.Roles, v.roles)
+		}
+		if got := legacyRoles(v.id); got != v.roles {
+			t.Errorf("legacy roles after migration = %q, want %q", got, v.roles)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package authadmin
+
+import (
+	"fmt"
+	"go-auth-admin/internal/config"
+	"go-auth-admin/internal/config/consts"
+	controller "go-auth-admin/internal/controller"
+	"go-auth-admin/internal/mvc"
+	xtoken "go-auth-admin/internal/token"
+	xweb "go-auth-admin/internal/web"
+	"slices"
+	"time"
+
+	"go-auth-admin/internal/i18n"
+	"go-auth-admin/internal/service"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type AccountsImpersonateDTO struct {
+	Input struct {
+		ID string `param:"id"`
+	}
+	Meta struct {
+		Status int
+	}
+	Output struct {
+		mvc.ModelBaseDTO
+		UserID    string     `json:"user_id,omitempty"`    // whose token is set
+		ExpiresAt *time.Time `json:"expires_at,omitempty"` // impersonation end
+	}
+}
+type AccountsImpersonateAPIController struct {
+	appService service.AppService
+	appConfig  *config.AppConfig
+	userLang   i18n.UserLang
+
+	IsPOST   bool // POST /accounts/:id/impersonate
+	IsDELETE bool // DELETE /impersonate
+
+	webCtxt echo.Context // webCtxt
+
+	claims      *xtoken.TokenClaimsDTO
+	userAccount *service.UserAccount // actor
+	account     *service.UserAccount // target
+
+	DTO AccountsImpersonateDTO
+}
+
+func (x *AccountsImpersonateAPIController) Handler() error {
+
+	err := x.validateDTO()
+	if err != nil {
+		return err
+	}
+
+	err = x.handleDTO()
+	if err != nil {
+		return err
+	}
+
+	err = x.responseDTO()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// NewAccountsImpersonateAPIController is constructor.
+func NewAccountsImpersonateAPIController(appService service.AppService, c echo.Context) *AccountsImpersonateAPIController {
+
+	appConfig := appService.Config()
+	return &AccountsImpersonateAPIController{
+		appService:  appService,
+		appConfig:   appConfig,
+		userLang:    controller.UserLang(c, appService),
+		IsPOST:      controller.IsPOST(c),
+		IsDELETE:    controller.IsDELETE(c),
+		claims:      xweb.AuthTokenClaims(c),
+		userAccount: controller.GetAccount(c),
+		webCtxt:     c,
+	}
+}
+
+func (x *AccountsImpersonateAPIController) validateDTOFields() (err error) {
+
+	dto := &x.DTO
+	input := &dto.Input
+	output := &dto.Output
+	meta := &dto.Meta
+	srv := x.appService.AuthAdmin()
+
+	if x.claims == nil || (x.IsPOST && x.userAccount == nil) {
+		meta.Status = http.StatusUnauthorized // 401
+		return nil
+	}
+
+	if x.IsDELETE && !x.claims.IsImpersonated() {
+		meta.Status = http.StatusConflict // 409
+		output.AddError("", x.userLang.Lang("Impersonation is not active."))
+		return nil
+	}
+
+	if x.IsPOST {
+
+		if x.claims.IsImpersonated() {
+			meta.Status = http.StatusConflict // 409, no nested impersonation
+			output.AddError("", x.userLang.Lang("Impersonation is already active."))
+			return nil
+		}
+
+		if !xweb.HasAuthCookie(x.webCtxt) {
+			meta.Status = http.StatusBadRequest // 400, actor token is kept in cookie
+			output.AddError("", x.userLang.Lang("Impersonation requires cookie sign-in."))
+			return nil
+		}
+
+		x.account, err = srv.UserAccounts().FindByID(input.ID)
+		if err != nil {
+			return err
+		}
+
+		if x.account == nil {
+			meta.Status = http.StatusNotFound // 404
+			return nil
+		}
+
+		if x.account.ID == x.userAccount.ID || slices.Contains(x.account.EffectiveRoleCodes(), consts.RoleAdmin) {
+			meta.Status = http.StatusForbidden // 403
+			output.AddError("", x.userLang.Lang("Account cannot be impersonated."))
+			return nil
+		}
+
+	}
+
+	return nil
+
+}
+
+func (x *AccountsImpersonateAPIController) validateDTO() error {
+
+	dto := &x.DTO
+	input := &dto.Input
+
+	c := x.webCtxt
+
+	if err := c.Bind(input); err != nil {
+		return err
+	}
+
+	return x.validateDTOFields()
+
+}
+
+func (x *AccountsImpersonateAPIController) handlePOST() (err error) {
+	dto := &x.DTO
+	output := &dto.Output
+	srv := x.appService.AuthAdmin()
+	acc := x.account
+
+	maxAge := time.Duration(x.appConfig.Identity.ImpersonateMaxAge) * time.Second
+
+	claims := &xtoken.TokenClaimsDTO{
+		UserID: acc.ID,
+		Email:  acc.Email,
+		Tel:    acc.Tel,
+		Act:    &xtoken.ActorClaims{UserID: x.userAccount.ID},
+	}
+	claims.ID = uuid.New().String() // jti
+	claims.SetIssuer(x.appConfig.Identity.AuthTokenIssuer)
+	claims.AddScope(xtoken.ScopeAuth)
+	claims.SetLifetime(maxAge)
+
+	if aud := x.appConfig.Identity.AuthTokenAudience; aud != "" {
+		claims.Audience = []string{aud}
+	}
+
+	if err = xweb.StartImpersonation(x.webCtxt, claims, x.appService.Vault().KeyScopeAuth()); err != nil {
+		return err
+	}
+
+	// audit of issued token only
+	if err = srv.AuditLogs().Add(x.userAccount.ID, service.AuditActionImpersonateStart, acc.ID, "jti="+claims.ID); err != nil {
+		return err
+	}
+
+	output.UserID = acc.ID
+	output.ExpiresAt = &claims.ExpiresAt.Time
+	output.Status = consts.StatusSuccess
+	return nil
+
+}
+
+func (x *AccountsImpersonateAPIController) handleDELETE() (err error) {
+	dto := &x.DTO
+	meta := &dto.Meta
+	output := &dto.Output
+	srv := x.appService.AuthAdmin()
+
+	actorID := x.claims.Act.UserID
+
+	if err = srv.AuditLogs().Add(actorID, service.AuditActionImpersonateEnd, x.claims.UserID, "jti="+x.claims.ID); err != nil {
+		return err
+	}
+
+	// copied impersonation token is not accepted after end
+	if err = x.appService.TokenRevocation().RevokeToken(x.claims); err != nil {
+		return err
+	}
+
+	actor, err := xweb.EndImpersonation(x.webCtxt, x.appService.Vault().KeyScopeAuth())
+	if err == nil {
+		if revoked, errRevoked := x.appService.TokenRevocation().IsRevoked(actor); errRevoked != nil || revoked {
+			err = fmt.Errorf("error actor token revoked")
+		}
+	}
+	if err != nil {
+		xweb.DeleteAuthToken(x.webCtxt)       // sign out, actor token is gone
+		meta.Status = http.StatusUnauthorized // 401
+		output.AddError("", x.userLang.Lang("Impersonation ended, sign in again."))
+		return nil
+	}
+
+	output.UserID = actor.UserID
+	output.ExpiresAt = &actor.ExpiresAt.Time
+	output.Status = consts.StatusSuccess
+	return nil
+
+}
+
+func (x *AccountsImpersonateAPIController) handleDTO() error {
+
+	dto := &x.DTO
+	meta := &dto.Meta
+	output := &dto.Output
+
+	if meta.Status > 0 {
+		return nil // stop processing
+	}
+
+	switch {
+	case x.IsPOST:
+		return x.handlePOST()
+	case x.IsDELETE:
+		return x.handleDELETE()
+	default:
+		{
+			meta.Status = http.StatusMethodNotAllowed
+			output.Message = "method action undef"
+		}
+	}
+
+	return nil
+}
+func (x *AccountsImpersonateAPIController) responseDTOAsAPI() (err error) {
+
+	dto := &x.DTO
+	meta := &dto.Meta
+	output := &dto.Output
+	c := x.webCtxt
+
+	if meta.Status == 0 {
+		meta.Status = http.StatusOK
+	}
+
+	c.Response().Header().Set(`Cache-Control`, "no-store") // sets auth cookie
+
+	return c.JSON(meta.Status, output)
+
+}
+
+func (x *AccountsImpersonateAPIController) responseDTO() (err error) {
+	return x.responseDTOAsAPI()
+}
@@ -0,0 +1,186 @@
+package authadmin
+
+import (
+	"go-auth-admin/internal/service"
+	xtoken "go-auth-admin/internal/token"
+	xweb "go-auth-admin/internal/web"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+func newTestToken(t *testing.T, appService service.AppService, claims *xtoken.TokenClaimsDTO) string {
+	t.Helper()
+
+	claims.SetIssuer(appService.Config().Identity.AuthTokenIssuer)
+	claims.AddScope(xtoken.ScopeAuth)
+	claims.SetLifetime(time.Hour)
+
+	res, err := xtoken.CreateToken(claims, appService.Vault().KeyScopeAuth())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res
+}
+
+// serveImpersonate cookies as sent by browser, token is parsed like by TokenParserMiddleware
+func serveImpersonate(t *testing.T, appService service.AppService, actor *service.UserAccount, method string, id string, cookies map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	e := echo.New()
+
+	req := httptest.NewRequest(method, "/", nil)
+	for k, v := range cookies {
+		req.AddCookie(&http.Cookie{Name: k, Value: v})
+	}
+	rec := httptest.NewRecorder()
+
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(id)
+	c.Set("user_account", actor)
+
+	claims, err := xtoken.ParseToken(cookies[xweb.JwtKey], appService.Vault().KeyScopeAuth())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Set(xweb.JwtKey, &jwt.Token{Claims: claims, Valid: true})
+
+	if err := NewAccountsImpersonateAPIController(appService, c).Handler(); err != nil {
+		t.Fatal(err)
+	}
+
+	return rec
+}
+
+func responseCookie(rec *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, v := range rec.Result().Cookies() {
+		if v.Name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+func TestAccountsImpersonate(t *testing.T) {
+
+	appService := newTestAppService(t)
+
+	support := newTestAccount(t, appService, "support1", "auth_access auth_impersonate")
+	admin := newTestAccount(t, appService, "admin1", "admin")
+	target := newTestAccount(t, appService, "target1", "")
+
+	supportToken := newTestToken(t, appService, &xtoken.TokenClaimsDTO{UserID: support.ID})
+
+	if rec := serveImpersonate(t, appService, support, http.MethodPost, admin.ID, map[string]string{xweb.JwtKey: supportToken}); rec.Code != http.StatusForbidden {
+		t.Errorf("impersonate admin status = %v, want %v", rec.Code, http.StatusForbidden)
+	}
+
+	rec := serveImpersonate(t, appService, support, http.MethodPost, target.ID, map[string]string{xweb.JwtKey: supportToken})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("impersonate status = %v, want %v", rec.Code, http.StatusOK)
+	}
+
+	auth, saved := responseCookie(rec, xweb.JwtKey), responseCookie(rec, xweb.JwtActorKey)
+	if auth == nil || saved == nil || saved.Value != supportToken {
+		t.Fatalf("cookies = %v %v, want target token and kept actor token", auth, saved)
+	}
+
+	claims, err := xtoken.ParseToken(auth.Value, appService.Vault().KeyScopeAuth())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.UserID != target.ID || !claims.IsImpersonated() || claims.Act.UserID != support.ID {
+		t.Errorf("claims user = %v act = %v, want target acted by support", claims.UserID, claims.Act)
+	}
+	if claims.Rotate(true) != nil {
+		t.Errorf("impersonation token rotated, want short-lived")
+	}
+
+	cookies := map[string]string{xweb.JwtKey: auth.Value, xweb.JwtActorKey: saved.Value}
+
+	if rec := serveImpersonate(t, appService, target, http.MethodPost, admin.ID, cookies); rec.Code != http.StatusConflict {
+		t.Errorf("nested impersonate status = %v, want %v", rec.Code, http.StatusConflict)
+	}
+
+	rec = serveImpersonate(t, appService, target, http.MethodDelete, "", cookies)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("end impersonation status = %v, want %v", rec.Code, http.StatusOK)
+	}
+
+	if auth := responseCookie(rec, xweb.JwtKey); auth == nil || auth.Value != supportToken {
+		t.Errorf("restored token = %v, want actor token", auth)
+	}
+
+	if revoked, err := appService.TokenRevocation().IsRevoked(claims); err != nil || !revoked {
+		t.Errorf("impersonation token revoked = %v, %v, want true after end", revoked, err)
+	}
+
+	var count int64
+	appService.Repository().Model(&service.AuditLog{}).
+		Where("actor_id = ? and target_id = ? and action in ?", support.ID, target.ID,
+			[]string{service.AuditActionImpersonateStart, service.AuditActionImpersonateEnd}).
+		Count(&count)
+
+	if count != 2 {
+		t.Errorf("audit count = %v, want 2", count)
+	}
+}
+
+func TestAccountsImpersonateBearer(t *testing.T) {
+
+	appService := newTestAppService(t)
+
+	support := newTestAccount(t, appService, "support1", "auth_access auth_impersonate")
+	target := newTestAccount(t, appService, "target1", "")
+
+	supportToken := newTestToken(t, appService, &xtoken.TokenClaimsDTO{UserID: support.ID})
+
+	for name, cookie := range map[string]bool{"Bearer": false, "Bearer with cookie": true} {
+		t.Run(name, func(t *testing.T) {
+			e := echo.New()
+
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+supportToken)
+			if cookie {
+				req.AddCookie(&http.Cookie{Name: xweb.JwtKey, Value: supportToken})
+			}
+			rec := httptest.NewRecorder()
+
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(target.ID)
+			c.Set("user_account", support)
+
+			claims, err := xtoken.ParseToken(supportToken, appService.Vault().KeyScopeAuth())
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.Set(xweb.JwtKey, &jwt.Token{Claims: claims, Valid: true})
+
+			if err := NewAccountsImpersonateAPIController(appService, c).Handler(); err != nil {
+				t.Fatal(err)
+			}
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("impersonate status = %v, want %v", rec.Code, http.StatusBadRequest)
+			}
+			if auth := responseCookie(rec, xweb.JwtKey); auth != nil {
+				t.Errorf("token cookie = %v, want none", auth)
+			}
+		})
+	}
+
+	var count int64
+	appService.Repository().Model(&service.AuditLog{}).
+		Where("action = ?", service.AuditActionImpersonateStart).
+		Count(&count)
+
+	if count != 0 {
+		t.Errorf("audit count = %v, want 0", count)
+	}
+}
@@ -143,6 +143,13 @@ func (x *AccountsPasswordAPIController) handlePOST() (err error) {
 		return err
 	}
 
+	acc, err := srv.UserAccounts().FindByID(input.ID)
+	if err != nil {
+		return err
+	}
+
+	srv.Notify().Notify(service.NotifyEventPasswordReset, acc, nil) // generated password is sent instead
+
 	output.Message = userLang.Lang("Password changed")
 	output.Status = consts.StatusSuccess
 	return nil
@@ -0,0 +1,250 @@
+package authadmin
+
+import (
+	"fmt"
+	"go-auth-admin/internal/service"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func outboxKinds(t *testing.T, appService service.AppService, recipient string) []string {
+	t.Helper()
+
+	res := []string{}
+
+	err := appService.Repository().Model(&service.OutboxMessage{}).
+		Where("recipient = ?", recipient).
+		Order("created_at asc").
+		Pluck("kind", &res).Error
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res
+}
+
+func TestAccountsPasswordNotify(t *testing.T) {
+
+	appService := newTestAppService(t)
+
+	target := newTestAccount(t, appService, "target1", "")
+	target.Email = "target1@example.com"
+	if err := appService.AuthAdmin().UserAccounts().Update(target); err != nil {
+		t.Fatal(err)
+	}
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"new_password":"Secret-pass-123"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(target.ID)
+
+	if err := NewAccountsPasswordAPIController(appService, c).Handler(); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("password status = %v, want %v", rec.Code, http.StatusOK)
+	}
+
+	if got := outboxKinds(t, appService, target.Email); len(got) != 1 || got[0] != "email-password-reset" {
+		t.Errorf("notifications = %v, want email-password-reset", got)
+	}
+}
+
+func TestRolesChangedNotify(t *testing.T) {
+
+	appService := newTestAppService(t)
+	srv := appService.AuthAdmin()
+
+	target := newTestAccount(t, appService, "target1", "")
+	target.Tel = "+123456"
+	if err := srv.UserAccounts().Update(target); err != nil {
+		t.Fatal(err)
+	}
+
+	target.Roles = "auth_access"
+	if err := srv.UserAccounts().Update(target); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := srv.UserAccounts().Update(target); err != nil { // same roles
+		t.Fatal(err)
+	}
+
+	if got := outboxKinds(t, appService, target.Tel); len(got) != 1 || got[0] != "sms-roles-changed" {
+		t.Errorf("notifications = %v, want one sms-roles-changed", got)
+	}
+
+	appService.Config().Notify.Disabled = []string{service.NotifyEventRolesChanged}
+
+	target.Roles = ""
+	if err := srv.UserAccounts().Update(target); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := outboxKinds(t, appService, target.Tel); len(got) != 1 {
+		t.Errorf("notifications = %v, want none after opt-out", got)
+	}
+}
+
+func TestGroupRolesChangedNotify(t *testing.T) {
+
+	appService := newTestAppService(t)
+	srv := appService.AuthAdmin()
+
+	target := newTestAccount(t, appService, "target1", "")
+	target.Tel = "+123456"
+	if err := srv.UserAccounts().Update(target); err != nil {
+		t.Fatal(err)
+	}
+
+	group := &service.AccountGroup{Name: "group1"}
+	if err := srv.Groups().Create(group); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Groups().AddMember(group.ID, target.ID); err != nil { // group has no roles
+		t.Fatal(err)
+	}
+
+	group.Roles = "auth_access"
+	if err := srv.Groups().Update(group); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := srv.Groups().Delete(group.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := outboxKinds(t, appService, target.Tel); len(got) != 2 {
+		t.Errorf("notifications = %v, want sms-roles-changed on group update and delete", got)
+	}
+}
+
+func TestAccountLockedNotify(t *testing.T) {
+
+	appService := newTestAppService(t)
+	srv := appService.AuthAdmin()
+
+	target := newTestAccount(t, appService, "target1", "auth_access")
+	target.Tel = "+123456"
+	if err := srv.UserAccounts().Update(target); err != nil {
+		t.Fatal(err)
+	}
+
+	target.Roles = "auth_access auth_locked"
+	if err := srv.UserAccounts().Update(target); err != nil {
+		t.Fatal(err)
+	}
+
+	target.Roles = "auth_access"
+	if err := srv.UserAccounts().Update(target); err != nil {
+		t.Fatal(err)
+	}
+
+	group := &service.AccountGroup{Name: "locked", Roles: "auth_locked"}
+	if err := srv.Groups().Create(group); err != nil {
+		t.Fatal(err)
+	}
+
+	appService.Config().Notify.Disabled = []string{service.NotifyEventRolesChanged}
+
+	if err := srv.Groups().AddMember(group.ID, target.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"sms-account-locked", "sms-roles-changed", "sms-account-locked"}
+	if got := outboxKinds(t, appService, target.Tel); !slices.Equal(got, want) {
+		t.Errorf("notifications = %v, want %v", got, want)
+	}
+}
+
+func TestAccountsPasswordGenerateDelivery(t *testing.T) {
+
+	for _, tt := range []struct {
+		name      string
+		messenger int // status of messenger service
+		status    int
+		changed   bool
+	}{
+		{"Delivered", http.StatusOK, http.StatusOK, true},
+		{"Delivery failed", http.StatusInternalServerError, http.StatusBadGateway, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+
+			received := ""
+
+			messengerService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received = r.FormValue("password")
+				w.WriteHeader(tt.messenger)
+			}))
+			t.Cleanup(messengerService.Close)
+
+			t.Setenv("APP_MESSENGER_SERVICE_URL", messengerService.URL+"/{code}") // outbox is on by default
+
+			appService := newTestAppService(t)
+			srv := appService.AuthAdmin().UserAccounts()
+
+			target := newTestAccount(t, appService, "target1", "")
+			target.Email = "target1@example.com"
+			if err := srv.Update(target); err != nil {
+				t.Fatal(err)
+			}
+			if err := srv.UpdatePassword(target.ID, "Secret-pass-123"); err != nil {
+				t.Fatal(err)
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"reveal":true}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(target.ID)
+
+			if err := NewAccountsPasswordGenerateAPIController(appService, c).Handler(); err != nil {
+				t.Fatal(err)
+			}
+			if rec.Code != tt.status {
+				t.Fatalf("generate status = %v, want %v", rec.Code, tt.status)
+			}
+			if got := strings.Contains(rec.Body.String(), `"password"`); got != tt.changed {
+				t.Errorf("password revealed = %v, want %v", got, tt.changed)
+			}
+
+			if got := outboxKinds(t, appService, target.Email); len(got) != 0 {
+				t.Errorf("outbox = %v, want password sent without outbox", got)
+			}
+
+			stored, err := srv.FindByID(target.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := !stored.CompareHashAndPassword("Secret-pass-123"); got != tt.changed {
+				t.Errorf("password changed = %v, want %v", got, tt.changed)
+			}
+			if tt.changed && !stored.CompareHashAndPassword(received) {
+				t.Error("changed password is not the delivered one")
+			}
+
+			var count int64
+			appService.Repository().Model(&service.AuditLog{}).
+				Where("action = ? and target_id = ? and details like ?", service.AuditActionPasswordGenerate, target.ID,
+					fmt.Sprintf("%%delivered=%t changed=%t", tt.changed, tt.changed)).
+				Count(&count)
+
+			if count != 1 {
+				t.Errorf("audit count = %v, want 1", count)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package authadmin
+
+import (
+	"fmt"
+	"go-auth-admin/internal/config"
+	"go-auth-admin/internal/config/consts"
+	controller "go-auth-admin/internal/controller"
+	"go-auth-admin/internal/messenger"
+	"go-auth-admin/internal/mvc"
+	"go-auth-admin/internal/util/utilcrypto"
+	xlog "go-auth-admin/internal/util/utillog"
+
+	"go-auth-admin/internal/i18n"
+	"go-auth-admin/internal/service"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type AccountsPasswordGenerateDTO struct {
+	Input struct {
+		ID     string `param:"id"`
+		Reveal bool   `json:"reveal"` // return plaintext in response
+	}
+	Meta struct {
+		Status int
+	}
+	Output struct {
+		mvc.ModelBaseDTO
+		Channel  string `json:"channel,omitempty"`  // email || tel
+		Password string `json:"password,omitempty"` // only if Reveal
+	}
+}
+type AccountsPasswordGenerateAPIController struct {
+	appService service.AppService
+	appConfig  *config.AppConfig
+	userLang   i18n.UserLang
+	IsPOST     bool
+	webCtxt    echo.Context // webCtxt
+
+	userAccount *service.UserAccount // actor
+	account     *service.UserAccount // target
+
+	DTO AccountsPasswordGenerateDTO
+}
+
+func (x *AccountsPasswordGenerateAPIController) Handler() error {
+
+	err := x.validateDTO()
+	if err != nil {
+		return err
+	}
+
+	err = x.handleDTO()
+	if err != nil {
+		return err
+	}
+
+	err = x.responseDTO()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// NewAccountsPasswordGenerateAPIController is constructor.
+func NewAccountsPasswordGenerateAPIController(appService service.AppService, c echo.Context) *AccountsPasswordGenerateAPIController {
+
+	appConfig := appService.Config()
+	return &AccountsPasswordGenerateAPIController{
+		appService:  appService,
+		appConfig:   appConfig,
+		userLang:    controller.UserLang(c, appService),
+		IsPOST:      controller.IsPOST(c),
+		userAccount: controller.GetAccount(c),
+		webCtxt:     c,
+	}
+}
+
+func (x *AccountsPasswordGenerateAPIController) validateDTOFields() (err error) {
+
+	dto := &x.DTO
+	input := &dto.Input
+	output := &dto.Output
+	meta := &dto.Meta
+	srv := x.appService.AuthAdmin()
+
+	if x.IsPOST {
+
+		x.account, err = srv.UserAccounts().FindByID(input.ID)
+		if err != nil {
+			return err
+		}
+
+		if x.account == nil {
+			meta.Status = http.StatusNotFound // 404
+			return nil
+		}
+
+		if x.account.Email == "" && x.account.Tel == "" && !input.Reveal {
+			output.AddError("", x.userLang.Lang("Account has no email or phone number."))
+		}
+
+	}
+
+	if !output.IsModelValid() {
+		meta.Status = http.StatusUnprocessableEntity // 422 validation
+		return nil
+	}
+
+	return nil
+
+}
+
+func (x *AccountsPasswordGenerateAPIController) validateDTO() error {
+
+	dto := &x.DTO
+	input := &dto.Input
+
+	c := x.webCtxt
+
+	if err := c.Bind(input); err != nil {
+		return err
+	}
+
+	return x.validateDTOFields()
+
+}
+
+// deliver sends password to email, or to tel if email is empty,
+// bypassing outbox: password is changed only if delivered
+func (x *AccountsPasswordGenerateAPIController) deliver(password string) (channel string, err error) {
+
+	msg := x.appService.Messenger()
+	lang := x.userLang.LangCode()
+	acc := x.account
+	data := map[string]string{"password": password}
+
+	switch {
+	case acc.Email != "":
+		return "email", msg.SendNow(messenger.KindEmailPassword, acc.Email, lang, data)
+	case acc.Tel != "":
+		return "tel", msg.SendNow(messenger.KindSmsPassword, acc.Tel, lang, data)
+	}
+
+	return "", nil
+}
+
+func (x *AccountsPasswordGenerateAPIController) handlePOST() (err error) {
+	userLang := x.userLang
+	dto := &x.DTO
+	output := &dto.Output
+	input := &dto.Input
+	meta := &dto.Meta
+	srv := x.appService.AuthAdmin()
+
+	password, err := utilcrypto.RandomPassword(consts.PasswordGenerateLength)
+	if err != nil {
+		return err
+	}
+
+	// deliver first, failed delivery keeps old password
+	channel, errSend := x.deliver(password)
+	if errSend != nil {
+		xlog.Error("error on password delivery %v: %v", input.ID, errSend)
+	}
+
+	var errUpdate error
+	if errSend == nil {
+		errUpdate = srv.UserAccounts().UpdatePassword(input.ID, password)
+	}
+
+	{
+		actorID := ""
+		if x.userAccount != nil {
+			actorID = x.userAccount.ID
+		}
+		// delivered=true changed=false: user got password which does not work
+		details := fmt.Sprintf("channel=%s reveal=%t delivered=%t changed=%t",
+			channel, input.Reveal, errSend == nil, errSend == nil && errUpdate == nil)
+		if err = srv.AuditLogs().Add(actorID, service.AuditActionPasswordGenerate, input.ID, details); err != nil {
+			return err
+		}
+	}
+
+	if errUpdate != nil {
+		return errUpdate
+	}
+
+	output.Channel = channel
+
+	if errSend != nil {
+		meta.Status = http.StatusBadGateway // 502 messenger
+		output.AddError("", userLang.Lang("Delivery failed, password not changed."))
+		return nil
+	}
+
+	if input.Reveal {
+		output.Password = password
+	}
+
+	output.Message = userLang.Lang("Password changed")
+	output.Status = consts.StatusSuccess
+	return nil
+
+}
+
+func (x *AccountsPasswordGenerateAPIController) handleDTO() error {
+
+	dto := &x.DTO
+	meta := &dto.Meta
+	output := &dto.Output
+
+	if meta.Status > 0 {
+		return nil // stop processing
+	}
+
+	switch {
+	case x.IsPOST:
+		return x.handlePOST()
+	default:
+		{
+			meta.Status = http.StatusMethodNotAllowed
+			output.Message = "method action undef"
+		}
+	}
+
+	return nil
+}
+func (x *AccountsPasswordGenerateAPIController) responseDTOAsAPI() (err error) {
+
+	dto := &x.DTO
+	meta := &dto.Meta
+	output := &dto.Output
+	c := x.webCtxt
+
+	if meta.Status == 0 {
+		meta.Status = http.StatusOK
+	}
+
+	c.Response().Header().Set(`Cache-Control`, "no-store") // may contain password
+
+	return c.JSON(meta.Status, output)
+
+}
+
+func (x *AccountsPasswordGenerateAPIController) responseDTO() (err error) {
+	return x.responseDTOAsAPI()
+}
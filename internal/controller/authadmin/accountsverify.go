@@ -0,0 +1,240 @@
+package authadmin
+
+import (
+	"go-auth-admin/internal/config"
+	"go-auth-admin/internal/config/consts"
+	controller "go-auth-admin/internal/controller"
+	"go-auth-admin/internal/mvc"
+	"time"
+
+	"go-auth-admin/internal/i18n"
+	"go-auth-admin/internal/service"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type AccountsVerifyDTO struct {
+	Input struct {
+		ID       string `param:"id"`
+		Contact  string `param:"contact"` // email || tel
+		Passcode string `json:"passcode"` // POST, optional, passcode sent to contact
+	}
+	Meta struct {
+		Status int
+	}
+	Output struct {
+		mvc.ModelBaseDTO
+		VerifiedAt *time.Time `json:"verified_at,omitempty"`
+	}
+}
+type AccountsVerifyAPIController struct {
+	appService service.AppService
+	appConfig  *config.AppConfig
+	userLang   i18n.UserLang
+
+	IsPOST     bool
+	IsDELETE   bool
+	IsPasscode bool // POST /passcode
+
+	webCtxt echo.Context // webCtxt
+
+	userAccount *service.UserAccount // actor
+	account     *service.UserAccount // target
+
+	DTO AccountsVerifyDTO
+}
+
+func (x *AccountsVerifyAPIController) Handler() error {
+
+	err := x.validateDTO()
+	if err != nil {
+		return err
+	}
+
+	err = x.handleDTO()
+	if err != nil {
+		return err
+	}
+
+	err = x.responseDTO()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// NewAccountsVerifyAPIController is constructor.
+func NewAccountsVerifyAPIController(appService service.AppService, c echo.Context) *AccountsVerifyAPIController {
+
+	appConfig := appService.Config()
+	return &AccountsVerifyAPIController{
+		appService:  appService,
+		appConfig:   appConfig,
+		userLang:    controller.UserLang(c, appService),
+		IsPOST:      controller.IsPOST(c),
+		IsDELETE:    controller.IsDELETE(c),
+		IsPasscode:  controller.IsPOST(c) && c.Path() == consts.PathAuthAdminAccountsEntityVerifyPasscodeAPI,
+		userAccount: controller.GetAccount(c),
+		webCtxt:     c,
+	}
+}
+
+func (x *AccountsVerifyAPIController) actorID() string {
+	if x.userAccount != nil {
+		return x.userAccount.ID
+	}
+	return ""
+}
+
+func (x *AccountsVerifyAPIController) contactValue() string {
+	if x.DTO.Input.Contact == service.ContactEmail {
+		return x.account.Email
+	}
+	return x.account.Tel
+}
+
+func (x *AccountsVerifyAPIController) validateDTOFields() (err error) {
+
+	dto := &x.DTO
+	input := &dto.Input
+	output := &dto.Output
+	meta := &dto.Meta
+	srv := x.appService.AuthAdmin()
+
+	if input.Contact != service.ContactEmail && input.Contact != service.ContactTel {
+		meta.Status = http.StatusNotFound // 404 unknown path
+		return nil
+	}
+
+	x.account, err = srv.UserAccounts().FindByID(input.ID)
+	if err != nil {
+		return err
+	}
+
+	if x.account == nil {
+		meta.Status = http.StatusNotFound // 404
+		return nil
+	}
+
+	if (x.IsPOST || x.IsPasscode) && x.contactValue() == "" {
+		output.AddError(input.Contact, x.userLang.Lang("Account has no {0}.", input.Contact))
+	}
+
+	if x.IsPOST && !x.IsPasscode && input.Passcode != "" && output.IsModelValid() {
+		ok, err := srv.UserAccounts().ValidateVerifyPasscode(x.account, input.Contact, input.Passcode)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			output.AddError("passcode", x.userLang.Lang("Passcode is invalid or already used."))
+		}
+	}
+
+	if !output.IsModelValid() {
+		meta.Status = http.StatusUnprocessableEntity // 422 validation
+		return nil
+	}
+
+	return nil
+
+}
+
+func (x *AccountsVerifyAPIController) validateDTO() error {
+
+	dto := &x.DTO
+	input := &dto.Input
+
+	c := x.webCtxt
+
+	if err := c.Bind(input); err != nil {
+		return err
+	}
+
+	return x.validateDTOFields()
+
+}
+
+func (x *AccountsVerifyAPIController) setVerified(verified bool, action string) (err error) {
+	dto := &x.DTO
+	input := &dto.Input
+	output := &dto.Output
+	srv := x.appService.AuthAdmin()
+
+	output.VerifiedAt, err = srv.UserAccounts().SetVerified(input.ID, input.Contact, verified)
+	if err != nil {
+		return err
+	}
+
+	if err = srv.AuditLogs().Add(x.actorID(), action, input.ID, "contact="+input.Contact); err != nil {
+		return err
+	}
+
+	output.Status = consts.StatusSuccess
+	return nil
+}
+
+func (x *AccountsVerifyAPIController) handlePasscode() (err error) {
+	dto := &x.DTO
+	input := &dto.Input
+	output := &dto.Output
+	srv := x.appService.AuthAdmin()
+
+	if err = srv.UserAccounts().SendVerifyPasscode(x.account, input.Contact, x.userLang.LangCode()); err != nil {
+		return err
+	}
+
+	if err = srv.AuditLogs().Add(x.actorID(), service.AuditActionContactPasscode, input.ID, "contact="+input.Contact); err != nil {
+		return err
+	}
+
+	output.Message = x.userLang.Lang("Passcode sent")
+	output.Status = consts.StatusSuccess
+	return nil
+}
+
+func (x *AccountsVerifyAPIController) handleDTO() error {
+
+	dto := &x.DTO
+	meta := &dto.Meta
+	output := &dto.Output
+
+	if meta.Status > 0 {
+		return nil // stop processing
+	}
+
+	switch {
+	case x.IsPasscode:
+		return x.handlePasscode()
+	case x.IsPOST:
+		return x.setVerified(true, service.AuditActionContactVerify)
+	case x.IsDELETE:
+		return x.setVerified(false, service.AuditActionContactUnverify)
+	default:
+		{
+			meta.Status = http.StatusMethodNotAllowed
+			output.Message = "method action undef"
+		}
+	}
+
+	return nil
+}
+func (x *AccountsVerifyAPIController) responseDTOAsAPI() (err error) {
+
+	dto := &x.DTO
+	meta := &dto.Meta
+	output := &dto.Output
+	c := x.webCtxt
+
+	if meta.Status == 0 {
+		meta.Status = http.StatusOK
+	}
+
+	return c.JSON(meta.Status, output)
+
+}
+
+func (x *AccountsVerifyAPIController) responseDTO() (err error) {
+	return x.responseDTOAsAPI()
+}
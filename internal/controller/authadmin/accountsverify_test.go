@@ -0,0 +1,83 @@
+package authadmin
+
+import (
+	"go-auth-admin/internal/config/consts"
+	"go-auth-admin/internal/service"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func serveVerifyPath(t *testing.T, appService service.AppService, path string, id string, body string) int {
+	t.Helper()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	c := e.NewContext(req, rec)
+	c.SetPath(path)
+	c.SetParamNames("id", "contact")
+	c.SetParamValues(id, service.ContactEmail)
+
+	if err := NewAccountsVerifyAPIController(appService, c).Handler(); err != nil {
+		t.Fatal(err)
+	}
+
+	return rec.Code
+}
+
+func TestAccountsVerifyPasscode(t *testing.T) {
+
+	passcodes := []string{}
+	failed := false
+
+	messengerService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failed {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		passcodes = append(passcodes, r.FormValue("passcode"))
+	}))
+	t.Cleanup(messengerService.Close)
+
+	t.Setenv("APP_OUTBOX_ENABLED", "false") // send synchronously
+	t.Setenv("APP_MESSENGER_SERVICE_URL", messengerService.URL+"/{code}")
+
+	appService := newTestAppService(t)
+	srv := appService.AuthAdmin().UserAccounts()
+
+	target := newTestAccount(t, appService, "target1", "")
+	target.Email = "target1@example.com"
+	if err := srv.Update(target); err != nil {
+		t.Fatal(err)
+	}
+
+	if code := serveVerifyPath(t, appService, consts.PathAuthAdminAccountsEntityVerifyPasscodeAPI, target.ID, ""); code != http.StatusOK {
+		t.Fatalf("send passcode status = %v, want %v", code, http.StatusOK)
+	}
+	if len(passcodes) != 1 || passcodes[0] == "" {
+		t.Fatalf("sent passcodes = %v, want one", passcodes)
+	}
+
+	if code := serveVerifyPath(t, appService, consts.PathAuthAdminAccountsEntityVerifyAPI, target.ID, `{"passcode":"`+passcodes[0]+`"}`); code != http.StatusOK {
+		t.Fatalf("submit passcode status = %v, want %v", code, http.StatusOK)
+	}
+
+	stored, err := srv.FindByID(target.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.EmailVerifiedAt == nil {
+		t.Error("email is not verified after passcode")
+	}
+
+	failed = true
+	if err = srv.SendVerifyPasscode(stored, service.ContactEmail, ""); err == nil {
+		t.Error("SendVerifyPasscode() error = nil, want delivery error")
+	}
+}
@@ -1,6 +1,7 @@
 package authadmin
 
 import (
+	controller "go-auth-admin/internal/controller"
 	"go-auth-admin/internal/service"
 	xweb "go-auth-admin/internal/web"
 	"net/http"
@@ -78,7 +79,7 @@ func (x *StatusAPIController) Handler() (err error) {
 	//
 	output.IsAuth = xweb.IsSignedIn(c)
 	//
-	// controller.CsrfToHeader(c)
+	controller.CsrfToHeader(c)
 	//
 	if meta.Status == 0 {
 		meta.Status = http.StatusOK
@@ -0,0 +1,176 @@
+package authadmin
+
+import (
+	"go-auth-admin/internal/config/consts"
+	xtoken "go-auth-admin/internal/token"
+	xweb "go-auth-admin/internal/web"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestStatusCsrf(t *testing.T) {
+
+	appService := newTestAppService(t)
+
+	e := echo.New()
+	e.Use(xweb.CsrfMiddleware(appService))
+	e.GET(consts.PathAuthAdminStatusAPI, func(c echo.Context) error {
+		return NewStatusAPIController(appService, c).Handler()
+	})
+	e.POST(consts.PathAuthAdminAccountsAPI, func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	e.POST("/sys/api/config/reload", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, consts.PathAuthAdminStatusAPI, nil))
+
+	token := rec.Header().Get(xweb.CsrfHeader)
+	cookie := responseCookie(rec, xweb.CsrfKey)
+	if token == "" || cookie == nil || cookie.Value != token {
+		t.Fatalf("status token = %q, cookie = %v, want same token", token, cookie)
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		header map[string]string
+		cookie bool
+		want   int
+	}{
+		{name: "no token", target: consts.PathAuthAdminAccountsAPI, cookie: true, want: http.StatusBadRequest},
+		{name: "wrong token", target: consts.PathAuthAdminAccountsAPI, cookie: true, header: map[string]string{xweb.CsrfHeader: "x"}, want: http.StatusForbidden},
+		{name: "no cookie", target: consts.PathAuthAdminAccountsAPI, header: map[string]string{xweb.CsrfHeader: token}, want: http.StatusForbidden},
+		{name: "token", target: consts.PathAuthAdminAccountsAPI, cookie: true, header: map[string]string{xweb.CsrfHeader: token}, want: http.StatusOK},
+		{name: "bearer", target: consts.PathAuthAdminAccountsAPI, header: map[string]string{echo.HeaderAuthorization: "Bearer abc"}, want: http.StatusOK},
+		{name: "not api", target: "/sys/api/config/reload", want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, tt.target, nil)
+		for k, v := range tt.header {
+			req.Header.Set(k, v)
+		}
+		if tt.cookie {
+			req.AddCookie(&http.Cookie{Name: xweb.CsrfKey, Value: token})
+		}
+		rec := httptest.NewRecorder()
+
+		e.ServeHTTP(rec, req)
+
+		if rec.Code != tt.want {
+			t.Errorf("%v: code = %v, want %v", tt.name, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestStatusCsrfSecure(t *testing.T) {
+
+	appService := newTestAppService(t)
+
+	e := echo.New()
+	e.Use(xweb.CsrfMiddleware(appService))
+	e.GET(consts.PathAuthAdminStatusAPI, func(c echo.Context) error {
+		return NewStatusAPIController(appService, c).Handler()
+	})
+
+	tests := []struct {
+		name   string
+		header map[string]string
+		want   bool
+	}{
+		{name: "http", want: false},
+		{name: "https proxy", header: map[string]string{echo.HeaderXForwardedProto: "https"}, want: true},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, consts.PathAuthAdminStatusAPI, nil)
+		for k, v := range tt.header {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+
+		e.ServeHTTP(rec, req)
+
+		cookie := responseCookie(rec, xweb.CsrfKey)
+		if cookie == nil {
+			t.Fatalf("%v: no csrf cookie", tt.name)
+		}
+		if cookie.Secure != tt.want {
+			t.Errorf("%v: secure = %v, want %v", tt.name, cookie.Secure, tt.want)
+		}
+	}
+}
+
+func TestStatusBearer(t *testing.T) {
+
+	appService := newTestAppService(t)
+	target := newTestAccount(t, appService, "target1", "")
+
+	e := echo.New()
+	e.Use(xweb.TokenParserMiddleware(appService))
+	e.GET(consts.PathAuthAdminStatusAPI, func(c echo.Context) error {
+		return NewStatusAPIController(appService, c).Handler()
+	})
+
+	token := newTestToken(t, appService, &xtoken.TokenClaimsDTO{UserID: target.ID})
+
+	for header, want := range map[string]string{
+		"Bearer " + token: `{"is_auth":true}`,
+		"Bearer x":        `{}`,
+		"":                `{}`,
+	} {
+		req := httptest.NewRequest(http.MethodGet, consts.PathAuthAdminStatusAPI, nil)
+		req.Header.Set(echo.HeaderAuthorization, header)
+		rec := httptest.NewRecorder()
+
+		e.ServeHTTP(rec, req)
+
+		if got := strings.TrimSpace(rec.Body.String()); got != want {
+			t.Errorf("authorization %.12q: status = %v, want %v", header, got, want)
+		}
+	}
+}
+
+func TestStatusBearerWithCookie(t *testing.T) {
+
+	appService := newTestAppService(t)
+	target := newTestAccount(t, appService, "target1", "")
+
+	e := echo.New()
+	e.Use(xweb.TokenParserMiddleware(appService))
+	e.Use(xweb.CsrfMiddleware(appService))
+	e.POST(consts.PathAuthAdminAccountsAPI, func(c echo.Context) error {
+		if xweb.UserID(c) == "" {
+			return c.NoContent(http.StatusUnauthorized)
+		}
+		return c.NoContent(http.StatusOK)
+	})
+
+	token := newTestToken(t, appService, &xtoken.TokenClaimsDTO{UserID: target.ID})
+
+	for _, tt := range []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{name: "junk bearer", header: "Bearer x", want: http.StatusUnauthorized}, // csrf skipped, cookie not used
+		{name: "no bearer", header: "", want: http.StatusBadRequest},             // csrf token missing
+		{name: "bearer", header: "Bearer " + token, want: http.StatusOK},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, consts.PathAuthAdminAccountsAPI, nil)
+			req.Header.Set(echo.HeaderAuthorization, tt.header)
+			req.AddCookie(&http.Cookie{Name: xweb.JwtKey, Value: token})
+			rec := httptest.NewRecorder()
+
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("code = %v, want %v", rec.Code, tt.want)
+			}
+		})
+	}
+}
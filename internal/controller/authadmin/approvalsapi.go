@@ -0,0 +1,138 @@
+package authadmin
+
+import (
+	"go-auth-admin/internal/config"
+	controller "go-auth-admin/internal/controller"
+	"go-auth-admin/internal/util/utilpaging"
+
+	"go-auth-admin/internal/i18n"
+	"go-auth-admin/internal/service"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type ApprovalsDTO struct {
+	Input struct {
+		utilpaging.PagingInputDTO
+		Status   string `query:"status"` // pending approved rejected expired
+		Action   string `query:"action"`
+		TargetID string `query:"target_id"`
+	}
+	Meta struct {
+		Status int
+	}
+	Output struct {
+		Message string `json:"message,omitempty"`
+		utilpaging.PagingOutputDTO[service.ChangeRequest]
+	}
+}
+
+type ApprovalsAPIController struct {
+	appService service.AppService
+	appConfig  *config.AppConfig
+	userLang   i18n.UserLang
+
+	IsGET bool
+
+	webCtxt echo.Context // webCtxt
+
+	DTO ApprovalsDTO
+}
+
+func (x *ApprovalsAPIController) Handler() error {
+
+	err := x.validateDTO()
+	if err != nil {
+		return err
+	}
+
+	err = x.handleDTO()
+	if err != nil {
+		return err
+	}
+
+	err = x.responseDTO()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// NewApprovalsAPIController is constructor.
+func NewApprovalsAPIController(appService service.AppService, c echo.Context) *ApprovalsAPIController {
+
+	appConfig := appService.Config()
+
+	return &ApprovalsAPIController{
+		appService: appService,
+		appConfig:  appConfig,
+		userLang:   controller.UserLang(c, appService),
+		IsGET:      controller.IsGET(c),
+		webCtxt:    c,
+	}
+}
+
+func (x *ApprovalsAPIController) validateDTO() error {
+
+	dto := &x.DTO
+	input := &dto.Input
+
+	c := x.webCtxt
+
+	if err := c.Bind(input); err != nil {
+		return err
+	}
+
+	input.Filters = utilpaging.Filters{
+		"status": input.Status,
+		"action": input.Action,
+		"target": input.TargetID,
+	}
+
+	return nil
+}
+
+func (x *ApprovalsAPIController) handleDTO() error {
+
+	dto := &x.DTO
+	input := &dto.Input
+	meta := &dto.Meta
+	output := &dto.Output
+
+	if x.IsGET {
+
+		bs := x.appService.AuthAdmin()
+
+		if err := bs.ChangeRequests().Query(&input.PagingInputDTO, &output.PagingOutputDTO); err != nil {
+			return err
+		}
+
+	} else {
+		meta.Status = http.StatusMethodNotAllowed
+		output.Message = "method action undef"
+	}
+
+	return nil
+}
+func (x *ApprovalsAPIController) responseDTOAsAPI() (err error) {
+
+	dto := &x.DTO
+	meta := &dto.Meta
+	output := &dto.Output
+	c := x.webCtxt
+
+	if meta.Status == 0 {
+		meta.Status = http.StatusOK
+	}
+
+	return c.JSON(meta.Status, output)
+
+}
+
+func (x *ApprovalsAPIController) responseDTO() (err error) {
+
+	return x.responseDTOAsAPI()
+
+}
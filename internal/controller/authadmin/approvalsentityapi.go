@@ -0,0 +1,415 @@
+package authadmin
+
+import (
+	"errors"
+	"go-auth-admin/internal/config"
+	"go-auth-admin/internal/config/consts"
+	controller "go-auth-admin/internal/controller"
+	"go-auth-admin/internal/mvc"
+	"go-auth-admin/internal/util/utilaccess"
+	"slices"
+
+	"strings"
+
+	"go-auth-admin/internal/i18n"
+	"go-auth-admin/internal/service"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type ChangeRequestDTO struct {
+	service.ChangeRequest
+}
+type ApprovalsEntityDTO struct {
+	Input struct {
+		ID string `param:"id"`
+	}
+	Meta struct {
+		Status int
+	}
+	Output struct {
+		mvc.ModelBaseDTO
+		Data    ChangeRequestDTO `json:"data,omitempty"`
+		Expired []string         `json:"expired,omitempty"` // POST /expire
+	}
+}
+type ApprovalsEntityAPIController struct {
+	appService service.AppService
+	appConfig  *config.AppConfig
+	userLang   i18n.UserLang
+
+	IsGET     bool
+	IsApprove bool // POST /:id/approve
+	IsReject  bool // POST /:id/reject
+	IsExpire  bool // POST /expire
+
+	webCtxt echo.Context // webCtxt
+
+	userAccount *service.UserAccount // actor
+	request     *service.ChangeRequest
+
+	DTO ApprovalsEntityDTO
+}
+
+func (x *ApprovalsEntityAPIController) Handler() error {
+
+	err := x.validateDTO()
+	if err != nil {
+		return err
+	}
+
+	err = x.handleDTO()
+	if err != nil {
+		return err
+	}
+
+	err = x.responseDTO()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// NewApprovalsEntityAPIController is constructor.
+func NewApprovalsEntityAPIController(appService service.AppService, c echo.Context) *ApprovalsEntityAPIController {
+
+	appConfig := appService.Config()
+
+	isPOST := controller.IsPOST(c)
+
+	return &ApprovalsEntityAPIController{
+		appService:  appService,
+		appConfig:   appConfig,
+		userLang:    controller.UserLang(c, appService),
+		IsGET:       controller.IsGET(c),
+		IsApprove:   isPOST && c.Path() == consts.PathAuthAdminApprovalsEntityApproveAPI,
+		IsReject:    isPOST && c.Path() == consts.PathAuthAdminApprovalsEntityRejectAPI,
+		IsExpire:    isPOST && c.Path() == consts.PathAuthAdminApprovalsExpireAPI,
+		userAccount: controller.GetAccount(c),
+		webCtxt:     c,
+	}
+}
+
+func (x *ApprovalsEntityAPIController) actorID() string {
+	if x.userAccount != nil {
+		return x.userAccount.ID
+	}
+	return ""
+}
+
+func (x *ApprovalsEntityAPIController) validateDTOFields() (err error) {
+
+	dto := &x.DTO
+	input := &dto.Input
+	output := &dto.Output
+	meta := &dto.Meta
+	srv := x.appService.AuthAdmin()
+
+	if x.IsExpire {
+		return nil
+	}
+
+	// exists: get, approve, reject
+
+	x.request, err = srv.ChangeRequests().FindByID(input.ID)
+	if err != nil {
+		return err
+	}
+
+	if x.request == nil {
+		meta.Status = http.StatusNotFound // 404
+		return nil
+	}
+
+	if x.IsApprove || x.IsReject {
+
+		if !x.request.IsPending() {
+			meta.Status = http.StatusConflict // 409
+			output.AddError("", x.userLang.Lang("Change request is not pending."))
+			return nil
+		}
+
+	}
+
+	if x.IsApprove && x.request.CreatedBy == x.actorID() {
+		meta.Status = http.StatusForbidden // 403, four-eyes
+		output.AddError("", x.userLang.Lang("Change request cannot be approved by its requester."))
+		return nil
+	}
+
+	if x.IsApprove {
+
+		roles, err := x.requestRoles()
+		if err != nil {
+			return err
+		}
+
+		var actorRoles []string
+		if x.userAccount != nil {
+			actorRoles = x.userAccount.EffectiveRoleCodes()
+		}
+
+		for _, code := range utilaccess.NotHeldRoles(actorRoles, roles...) {
+			meta.Status = http.StatusForbidden // 403, approver holds what is granted or revoked
+			output.AddError("", x.userLang.Lang("Role {0} cannot be granted or revoked without holding it.", code))
+		}
+	}
+
+	return nil
+
+}
+
+func (x *ApprovalsEntityAPIController) validateDTO() error {
+
+	dto := &x.DTO
+	input := &dto.Input
+
+	c := x.webCtxt
+
+	if err := c.Bind(input); err != nil {
+		return err
+	}
+
+	return x.validateDTOFields()
+
+}
+func (x *ApprovalsEntityAPIController) handleGET() (err error) {
+	dto := &x.DTO
+	output := &dto.Output
+
+	output.Data.ChangeRequest = *x.request // copy
+
+	return nil
+}
+
+func (x *ApprovalsEntityAPIController) handleApprove() (err error) {
+	dto := &x.DTO
+	output := &dto.Output
+	srv := x.appService.AuthAdmin()
+
+	data := &output.Data.ChangeRequest
+	*data = *x.request // copy
+
+	err = srv.ChangeRequests().Approve(data, x.actorID())
+	if isLastAdmin(err, x.userLang, &output.ModelBaseDTO, &dto.Meta.Status) {
+		return nil
+	}
+	if x.isNotPending(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = srv.AuditLogs().Add(x.actorID(), service.AuditActionApprovalApprove, data.TargetID, x.details(data)); err != nil {
+		return err
+	}
+
+	output.Status = consts.StatusSuccess
+	return nil
+}
+
+func (x *ApprovalsEntityAPIController) handleReject() (err error) {
+	dto := &x.DTO
+	output := &dto.Output
+	srv := x.appService.AuthAdmin()
+
+	data := &output.Data.ChangeRequest
+	*data = *x.request // copy
+
+	err = srv.ChangeRequests().Reject(data, x.actorID())
+	if x.isNotPending(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = srv.AuditLogs().Add(x.actorID(), service.AuditActionApprovalReject, data.TargetID, x.details(data)); err != nil {
+		return err
+	}
+
+	output.Status = consts.StatusSuccess
+	return nil
+}
+
+func (x *ApprovalsEntityAPIController) handleExpire() (err error) {
+	dto := &x.DTO
+	output := &dto.Output
+	srv := x.appService.AuthAdmin()
+
+	output.Expired, err = srv.ChangeRequests().Expire()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range output.Expired {
+		if err = srv.AuditLogs().Add(x.actorID(), service.AuditActionApprovalExpire, "", "request="+id); err != nil {
+			return err
+		}
+	}
+
+	output.Status = consts.StatusSuccess
+	return nil
+}
+
+// isNotPending concurrent decision or expired meanwhile
+func (x *ApprovalsEntityAPIController) isNotPending(err error) bool {
+
+	if !errors.Is(err, service.ErrChangeRequestNotPending) {
+		return false
+	}
+
+	x.DTO.Meta.Status = http.StatusConflict // 409
+	x.DTO.Output.AddError("", x.userLang.Lang("Change request is not pending."))
+
+	return true
+}
+
+// requestRoles roles granted or revoked by approving the request
+func (x *ApprovalsEntityAPIController) requestRoles() ([]string, error) {
+
+	srv := x.appService.AuthAdmin()
+
+	if x.request.Action == service.ChangeActionAccountDelete {
+
+		account, err := srv.UserAccounts().FindByID(x.request.TargetID)
+		if err != nil || account == nil {
+			return nil, err
+		}
+
+		return strings.Fields(account.Roles), nil
+	}
+
+	payload, err := x.request.GetPayload()
+	if err != nil {
+		return nil, err
+	}
+
+	if payload.GroupID != "" {
+
+		group, err := srv.Groups().FindByID(payload.GroupID)
+		if err != nil || group == nil {
+			return nil, err
+		}
+
+		return strings.Fields(group.Roles), nil
+	}
+
+	return payload.Roles, nil
+}
+
+func (x *ApprovalsEntityAPIController) details(data *service.ChangeRequest) string {
+	return strings.Join([]string{"request=" + data.ID, "action=" + data.Action, "requester=" + data.CreatedBy}, " ")
+}
+
+func (x *ApprovalsEntityAPIController) handleDTO() error {
+
+	dto := &x.DTO
+	meta := &dto.Meta
+	output := &dto.Output
+
+	if meta.Status > 0 {
+		return nil // stop processing
+	}
+
+	switch {
+
+	case x.IsGET:
+		return x.handleGET()
+	case x.IsApprove:
+		return x.handleApprove()
+	case x.IsReject:
+		return x.handleReject()
+	case x.IsExpire:
+		return x.handleExpire()
+	default:
+		{
+			meta.Status = http.StatusMethodNotAllowed
+			output.AddError("", "method action undef")
+		}
+	}
+
+	return nil
+}
+func (x *ApprovalsEntityAPIController) responseDTOAsAPI() (err error) {
+
+	dto := &x.DTO
+	meta := &dto.Meta
+	output := &dto.Output
+	c := x.webCtxt
+
+	if meta.Status == 0 {
+		meta.Status = http.StatusOK
+	}
+
+	return c.JSON(meta.Status, output)
+
+}
+
+func (x *ApprovalsEntityAPIController) responseDTO() (err error) {
+
+	return x.responseDTOAsAPI()
+
+}
+
+// grantAdminPayload change request adding admin role
+func grantAdminPayload() *service.ChangeRequestPayload {
+	return &service.ChangeRequestPayload{Roles: []string{consts.RoleAdmin}}
+}
+
+// withoutAdmin space-separated roles except admin
+func withoutAdmin(roles string) string {
+	return strings.Join(slices.DeleteFunc(strings.Fields(roles), utilaccess.IsAdmin), " ")
+}
+
+// requestApproval stores change as pending request when action type needs approval,
+// returns nil if change may run now, sets 202 Accepted otherwise
+func requestApproval(appService service.AppService, userLang i18n.UserLang, output *mvc.ModelBaseDTO, status *int,
+	actor *service.UserAccount, action string, targetID string, payload *service.ChangeRequestPayload) (*service.ChangeRequest, error) {
+
+	srv := appService.AuthAdmin()
+
+	if !srv.ChangeRequests().IsRequired(action) {
+		return nil, nil
+	}
+
+	id, err := srv.ChangeRequests().Pending(action, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	if id != "" {
+		*status = http.StatusConflict // 409
+		output.AddError("", userLang.Lang("Change request is already pending."))
+		return &service.ChangeRequest{ID: id}, nil
+	}
+
+	data := &service.ChangeRequest{
+		Action:   action,
+		TargetID: targetID,
+	}
+
+	if actor != nil {
+		data.CreatedBy = actor.ID
+	}
+
+	if err = data.SetPayload(payload); err != nil {
+		return nil, err
+	}
+
+	if err = srv.ChangeRequests().Create(data); err != nil {
+		return nil, err
+	}
+
+	if err = srv.AuditLogs().Add(data.CreatedBy, service.AuditActionApprovalRequest, targetID, "request="+data.ID+" action="+action); err != nil {
+		return nil, err
+	}
+
+	*status = http.StatusAccepted // 202, waits for approval
+	output.Message = userLang.Lang("Change request is waiting for approval.")
+
+	return data, nil
+}
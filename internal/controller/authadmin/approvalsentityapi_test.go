@@ -0,0 +1,408 @@
+package authadmin
+
+import (
+	"go-auth-admin/internal/config/consts"
+	"go-auth-admin/internal/service"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func serveApprovalsEntity(t *testing.T, appService service.AppService, actor *service.UserAccount, path string, id string) int {
+	t.Helper()
+
+	e := echo.New()
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	rec := httptest.NewRecorder()
+
+	c := e.NewContext(req, rec)
+	c.SetPath(path)
+	c.SetParamNames("id")
+	c.SetParamValues(id)
+	c.Set("user_account", actor)
+
+	if err := NewApprovalsEntityAPIController(appService, c).Handler(); err != nil {
+		t.Fatal(err)
+	}
+
+	return rec.Code
+}
+
+func pendingChangeRequest(t *testing.T, appService service.AppService, action string, targetID string) *service.ChangeRequest {
+	t.Helper()
+
+	srv := appService.AuthAdmin().ChangeRequests()
+
+	id, err := srv.Pending(action, targetID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := srv.FindByID(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res == nil {
+		t.Fatalf("change request %v %v not found", action, targetID)
+	}
+
+	return res
+}
+
+func TestApprovalsGrantAdmin(t *testing.T) {
+
+	appService := newTestAppService(t)
+	appService.Config().Approval.Actions = []string{service.ChangeActionAccountGrantAdmin}
+
+	admin := newTestAccount(t, appService, "admin1", "admin")
+	approver := newTestAccount(t, appService, "approver1", "admin auth_approve")
+	target := newTestAccount(t, appService, "target1", "auth_access")
+
+	if status := serveAccountsEntity(t, appService, admin, http.MethodPut, target.ID, accountBody(target, "auth_access admin")); status != http.StatusAccepted {
+		t.Fatalf("grant admin status = %v, want %v", status, http.StatusAccepted)
+	}
+
+	if status := serveAccountsEntity(t, appService, admin, http.MethodPut, target.ID, accountBody(target, "auth_access admin")); status != http.StatusConflict {
+		t.Errorf("grant admin again status = %v, want %v", status, http.StatusConflict)
+	}
+
+	stored, err := appService.AuthAdmin().UserAccounts().FindByID(target.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Roles != "auth_access" {
+		t.Errorf("roles before approve = %q, want unchanged", stored.Roles)
+	}
+
+	req := pendingChangeRequest(t, appService, service.ChangeActionAccountGrantAdmin, target.ID)
+
+	if status := serveApprovalsEntity(t, appService, admin, consts.PathAuthAdminApprovalsEntityApproveAPI, req.ID); status != http.StatusForbidden {
+		t.Errorf("self approve status = %v, want %v", status, http.StatusForbidden)
+	}
+
+	if status := serveApprovalsEntity(t, appService, approver, consts.PathAuthAdminApprovalsEntityApproveAPI, req.ID); status != http.StatusOK {
+		t.Fatalf("approve status = %v, want %v", status, http.StatusOK)
+	}
+
+	if status := serveApprovalsEntity(t, appService, approver, consts.PathAuthAdminApprovalsEntityRejectAPI, req.ID); status != http.StatusConflict {
+		t.Errorf("reject approved status = %v, want %v", status, http.StatusConflict)
+	}
+
+	stored, err = appService.AuthAdmin().UserAccounts().FindByID(target.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Roles != "admin auth_access" {
+		t.Errorf("roles after approve = %q, want admin granted", stored.Roles)
+	}
+
+	var count int64
+	appService.Repository().Model(&service.AuditLog{}).
+		Where("action = ? and actor_id = ? and target_id = ?", service.AuditActionApprovalApprove, approver.ID, target.ID).
+		Count(&count)
+
+	if count != 1 {
+		t.Errorf("approve audit count = %v, want 1", count)
+	}
+}
+
+func TestApprovalsDeleteRejectExpire(t *testing.T) {
+
+	appService := newTestAppService(t)
+	appService.Config().Approval.Actions = []string{service.ChangeActionAccountDelete}
+
+	admin := newTestAccount(t, appService, "admin1", "admin")
+	approver := newTestAccount(t, appService, "approver1", "auth_access auth_approve")
+	target := newTestAccount(t, appService, "target1", "auth_access")
+
+	if status := serveAccountsEntity(t, appService, admin, http.MethodDelete, target.ID, ""); status != http.StatusAccepted {
+		t.Fatalf("delete status = %v, want %v", status, http.StatusAccepted)
+	}
+
+	req := pendingChangeRequest(t, appService, service.ChangeActionAccountDelete, target.ID)
+
+	if status := serveApprovalsEntity(t, appService, approver, consts.PathAuthAdminApprovalsEntityRejectAPI, req.ID); status != http.StatusOK {
+		t.Fatalf("reject status = %v, want %v", status, http.StatusOK)
+	}
+
+	if status := serveAccountsEntity(t, appService, admin, http.MethodDelete, target.ID, ""); status != http.StatusAccepted {
+		t.Fatalf("delete again status = %v, want %v", status, http.StatusAccepted)
+	}
+
+	req = pendingChangeRequest(t, appService, service.ChangeActionAccountDelete, target.ID)
+
+	// overdue
+	appService.Repository().Model(req).Update("expires_at", req.CreatedAt)
+
+	if status := serveApprovalsEntity(t, appService, approver, consts.PathAuthAdminApprovalsEntityApproveAPI, req.ID); status != http.StatusConflict {
+		t.Errorf("approve overdue status = %v, want %v", status, http.StatusConflict)
+	}
+
+	if status := serveApprovalsEntity(t, appService, approver, consts.PathAuthAdminApprovalsExpireAPI, ""); status != http.StatusOK {
+		t.Errorf("expire status = %v, want %v", status, http.StatusOK)
+	}
+
+	expired, err := appService.AuthAdmin().ChangeRequests().FindByID(req.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expired.Status != service.ChangeRequestStatusExpired {
+		t.Errorf("status = %v, want %v", expired.Status, service.ChangeRequestStatusExpired)
+	}
+
+	if stored, _ := appService.AuthAdmin().UserAccounts().FindByID(target.ID); stored == nil {
+		t.Errorf("account deleted without approval")
+	}
+}
+
+func TestApprovalsGrantAdminOnCreate(t *testing.T) {
+
+	appService := newTestAppService(t)
+	appService.Config().Approval.Actions = []string{service.ChangeActionAccountGrantAdmin}
+
+	admin := newTestAccount(t, appService, "admin1", "admin")
+
+	body := `{"data":{"username":"target1","roles":"auth_access admin"}}`
+	if status := serveAccountsEntity(t, appService, admin, http.MethodPost, "", body); status != http.StatusAccepted {
+		t.Fatalf("create with admin status = %v, want %v", status, http.StatusAccepted)
+	}
+
+	srv := appService.AuthAdmin().UserAccounts()
+
+	id, err := srv.Username("target1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := srv.FindByID(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored == nil || stored.Roles != "auth_access" {
+		t.Fatalf("created account = %+v, want roles without admin", stored)
+	}
+
+	req := pendingChangeRequest(t, appService, service.ChangeActionAccountGrantAdmin, id)
+	if req.CreatedBy != admin.ID {
+		t.Errorf("requester = %v, want %v", req.CreatedBy, admin.ID)
+	}
+}
+
+func TestApprovalsGrantAdminCurrentState(t *testing.T) {
+
+	appService := newTestAppService(t)
+	appService.Config().Approval.Actions = []string{service.ChangeActionAccountGrantAdmin}
+
+	admin := newTestAccount(t, appService, "admin1", "admin")
+	approver := newTestAccount(t, appService, "approver1", "admin auth_approve")
+	target := newTestAccount(t, appService, "target1", "auth_access")
+
+	// rest of change is made now
+	if status := serveAccountsEntity(t, appService, admin, http.MethodPut, target.ID, accountBody(target, "admin auth_access auth_view")); status != http.StatusAccepted {
+		t.Fatalf("grant admin status = %v, want %v", status, http.StatusAccepted)
+	}
+
+	srv := appService.AuthAdmin().UserAccounts()
+
+	stored, err := srv.FindByID(target.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Roles != "auth_access auth_view" {
+		t.Errorf("roles before approve = %q, want change without admin", stored.Roles)
+	}
+
+	// changed while pending
+	stored.Roles = "auth_view"
+	stored.Email = "target1@example.com"
+	if err = srv.Update(stored); err != nil {
+		t.Fatal(err)
+	}
+
+	req := pendingChangeRequest(t, appService, service.ChangeActionAccountGrantAdmin, target.ID)
+
+	if status := serveApprovalsEntity(t, appService, approver, consts.PathAuthAdminApprovalsEntityApproveAPI, req.ID); status != http.StatusOK {
+		t.Fatalf("approve status = %v, want %v", status, http.StatusOK)
+	}
+
+	stored, err = srv.FindByID(target.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Roles != "admin auth_view" || stored.Email != "target1@example.com" {
+		t.Errorf("account after approve = %q %q, want admin added to current state", stored.Roles, stored.Email)
+	}
+}
+
+func TestApprovalsApproveRollback(t *testing.T) {
+
+	appService := newTestAppService(t)
+	appService.Config().Approval.Actions = []string{service.ChangeActionAccountGrantAdmin}
+
+	admin := newTestAccount(t, appService, "admin1", "admin")
+	approver := newTestAccount(t, appService, "approver1", "admin auth_approve")
+	target := newTestAccount(t, appService, "target1", "auth_access")
+
+	if status := serveAccountsEntity(t, appService, admin, http.MethodPut, target.ID, accountBody(target, "admin auth_access")); status != http.StatusAccepted {
+		t.Fatalf("grant admin status = %v, want %v", status, http.StatusAccepted)
+	}
+
+	if err := appService.AuthAdmin().UserAccounts().Delete(target.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := appService.AuthAdmin().ChangeRequests()
+
+	req := pendingChangeRequest(t, appService, service.ChangeActionAccountGrantAdmin, target.ID)
+
+	if err := srv.Approve(req, approver.ID); err == nil {
+		t.Fatal("Approve() of deleted target error = nil, want error")
+	}
+
+	stored, err := srv.FindByID(req.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != service.ChangeRequestStatusPending || stored.DecidedBy != "" || req.Status != service.ChangeRequestStatusPending {
+		t.Errorf("request after failed approve = %v %q, want pending", stored.Status, stored.DecidedBy)
+	}
+}
+
+func serveGroupsEntity(t *testing.T, appService service.AppService, actor *service.UserAccount, method string, id string, body string) int {
+	t.Helper()
+
+	e := echo.New()
+
+	req := httptest.NewRequest(method, "/", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(id)
+	c.Set("user_account", actor)
+
+	if err := NewGroupsEntityAPIController(appService, c).Handler(); err != nil {
+		t.Fatal(err)
+	}
+
+	return rec.Code
+}
+
+func TestApprovalsGroupGrantAdmin(t *testing.T) {
+
+	appService := newTestAppService(t)
+	appService.Config().Approval.Actions = []string{service.ChangeActionAccountGrantAdmin}
+
+	admin := newTestAccount(t, appService, "admin1", "admin")
+	approver := newTestAccount(t, appService, "approver1", "admin auth_approve")
+	target := newTestAccount(t, appService, "target1", "")
+
+	srv := appService.AuthAdmin()
+
+	group := &service.AccountGroup{Name: "group1", Roles: "auth_access"}
+	if err := srv.Groups().Create(group); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Groups().AddMember(group.ID, target.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	body := `{"data":{"id":"` + group.ID + `","name":"group2","roles":"admin auth_access"}}`
+	if status := serveGroupsEntity(t, appService, admin, http.MethodPut, group.ID, body); status != http.StatusAccepted {
+		t.Fatalf("group grant admin status = %v, want %v", status, http.StatusAccepted)
+	}
+
+	stored, err := srv.Groups().FindByID(group.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Name != "group2" || stored.Roles != "auth_access" {
+		t.Errorf("group before approve = %q %q, want renamed without admin", stored.Name, stored.Roles)
+	}
+
+	req := pendingChangeRequest(t, appService, service.ChangeActionGroupGrantAdmin, group.ID)
+
+	if status := serveApprovalsEntity(t, appService, approver, consts.PathAuthAdminApprovalsEntityApproveAPI, req.ID); status != http.StatusOK {
+		t.Fatalf("approve status = %v, want %v", status, http.StatusOK)
+	}
+
+	member, err := srv.UserAccounts().FindByID(target.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !member.HasAnyOfRoles("admin") || slices.Contains(member.RoleCodes(), "admin") {
+		t.Errorf("member roles = %q by group %q, want admin by group", member.Roles, member.EffectiveRoles)
+	}
+}
+
+func TestApprovalsDeleteLastAdmin(t *testing.T) {
+
+	appService := newTestAppService(t)
+	appService.Config().Approval.Actions = []string{service.ChangeActionAccountDelete}
+
+	admin := newTestAccount(t, appService, "admin1", "admin")
+	approver := newTestAccount(t, appService, "approver1", "admin auth_approve")
+
+	if status := serveAccountsEntity(t, appService, admin, http.MethodDelete, approver.ID, ""); status != http.StatusAccepted {
+		t.Fatalf("delete status = %v, want %v", status, http.StatusAccepted)
+	}
+
+	req := pendingChangeRequest(t, appService, service.ChangeActionAccountDelete, approver.ID)
+
+	// requester left meanwhile, approver is the last admin
+	if err := appService.AuthAdmin().UserAccounts().Delete(admin.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if status := serveApprovalsEntity(t, appService, approver, consts.PathAuthAdminApprovalsEntityApproveAPI, req.ID); status != http.StatusConflict {
+		t.Errorf("approve delete of last admin status = %v, want %v", status, http.StatusConflict)
+	}
+
+	if stored, _ := appService.AuthAdmin().UserAccounts().FindByID(approver.ID); stored == nil {
+		t.Error("last admin deleted")
+	}
+
+	pendingChangeRequest(t, appService, service.ChangeActionAccountDelete, approver.ID) // still pending
+}
+
+func TestApprovalsApproverRoles(t *testing.T) {
+
+	appService := newTestAppService(t)
+	appService.Config().Approval.Actions = []string{service.ChangeActionAccountGrantAdmin, service.ChangeActionAccountDelete}
+
+	admin := newTestAccount(t, appService, "admin1", "admin")
+	approver := newTestAccount(t, appService, "approver1", "auth_approve")
+	target := newTestAccount(t, appService, "target1", "auth_access")
+	other := newTestAccount(t, appService, "admin2", "admin")
+
+	if status := serveAccountsEntity(t, appService, admin, http.MethodPut, target.ID, accountBody(target, "admin auth_access")); status != http.StatusAccepted {
+		t.Fatalf("grant admin status = %v, want %v", status, http.StatusAccepted)
+	}
+	if status := serveAccountsEntity(t, appService, admin, http.MethodDelete, other.ID, ""); status != http.StatusAccepted {
+		t.Fatalf("delete admin status = %v, want %v", status, http.StatusAccepted)
+	}
+
+	srv := appService.AuthAdmin().UserAccounts()
+
+	for _, req := range []*service.ChangeRequest{
+		pendingChangeRequest(t, appService, service.ChangeActionAccountGrantAdmin, target.ID),
+		pendingChangeRequest(t, appService, service.ChangeActionAccountDelete, other.ID),
+	} {
+		if status := serveApprovalsEntity(t, appService, approver, consts.PathAuthAdminApprovalsEntityApproveAPI, req.ID); status != http.StatusForbidden {
+			t.Errorf("approve %v without admin status = %v, want %v", req.Action, status, http.StatusForbidden)
+		}
+	}
+
+	if stored, _ := srv.FindByID(target.ID); stored == nil || stored.Roles != "auth_access" {
+		t.Errorf("target after approve = %+v, want admin not granted", stored)
+	}
+	if stored, _ := srv.FindByID(other.ID); stored == nil {
+		t.Error("admin deleted by approver without admin")
+	}
+}
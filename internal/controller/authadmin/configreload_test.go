@@ -0,0 +1,40 @@
+package authadmin
+
+import (
+	"testing"
+)
+
+func TestConfigReload(t *testing.T) {
+
+	appService := newTestAppService(t)
+
+	before := appService.Messenger()
+
+	if err := appService.ConfigSource().Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	if appService.Messenger() == before {
+		t.Error("messenger is not rebuilt on reload")
+	}
+
+	if err := appService.Messenger().Send("email-invite", "reload@example.com", "en", map[string]string{"link": "x"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := outboxKinds(t, appService, "reload@example.com"); len(got) != 1 {
+		t.Errorf("outbox = %v, want reloaded messenger to enqueue", got)
+	}
+
+	appService.Config().Title = "kept"
+
+	t.Setenv("APP_DB_MAX_OPEN", "not int") // env error rejects reload
+
+	if err := appService.ConfigSource().Reload(); err == nil {
+		t.Fatal("Reload() error = nil, want error")
+	}
+
+	if got := appService.Config().Title; got != "kept" {
+		t.Errorf("title = %v, want current config kept", got)
+	}
+}
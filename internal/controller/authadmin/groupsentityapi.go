@@ -0,0 +1,361 @@
+package authadmin
+
+import (
+	"go-auth-admin/internal/config"
+	"go-auth-admin/internal/config/consts"
+	controller "go-auth-admin/internal/controller"
+	"go-auth-admin/internal/mvc"
+	"go-auth-admin/internal/util/utilaccess"
+
+	"slices"
+	"strings"
+
+	"go-auth-admin/internal/i18n"
+	"go-auth-admin/internal/service"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type GroupDTO struct {
+	service.AccountGroup
+}
+type GroupsEntityDTO struct {
+	Input struct {
+		ID   string   `param:"id"`
+		Data GroupDTO `json:"data,omitempty"`
+	}
+	Meta struct {
+		Status int
+	}
+	Output struct {
+		mvc.ModelBaseDTO
+		Data          GroupDTO               `json:"data,omitempty"`
+		ChangeRequest *service.ChangeRequest `json:"change_request,omitempty"` // 202, waits for approval
+	}
+}
+type GroupsEntityAPIController struct {
+	appService service.AppService
+	appConfig  *config.AppConfig
+	userLang   i18n.UserLang
+
+	IsGET    bool
+	IsPOST   bool
+	IsPUT    bool
+	IsDELETE bool
+
+	webCtxt echo.Context // webCtxt
+
+	userAccount *service.UserAccount // actor
+	group       *service.AccountGroup
+
+	DTO GroupsEntityDTO
+}
+
+func (x *GroupsEntityAPIController) Handler() error {
+
+	err := x.validateDTO()
+	if err != nil {
+		return err
+	}
+
+	err = x.handleDTO()
+	if err != nil {
+		return err
+	}
+
+	err = x.responseDTO()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// NewGroupsEntityAPIController is constructor.
+func NewGroupsEntityAPIController(appService service.AppService, c echo.Context) *GroupsEntityAPIController {
+
+	appConfig := appService.Config()
+
+	return &GroupsEntityAPIController{
+		appService:  appService,
+		appConfig:   appConfig,
+		userLang:    controller.UserLang(c, appService),
+		IsGET:       controller.IsGET(c),
+		IsPOST:      controller.IsPOST(c),
+		IsPUT:       controller.IsPUT(c),
+		IsDELETE:    controller.IsDELETE(c),
+		userAccount: controller.GetAccount(c),
+		webCtxt:     c,
+	}
+}
+
+func (x *GroupsEntityAPIController) actorID() string {
+	if x.userAccount != nil {
+		return x.userAccount.ID
+	}
+	return ""
+}
+
+func (x *GroupsEntityAPIController) validateDTOFields() (err error) {
+
+	dto := &x.DTO
+	input := &dto.Input
+	output := &dto.Output
+	meta := &dto.Meta
+	srv := x.appService.AuthAdmin()
+
+	if x.IsPOST || x.IsPUT {
+
+		{
+			input.Data.Name = strings.TrimSpace(input.Data.Name)
+			input.Data.Description = strings.TrimSpace(input.Data.Description)
+			input.Data.Roles = utilaccess.NormalizeRoles(input.Data.Roles)
+		}
+
+		{
+			v := output.NewModelValidatorStr(x.userLang, "name", "Name" /*Lang*/, input.Data.Name, consts.TitleTextLengthLarge)
+			v.Required()
+		}
+
+		{
+			_ = output.NewModelValidatorStr(x.userLang, "description", "Description" /*Lang*/, input.Data.Description, consts.TitleTextLengthLarge)
+		}
+
+		{
+			_ = output.NewModelValidatorStr(x.userLang, "roles", "Roles" /*Lang*/, input.Data.Roles, consts.LongTextLength)
+		}
+
+		if err = validateRoles(x.appService, x.userLang, &output.ModelBaseDTO, input.Data.Roles); err != nil {
+			return err
+		}
+
+	}
+
+	if !output.IsModelValid() {
+		meta.Status = http.StatusUnprocessableEntity // 422 validation
+		return nil
+	}
+
+	if x.IsGET || x.IsPUT || x.IsDELETE {
+		// exists: get, update, delete
+
+		x.group, err = srv.Groups().FindByID(input.ID)
+		if err != nil {
+			return err
+		}
+
+		if x.group == nil {
+			meta.Status = http.StatusNotFound // 404
+			return nil
+		}
+
+	}
+
+	if x.IsPOST || x.IsPUT {
+		// roles change: members get group roles
+
+		stored := ""
+		if x.group != nil {
+			stored = x.group.Roles
+		}
+
+		if input.Data.Roles != stored && !canEditRoles(x.appService, x.userAccount) {
+			meta.Status = http.StatusForbidden // 403
+			output.AddError("roles", x.userLang.Lang("Field {0} cannot be changed.", "roles"))
+			return nil
+		}
+
+		validateRolesChange(x.userLang, &output.ModelBaseDTO, x.userAccount, stored, input.Data.Roles)
+	}
+
+	if x.IsDELETE && x.group.Roles != "" {
+		// members lose group roles
+
+		if !canEditRoles(x.appService, x.userAccount) {
+			meta.Status = http.StatusForbidden // 403
+			output.AddError("roles", x.userLang.Lang("Field {0} cannot be changed.", "roles"))
+			return nil
+		}
+
+		validateRolesChange(x.userLang, &output.ModelBaseDTO, x.userAccount, x.group.Roles, "")
+	}
+
+	if !output.IsModelValid() {
+		meta.Status = http.StatusForbidden // 403
+		return nil
+	}
+
+	if x.IsPOST || x.IsPUT {
+		// name dupl
+
+		val := input.Data.Name
+		id, err := srv.Groups().Name(val)
+		if err != nil {
+			return err
+		}
+		if id != "" && id != input.ID {
+			meta.Status = http.StatusConflict                                     // e.g., duplicate data 409
+			output.AddError("name", x.userLang.Lang("Duplicate entry {0}.", val)) // Lang
+			return nil
+		}
+	}
+
+	return nil
+
+}
+
+func (x *GroupsEntityAPIController) validateDTO() error {
+
+	dto := &x.DTO
+	input := &dto.Input
+
+	c := x.webCtxt
+
+	if err := c.Bind(input); err != nil {
+		return err
+	}
+
+	return x.validateDTOFields()
+
+}
+func (x *GroupsEntityAPIController) handleGET() (err error) {
+	dto := &x.DTO
+	output := &dto.Output
+
+	output.Data.AccountGroup = *x.group // copy
+
+	return nil
+}
+
+func (x *GroupsEntityAPIController) handlePOST() (err error) {
+	dto := &x.DTO
+	input := &dto.Input
+	output := &dto.Output
+	srv := x.appService.AuthAdmin()
+
+	output.Data.AccountGroup = service.AccountGroup{
+		Name:        input.Data.Name,
+		Description: input.Data.Description,
+		Roles:       input.Data.Roles,
+	}
+
+	if err = srv.Groups().Create(&output.Data.AccountGroup); err != nil {
+		return err
+	}
+
+	return srv.AuditLogs().Add(x.actorID(), service.AuditActionGroupCreate, output.Data.ID, "roles="+output.Data.Roles)
+
+}
+func (x *GroupsEntityAPIController) handlePUT() (err error) {
+	dto := &x.DTO
+	input := &dto.Input
+	output := &dto.Output
+	srv := x.appService.AuthAdmin()
+
+	output.Data.AccountGroup = *x.group // copy
+	output.Data.Name = input.Data.Name
+	output.Data.Description = input.Data.Description
+	output.Data.Roles = input.Data.Roles
+
+	if x.grantsAdmin() {
+		// members get admin
+
+		output.ChangeRequest, err = requestApproval(x.appService, x.userLang, &output.ModelBaseDTO, &dto.Meta.Status,
+			x.userAccount, service.ChangeActionGroupGrantAdmin, x.group.ID, grantAdminPayload())
+
+		if err != nil || dto.Meta.Status == http.StatusConflict {
+			output.Data.AccountGroup = *x.group // unchanged, request is pending already
+			return err
+		}
+
+		if output.ChangeRequest != nil {
+			output.Data.Roles = withoutAdmin(output.Data.Roles) // admin is added on approve, rest of change now
+		}
+	}
+
+	err = srv.Groups().Update(&output.Data.AccountGroup)
+	if isLastAdmin(err, x.userLang, &output.ModelBaseDTO, &dto.Meta.Status) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return srv.AuditLogs().Add(x.actorID(), service.AuditActionGroupUpdate, output.Data.ID, "roles="+output.Data.Roles)
+
+}
+func (x *GroupsEntityAPIController) handleDELETE() (err error) {
+
+	dto := &x.DTO
+	input := &dto.Input
+	output := &dto.Output
+	srv := x.appService.AuthAdmin()
+
+	output.Data.ID = input.ID
+
+	err = srv.Groups().Delete(input.ID)
+	if isLastAdmin(err, x.userLang, &output.ModelBaseDTO, &dto.Meta.Status) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return srv.AuditLogs().Add(x.actorID(), service.AuditActionGroupDelete, input.ID, "")
+
+}
+func (x *GroupsEntityAPIController) handleDTO() error {
+
+	dto := &x.DTO
+	meta := &dto.Meta
+	output := &dto.Output
+
+	if meta.Status > 0 {
+		return nil // stop processing
+	}
+
+	switch {
+
+	case x.IsGET:
+		return x.handleGET()
+	case x.IsPOST:
+		return x.handlePOST()
+	case x.IsPUT:
+		return x.handlePUT()
+	case x.IsDELETE:
+		return x.handleDELETE()
+	default:
+		{
+			meta.Status = http.StatusMethodNotAllowed
+			output.AddError("", "method action undef")
+		}
+	}
+
+	return nil
+}
+func (x *GroupsEntityAPIController) responseDTOAsAPI() (err error) {
+
+	dto := &x.DTO
+	meta := &dto.Meta
+	output := &dto.Output
+	c := x.webCtxt
+
+	if meta.Status == 0 {
+		meta.Status = http.StatusOK
+	}
+
+	return c.JSON(meta.Status, output)
+
+}
+
+func (x *GroupsEntityAPIController) responseDTO() (err error) {
+
+	return x.responseDTOAsAPI()
+
+}
+
+func (x *GroupsEntityAPIController) grantsAdmin() bool {
+	return slices.Contains(strings.Fields(x.DTO.Input.Data.Roles), consts.RoleAdmin) &&
+		!slices.Contains(strings.Fields(x.group.Roles), consts.RoleAdmin)
+}
@@ -0,0 +1,273 @@
+package authadmin
+
+import (
+	"go-auth-admin/internal/config"
+	"go-auth-admin/internal/config/consts"
+	controller "go-auth-admin/internal/controller"
+	"go-auth-admin/internal/mvc"
+
+	"slices"
+	"strings"
+
+	"go-auth-admin/internal/i18n"
+	"go-auth-admin/internal/service"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type GroupsMembersDTO struct {
+	Input struct {
+		ID        string `param:"id"`
+		AccountID string `param:"account_id" json:"account_id,omitempty"` // POST body, DELETE path
+	}
+	Meta struct {
+		Status int
+	}
+	Output struct {
+		mvc.ModelBaseDTO
+		ChangeRequest *service.ChangeRequest `json:"change_request,omitempty"` // 202, waits for approval
+	}
+}
+type GroupsMembersAPIController struct {
+	appService service.AppService
+	appConfig  *config.AppConfig
+	userLang   i18n.UserLang
+
+	IsPOST   bool
+	IsDELETE bool
+
+	webCtxt echo.Context // webCtxt
+
+	userAccount *service.UserAccount // actor
+	group       *service.AccountGroup
+	isMember    bool
+
+	DTO GroupsMembersDTO
+}
+
+func (x *GroupsMembersAPIController) Handler() error {
+
+	err := x.validateDTO()
+	if err != nil {
+		return err
+	}
+
+	err = x.handleDTO()
+	if err != nil {
+		return err
+	}
+
+	err = x.responseDTO()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// NewGroupsMembersAPIController is constructor.
+func NewGroupsMembersAPIController(appService service.AppService, c echo.Context) *GroupsMembersAPIController {
+
+	appConfig := appService.Config()
+	return &GroupsMembersAPIController{
+		appService:  appService,
+		appConfig:   appConfig,
+		userLang:    controller.UserLang(c, appService),
+		IsPOST:      controller.IsPOST(c),
+		IsDELETE:    controller.IsDELETE(c),
+		userAccount: controller.GetAccount(c),
+		webCtxt:     c,
+	}
+}
+
+func (x *GroupsMembersAPIController) actorID() string {
+	if x.userAccount != nil {
+		return x.userAccount.ID
+	}
+	return ""
+}
+
+func (x *GroupsMembersAPIController) validateDTOFields() (err error) {
+
+	dto := &x.DTO
+	input := &dto.Input
+	output := &dto.Output
+	meta := &dto.Meta
+	srv := x.appService.AuthAdmin()
+
+	{
+		v := output.NewModelValidatorStr(x.userLang, "account_id", "Account" /*Lang*/, input.AccountID, consts.DefaultTextLength)
+		v.Required()
+	}
+
+	if !output.IsModelValid() {
+		meta.Status = http.StatusUnprocessableEntity // 422 validation
+		return nil
+	}
+
+	if !canEditRoles(x.appService, x.userAccount) {
+		meta.Status = http.StatusForbidden // 403, membership grants group roles
+		output.AddError("", x.userLang.Lang("Field {0} cannot be changed.", "roles"))
+		return nil
+	}
+
+	group, err := srv.Groups().FindByID(input.ID)
+	if err != nil {
+		return err
+	}
+
+	x.group = group
+
+	if group == nil {
+		meta.Status = http.StatusNotFound // 404
+		return nil
+	}
+
+	// member gets or loses all group roles
+	validateRolesChange(x.userLang, &output.ModelBaseDTO, x.userAccount, "", group.Roles)
+
+	if !output.IsModelValid() {
+		meta.Status = http.StatusForbidden // 403
+		return nil
+	}
+
+	account, err := srv.UserAccounts().FindByID(input.AccountID)
+	if err != nil {
+		return err
+	}
+
+	if account == nil {
+		meta.Status = http.StatusNotFound // 404
+		return nil
+	}
+
+	x.isMember, err = srv.Groups().IsMember(input.ID, input.AccountID)
+	if err != nil {
+		return err
+	}
+
+	if x.IsPOST && x.isMember {
+		meta.Status = http.StatusConflict // 409
+		output.AddError("account_id", x.userLang.Lang("Duplicate entry {0}.", input.AccountID))
+		return nil
+	}
+
+	if x.IsDELETE && !x.isMember {
+		meta.Status = http.StatusNotFound // 404
+		return nil
+	}
+
+	return nil
+
+}
+
+func (x *GroupsMembersAPIController) validateDTO() error {
+
+	dto := &x.DTO
+	input := &dto.Input
+
+	c := x.webCtxt
+
+	if err := c.Bind(input); err != nil {
+		return err
+	}
+
+	return x.validateDTOFields()
+
+}
+
+func (x *GroupsMembersAPIController) handlePOST() (err error) {
+	dto := &x.DTO
+	input := &dto.Input
+	output := &dto.Output
+	srv := x.appService.AuthAdmin()
+
+	if slices.Contains(strings.Fields(x.group.Roles), consts.RoleAdmin) {
+
+		payload := &service.ChangeRequestPayload{GroupID: input.ID}
+
+		output.ChangeRequest, err = requestApproval(x.appService, x.userLang, &output.ModelBaseDTO, &dto.Meta.Status,
+			x.userAccount, service.ChangeActionAccountGrantAdmin, input.AccountID, payload)
+
+		if err != nil || output.ChangeRequest != nil {
+			return err
+		}
+	}
+
+	if err = srv.Groups().AddMember(input.ID, input.AccountID); err != nil {
+		return err
+	}
+
+	if err = srv.AuditLogs().Add(x.actorID(), service.AuditActionGroupMemberAdd, input.AccountID, "group="+input.ID); err != nil {
+		return err
+	}
+
+	output.Status = consts.StatusSuccess
+	return nil
+}
+
+func (x *GroupsMembersAPIController) handleDELETE() (err error) {
+	dto := &x.DTO
+	input := &dto.Input
+	output := &dto.Output
+	srv := x.appService.AuthAdmin()
+
+	err = srv.Groups().RemoveMember(input.ID, input.AccountID)
+	if isLastAdmin(err, x.userLang, &output.ModelBaseDTO, &dto.Meta.Status) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = srv.AuditLogs().Add(x.actorID(), service.AuditActionGroupMemberRemove, input.AccountID, "group="+input.ID); err != nil {
+		return err
+	}
+
+	output.Status = consts.StatusSuccess
+	return nil
+}
+
+func (x *GroupsMembersAPIController) handleDTO() error {
+
+	dto := &x.DTO
+	meta := &dto.Meta
+	output := &dto.Output
+
+	if meta.Status > 0 {
+		return nil // stop processing
+	}
+
+	switch {
+	case x.IsPOST:
+		return x.handlePOST()
+	case x.IsDELETE:
+		return x.handleDELETE()
+	default:
+		{
+			meta.Status = http.StatusMethodNotAllowed
+			output.Message = "method action undef"
+		}
+	}
+
+	return nil
+}
+func (x *GroupsMembersAPIController) responseDTOAsAPI() (err error) {
+
+	dto := &x.DTO
+	meta := &dto.Meta
+	output := &dto.Output
+	c := x.webCtxt
+
+	if meta.Status == 0 {
+		meta.Status = http.StatusOK
+	}
+
+	return c.JSON(meta.Status, output)
+
+}
+
+func (x *GroupsMembersAPIController) responseDTO() (err error) {
+	return x.responseDTOAsAPI()
+}
@@ -0,0 +1,375 @@
+package authadmin
+
+import (
+	"go-auth-admin/internal/config"
+	"go-auth-admin/internal/config/consts"
+	controller "go-auth-admin/internal/controller"
+	"go-auth-admin/internal/mvc"
+	"go-auth-admin/internal/util/utilaccess"
+	"go-auth-admin/internal/util/utilstring"
+
+	"go-auth-admin/internal/i18n"
+	"go-auth-admin/internal/service"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type InviteDTO struct {
+	service.AccountInvite
+}
+type InvitesEntityDTO struct {
+	Input struct {
+		ID   string    `param:"id"`
+		Data InviteDTO `json:"data,omitempty"`
+	}
+	Meta struct {
+		Status int
+	}
+	Output struct {
+		mvc.ModelBaseDTO
+		Data InviteDTO `json:"data,omitempty"`
+	}
+}
+type InvitesEntityAPIController struct {
+	appService service.AppService
+	appConfig  *config.AppConfig
+	userLang   i18n.UserLang
+
+	IsGET    bool
+	IsPOST   bool
+	IsDELETE bool
+	IsResend bool // POST /:id/resend
+
+	webCtxt echo.Context // webCtxt
+
+	userAccount *service.UserAccount // actor
+	invite      *service.AccountInvite
+
+	DTO InvitesEntityDTO
+}
+
+func (x *InvitesEntityAPIController) Handler() error {
+
+	err := x.validateDTO()
+	if err != nil {
+		return err
+	}
+
+	err = x.handleDTO()
+	if err != nil {
+		return err
+	}
+
+	err = x.responseDTO()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// NewInvitesEntityAPIController is constructor.
+func NewInvitesEntityAPIController(appService service.AppService, c echo.Context) *InvitesEntityAPIController {
+
+	appConfig := appService.Config()
+
+	return &InvitesEntityAPIController{
+		appService:  appService,
+		appConfig:   appConfig,
+		userLang:    controller.UserLang(c, appService),
+		IsGET:       controller.IsGET(c),
+		IsPOST:      controller.IsPOST(c),
+		IsDELETE:    controller.IsDELETE(c),
+		IsResend:    controller.IsPOST(c) && c.Path() == consts.PathAuthAdminInvitesEntityResendAPI,
+		userAccount: controller.GetAccount(c),
+		webCtxt:     c,
+	}
+}
+
+func (x *InvitesEntityAPIController) actorID() string {
+	if x.userAccount != nil {
+		return x.userAccount.ID
+	}
+	return ""
+}
+
+func (x *InvitesEntityAPIController) validateDTOFields() (err error) {
+
+	dto := &x.DTO
+	input := &dto.Input
+	output := &dto.Output
+	meta := &dto.Meta
+	srv := x.appService.AuthAdmin()
+
+	isCreate := x.IsPOST && !x.IsResend
+
+	if isCreate {
+
+		{
+			input.Data.Email = utilstring.NormalizeEmail(input.Data.Email)
+			input.Data.Tel = utilstring.NormalizeTel(input.Data.Tel)
+			input.Data.Roles = utilaccess.NormalizeRoles(input.Data.Roles)
+		}
+
+		{
+			v := output.NewModelValidatorStr(x.userLang, "email", "Email" /*Lang*/, input.Data.Email, consts.DefaultTextLength)
+			v.Email(0)
+		}
+		{
+			v := output.NewModelValidatorStr(x.userLang, "tel", "Phone number" /*Lang*/, input.Data.Tel, consts.TelMaxLength)
+			v.Tel()
+		}
+		{
+			_ = output.NewModelValidatorStr(x.userLang, "roles", "Roles" /*Lang*/, input.Data.Roles, consts.DefaultTextLength)
+		}
+
+		if input.Data.Email == "" && input.Data.Tel == "" {
+			output.AddError("", x.userLang.Lang("Email or phone number is required."))
+		}
+
+		if err := validateRoles(x.appService, x.userLang, &output.ModelBaseDTO, input.Data.Roles); err != nil {
+			return err
+		}
+
+	}
+
+	if isCreate && input.Data.Roles != "" && !canEditRoles(x.appService, x.userAccount) {
+		meta.Status = http.StatusForbidden // 403, roles granted on signup
+		output.AddError("roles", x.userLang.Lang("Field {0} cannot be changed.", "roles"))
+		return nil
+	}
+
+	if isCreate {
+		validateRolesChange(x.userLang, &output.ModelBaseDTO, x.userAccount, "", input.Data.Roles)
+
+		if !output.IsModelValid() {
+			meta.Status = http.StatusForbidden // 403
+			return nil
+		}
+	}
+
+	if !output.IsModelValid() {
+		meta.Status = http.StatusUnprocessableEntity // 422 validation
+		return nil
+	}
+
+	if x.IsGET || x.IsDELETE || x.IsResend {
+		// exists: get, revoke, resend
+
+		x.invite, err = srv.AccountInvites().FindByID(input.ID)
+		if err != nil {
+			return err
+		}
+
+		if x.invite == nil {
+			meta.Status = http.StatusNotFound // 404
+			return nil
+		}
+
+	}
+
+	if x.IsDELETE || x.IsResend {
+
+		if x.invite.Status != service.InviteStatusPending {
+			meta.Status = http.StatusConflict // 409
+			output.AddError("", x.userLang.Lang("Invite is not pending."))
+			return nil
+		}
+
+	}
+
+	if isCreate {
+		// dupl: account exists or invite pending
+
+		for _, itm := range []struct {
+			field string
+			find  func(string) (string, error)
+			val   string
+		}{
+			{"email", srv.UserAccounts().Email, input.Data.Email},
+			{"tel", srv.UserAccounts().Tel, input.Data.Tel},
+		} {
+			if itm.val == "" {
+				continue
+			}
+			id, err := itm.find(itm.val)
+			if err != nil {
+				return err
+			}
+			if id != "" {
+				meta.Status = http.StatusConflict                                            // e.g., duplicate data 409
+				output.AddError(itm.field, x.userLang.Lang("Duplicate entry {0}.", itm.val)) // Lang
+				return nil
+			}
+		}
+
+		id, err := srv.AccountInvites().Pending(input.Data.Email, input.Data.Tel)
+		if err != nil {
+			return err
+		}
+		if id != "" {
+			meta.Status = http.StatusConflict // 409
+			output.AddError("", x.userLang.Lang("Invite is already pending."))
+			return nil
+		}
+
+	}
+
+	return nil
+
+}
+
+func (x *InvitesEntityAPIController) validateDTO() error {
+
+	dto := &x.DTO
+	input := &dto.Input
+
+	c := x.webCtxt
+
+	if err := c.Bind(input); err != nil {
+		return err
+	}
+
+	return x.validateDTOFields()
+
+}
+func (x *InvitesEntityAPIController) handleGET() (err error) {
+	dto := &x.DTO
+	output := &dto.Output
+
+	output.Data.AccountInvite = *x.invite // copy
+
+	return nil
+}
+
+func (x *InvitesEntityAPIController) handlePOST() (err error) {
+	dto := &x.DTO
+	input := &dto.Input
+	output := &dto.Output
+	srv := x.appService.AuthAdmin()
+
+	data := &output.Data.AccountInvite
+
+	*data = service.AccountInvite{
+		Email:     input.Data.Email,
+		Tel:       input.Data.Tel,
+		Roles:     input.Data.Roles,
+		CreatedBy: x.actorID(),
+	}
+
+	if err = srv.AccountInvites().Create(data); err != nil {
+		return err
+	}
+
+	if err = srv.AuditLogs().Add(x.actorID(), service.AuditActionInviteCreate, data.ID, "roles="+data.Roles); err != nil {
+		return err
+	}
+
+	return x.send(data)
+
+}
+
+func (x *InvitesEntityAPIController) handleResend() (err error) {
+	dto := &x.DTO
+	output := &dto.Output
+	srv := x.appService.AuthAdmin()
+
+	data := &output.Data.AccountInvite
+	*data = *x.invite // copy
+
+	if err = srv.AuditLogs().Add(x.actorID(), service.AuditActionInviteResend, data.ID, ""); err != nil {
+		return err
+	}
+
+	return x.send(data)
+
+}
+
+// send issues new token, old link stops working
+func (x *InvitesEntityAPIController) send(data *service.AccountInvite) (err error) {
+	dto := &x.DTO
+	meta := &dto.Meta
+	output := &dto.Output
+	srv := x.appService.AuthAdmin()
+
+	if err = srv.AccountInvites().Send(data, x.userLang.LangCode()); err != nil {
+		meta.Status = http.StatusBadGateway // 502 messenger
+		output.AddError("", x.userLang.Lang("Invite delivery failed."))
+		return nil
+	}
+
+	output.Status = consts.StatusSuccess
+	return nil
+}
+
+func (x *InvitesEntityAPIController) handleDELETE() (err error) {
+
+	dto := &x.DTO
+	input := &dto.Input
+	output := &dto.Output
+	srv := x.appService.AuthAdmin()
+
+	if err = srv.AccountInvites().Revoke(input.ID); err != nil {
+		return err
+	}
+
+	if err = srv.AuditLogs().Add(x.actorID(), service.AuditActionInviteRevoke, input.ID, ""); err != nil {
+		return err
+	}
+
+	output.Data.ID = input.ID
+	output.Data.Status = service.InviteStatusRevoked
+	output.Status = consts.StatusSuccess
+	return nil
+
+}
+func (x *InvitesEntityAPIController) handleDTO() error {
+
+	dto := &x.DTO
+	meta := &dto.Meta
+	output := &dto.Output
+
+	if meta.Status > 0 {
+		return nil // stop processing
+	}
+
+	switch {
+
+	case x.IsGET:
+		return x.handleGET()
+	case x.IsResend:
+		return x.handleResend()
+	case x.IsPOST:
+		return x.handlePOST()
+	case x.IsDELETE:
+		return x.handleDELETE()
+	default:
+		{
+			meta.Status = http.StatusMethodNotAllowed
+			output.AddError("", "method action undef")
+		}
+	}
+
+	return nil
+}
+func (x *InvitesEntityAPIController) responseDTOAsAPI() (err error) {
+
+	dto := &x.DTO
+	meta := &dto.Meta
+	output := &dto.Output
+	c := x.webCtxt
+
+	if meta.Status == 0 {
+		meta.Status = http.StatusOK
+	}
+
+	return c.JSON(meta.Status, output)
+
+}
+
+func (x *InvitesEntityAPIController) responseDTO() (err error) {
+
+	return x.responseDTOAsAPI()
+
+}
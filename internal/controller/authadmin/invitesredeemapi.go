@@ -0,0 +1,197 @@
+package authadmin
+
+import (
+	"errors"
+	"go-auth-admin/internal/config"
+	"go-auth-admin/internal/config/consts"
+	controller "go-auth-admin/internal/controller"
+	"go-auth-admin/internal/mvc"
+
+	"go-auth-admin/internal/i18n"
+	"go-auth-admin/internal/service"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type InvitesRedeemDTO struct {
+	Input struct {
+		Token     string `json:"token"`      // signup token of invite link
+		AccountID string `json:"account_id"` // account signed up with token
+	}
+	Meta struct {
+		Status int
+	}
+	Output struct {
+		mvc.ModelBaseDTO
+		Data          InviteDTO              `json:"data,omitempty"`
+		ChangeRequest *service.ChangeRequest `json:"change_request,omitempty"` // admin waits for approval
+	}
+}
+
+// InvitesRedeemAPIController sys api, auth app redeems invite of signed up account
+type InvitesRedeemAPIController struct {
+	appService service.AppService
+	appConfig  *config.AppConfig
+	userLang   i18n.UserLang
+
+	IsPOST bool
+
+	webCtxt echo.Context // webCtxt
+
+	DTO InvitesRedeemDTO
+}
+
+func (x *InvitesRedeemAPIController) Handler() error {
+
+	err := x.validateDTO()
+	if err != nil {
+		return err
+	}
+
+	err = x.handleDTO()
+	if err != nil {
+		return err
+	}
+
+	err = x.responseDTO()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// NewInvitesRedeemAPIController is constructor.
+func NewInvitesRedeemAPIController(appService service.AppService, c echo.Context) *InvitesRedeemAPIController {
+
+	appConfig := appService.Config()
+	return &InvitesRedeemAPIController{
+		appService: appService,
+		appConfig:  appConfig,
+		userLang:   controller.UserLang(c, appService),
+		IsPOST:     controller.IsPOST(c),
+		webCtxt:    c,
+	}
+}
+
+func (x *InvitesRedeemAPIController) validateDTOFields() (err error) {
+
+	dto := &x.DTO
+	input := &dto.Input
+	output := &dto.Output
+	meta := &dto.Meta
+
+	{
+		v := output.NewModelValidatorStr(x.userLang, "token", "Token" /*Lang*/, input.Token, consts.LongTextLength)
+		v.Required()
+	}
+	{
+		v := output.NewModelValidatorStr(x.userLang, "account_id", "Account" /*Lang*/, input.AccountID, consts.DefaultTextLength)
+		v.Required()
+	}
+
+	if !output.IsModelValid() {
+		meta.Status = http.StatusUnprocessableEntity // 422 validation
+		return nil
+	}
+
+	return nil
+
+}
+
+func (x *InvitesRedeemAPIController) validateDTO() error {
+
+	dto := &x.DTO
+	input := &dto.Input
+
+	c := x.webCtxt
+
+	if err := c.Bind(input); err != nil {
+		return err
+	}
+
+	return x.validateDTOFields()
+
+}
+
+func (x *InvitesRedeemAPIController) handlePOST() (err error) {
+	dto := &x.DTO
+	input := &dto.Input
+	output := &dto.Output
+	srv := x.appService.AuthAdmin()
+
+	invite, changeRequest, err := srv.AccountInvites().Redeem(input.Token, input.AccountID)
+	if errors.Is(err, service.ErrInviteInvalid) {
+		dto.Meta.Status = http.StatusUnprocessableEntity // 422
+		output.AddError("token", x.userLang.Lang("Invite is invalid, expired or already used."))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	output.Data.AccountInvite = *invite // copy
+
+	details := "invite=" + invite.ID + " roles=" + invite.Roles
+
+	if changeRequest != nil {
+
+		dto.Meta.Status = http.StatusAccepted // 202, admin waits for approval
+		output.ChangeRequest = changeRequest
+		details += " request=" + changeRequest.ID
+
+		if err = srv.AuditLogs().Add(changeRequest.CreatedBy, service.AuditActionApprovalRequest, input.AccountID,
+			"request="+changeRequest.ID+" action="+changeRequest.Action); err != nil {
+			return err
+		}
+	}
+
+	if err = srv.AuditLogs().Add("", service.AuditActionInviteRedeem, input.AccountID, details); err != nil {
+		return err
+	}
+
+	output.Status = consts.StatusSuccess
+	return nil
+}
+
+func (x *InvitesRedeemAPIController) handleDTO() error {
+
+	dto := &x.DTO
+	meta := &dto.Meta
+	output := &dto.Output
+
+	if meta.Status > 0 {
+		return nil // stop processing
+	}
+
+	switch {
+	case x.IsPOST:
+		return x.handlePOST()
+	default:
+		{
+			meta.Status = http.StatusMethodNotAllowed
+			output.AddError("", "method POST only")
+		}
+	}
+
+	return nil
+}
+func (x *InvitesRedeemAPIController) responseDTOAsAPI() (err error) {
+
+	dto := &x.DTO
+	meta := &dto.Meta
+	output := &dto.Output
+	c := x.webCtxt
+
+	if meta.Status == 0 {
+		meta.Status = http.StatusOK
+	}
+
+	return c.JSON(meta.Status, output)
+
+}
+
+func (x *InvitesRedeemAPIController) responseDTO() (err error) {
+	return x.responseDTOAsAPI()
+}
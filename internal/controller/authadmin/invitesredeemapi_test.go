@@ -0,0 +1,101 @@
+package authadmin
+
+import (
+	"go-auth-admin/internal/service"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func serveInvitesRedeem(t *testing.T, appService service.AppService, token string, accountID string) int {
+	t.Helper()
+
+	e := echo.New()
+
+	body := `{"token":"` + token + `","account_id":"` + accountID + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	c := e.NewContext(req, rec)
+
+	if err := NewInvitesRedeemAPIController(appService, c).Handler(); err != nil {
+		t.Fatal(err)
+	}
+
+	return rec.Code
+}
+
+func TestInvitesRedeem(t *testing.T) {
+
+	links := []string{}
+
+	messengerService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		links = append(links, r.FormValue("link"))
+	}))
+	t.Cleanup(messengerService.Close)
+
+	t.Setenv("APP_OUTBOX_ENABLED", "false") // send synchronously
+	t.Setenv("APP_MESSENGER_SERVICE_URL", messengerService.URL+"/{code}")
+
+	appService := newTestAppService(t)
+	appService.Config().Approval.Actions = []string{service.ChangeActionAccountGrantAdmin}
+
+	srv := appService.AuthAdmin()
+
+	admin := newTestAccount(t, appService, "admin1", "admin")
+	other := newTestAccount(t, appService, "other1", "")
+
+	invite := &service.AccountInvite{Email: "target1@example.com", Roles: "admin auth_access", CreatedBy: admin.ID}
+	if err := srv.AccountInvites().Create(invite); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.AccountInvites().Send(invite, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(links) != 1 {
+		t.Fatalf("invite links = %v, want one", links)
+	}
+	link, err := url.Parse(links[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := link.Query().Get("token")
+
+	// signed up by auth app
+	target := newTestAccount(t, appService, "target1", "")
+	target.Email = "target1@example.com"
+	if err = srv.UserAccounts().Update(target); err != nil {
+		t.Fatal(err)
+	}
+
+	if status := serveInvitesRedeem(t, appService, token, other.ID); status != http.StatusUnprocessableEntity {
+		t.Errorf("redeem for other account status = %v, want %v", status, http.StatusUnprocessableEntity)
+	}
+
+	if status := serveInvitesRedeem(t, appService, token, target.ID); status != http.StatusAccepted {
+		t.Fatalf("redeem status = %v, want %v", status, http.StatusAccepted)
+	}
+
+	if status := serveInvitesRedeem(t, appService, token, target.ID); status != http.StatusUnprocessableEntity {
+		t.Errorf("redeem again status = %v, want %v", status, http.StatusUnprocessableEntity)
+	}
+
+	stored, err := srv.UserAccounts().FindByID(target.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Roles != "auth_access" {
+		t.Errorf("roles after redeem = %q, want invite roles without admin", stored.Roles)
+	}
+
+	req := pendingChangeRequest(t, appService, service.ChangeActionAccountGrantAdmin, target.ID)
+	if req.CreatedBy != admin.ID {
+		t.Errorf("requester = %v, want inviter %v", req.CreatedBy, admin.ID)
+	}
+}
@@ -0,0 +1,171 @@
+package authadmin
+
+import (
+	"go-auth-admin/internal/config"
+	"go-auth-admin/internal/config/consts"
+	controller "go-auth-admin/internal/controller"
+	"go-auth-admin/internal/messenger"
+	"go-auth-admin/internal/mvc"
+	"slices"
+
+	"go-auth-admin/internal/i18n"
+	"go-auth-admin/internal/service"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type MessagesPreviewDTO struct {
+	Input struct {
+		Kind string            `json:"kind"`
+		Lang string            `json:"lang"` // user lang if empty
+		Data map[string]string `json:"data"` // template fields
+	}
+	Meta struct {
+		Status int
+	}
+	Output struct {
+		mvc.ModelBaseDTO
+		Data *messenger.Message `json:"data,omitempty"`
+	}
+}
+type MessagesPreviewAPIController struct {
+	appService service.AppService
+	appConfig  *config.AppConfig
+	userLang   i18n.UserLang
+
+	IsPOST bool
+
+	webCtxt echo.Context // webCtxt
+
+	DTO MessagesPreviewDTO
+}
+
+func (x *MessagesPreviewAPIController) Handler() error {
+
+	err := x.validateDTO()
+	if err != nil {
+		return err
+	}
+
+	err = x.handleDTO()
+	if err != nil {
+		return err
+	}
+
+	err = x.responseDTO()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// NewMessagesPreviewAPIController is constructor.
+func NewMessagesPreviewAPIController(appService service.AppService, c echo.Context) *MessagesPreviewAPIController {
+
+	appConfig := appService.Config()
+	return &MessagesPreviewAPIController{
+		appService: appService,
+		appConfig:  appConfig,
+		userLang:   controller.UserLang(c, appService),
+		IsPOST:     controller.IsPOST(c),
+		webCtxt:    c,
+	}
+}
+
+func (x *MessagesPreviewAPIController) validateDTOFields() (err error) {
+
+	dto := &x.DTO
+	input := &dto.Input
+	output := &dto.Output
+	meta := &dto.Meta
+
+	if !slices.Contains(x.appService.Messenger().Kinds(), input.Kind) {
+		meta.Status = http.StatusBadRequest // 400
+		output.AddError("kind", x.userLang.Lang("Unknown message kind."))
+		return nil
+	}
+
+	if input.Lang == "" {
+		input.Lang = x.userLang.LangCode()
+	}
+
+	return nil
+
+}
+
+func (x *MessagesPreviewAPIController) validateDTO() error {
+
+	dto := &x.DTO
+	input := &dto.Input
+
+	c := x.webCtxt
+
+	if err := c.Bind(input); err != nil {
+		return err
+	}
+
+	return x.validateDTOFields()
+
+}
+
+func (x *MessagesPreviewAPIController) handlePOST() (err error) {
+	dto := &x.DTO
+	input := &dto.Input
+	meta := &dto.Meta
+	output := &dto.Output
+
+	msg, err := x.appService.Messenger().Preview(input.Kind, input.Lang, input.Data)
+	if err != nil {
+		meta.Status = http.StatusUnprocessableEntity // 422, template field missing
+		output.AddError("data", err.Error())
+		return nil
+	}
+
+	output.Data = msg
+	output.Status = consts.StatusSuccess
+	return nil
+
+}
+
+func (x *MessagesPreviewAPIController) handleDTO() error {
+
+	dto := &x.DTO
+	meta := &dto.Meta
+	output := &dto.Output
+
+	if meta.Status > 0 {
+		return nil // stop processing
+	}
+
+	switch {
+	case x.IsPOST:
+		return x.handlePOST()
+	default:
+		{
+			meta.Status = http.StatusMethodNotAllowed
+			output.Message = "method action undef"
+		}
+	}
+
+	return nil
+}
+func (x *MessagesPreviewAPIController) responseDTOAsAPI() (err error) {
+
+	dto := &x.DTO
+	meta := &dto.Meta
+	output := &dto.Output
+	c := x.webCtxt
+
+	if meta.Status == 0 {
+		meta.Status = http.StatusOK
+	}
+
+	return c.JSON(meta.Status, output)
+
+}
+
+func (x *MessagesPreviewAPIController) responseDTO() (err error) {
+	return x.responseDTOAsAPI()
+}
@@ -0,0 +1,188 @@
+package authadmin
+
+import (
+	"go-auth-admin/internal/config"
+	"go-auth-admin/internal/config/consts"
+	controller "go-auth-admin/internal/controller"
+	"go-auth-admin/internal/mvc"
+	"slices"
+
+	"go-auth-admin/internal/i18n"
+	"go-auth-admin/internal/service"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type NotifyDTO struct {
+	Input struct {
+		Event     string `json:"event"`      // one of service.NotifyEventsReported
+		AccountID string `json:"account_id"` // affected account
+	}
+	Meta struct {
+		Status int
+	}
+	Output struct {
+		mvc.ModelBaseDTO
+	}
+}
+
+// NotifyAPIController sys api, auth app reports security event of account, e.g. mfa enrollment
+type NotifyAPIController struct {
+	appService service.AppService
+	appConfig  *config.AppConfig
+	userLang   i18n.UserLang
+
+	IsPOST bool
+
+	webCtxt echo.Context // webCtxt
+
+	account *service.UserAccount
+
+	DTO NotifyDTO
+}
+
+func (x *NotifyAPIController) Handler() error {
+
+	err := x.validateDTO()
+	if err != nil {
+		return err
+	}
+
+	err = x.handleDTO()
+	if err != nil {
+		return err
+	}
+
+	err = x.responseDTO()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// NewNotifyAPIController is constructor.
+func NewNotifyAPIController(appService service.AppService, c echo.Context) *NotifyAPIController {
+
+	appConfig := appService.Config()
+	return &NotifyAPIController{
+		appService: appService,
+		appConfig:  appConfig,
+		userLang:   controller.UserLang(c, appService),
+		IsPOST:     controller.IsPOST(c),
+		webCtxt:    c,
+	}
+}
+
+func (x *NotifyAPIController) validateDTOFields() (err error) {
+
+	dto := &x.DTO
+	input := &dto.Input
+	output := &dto.Output
+	meta := &dto.Meta
+	srv := x.appService.AuthAdmin()
+
+	{
+		v := output.NewModelValidatorStr(x.userLang, "event", "Event" /*Lang*/, input.Event, consts.DefaultTextLength)
+		v.Required()
+	}
+	{
+		v := output.NewModelValidatorStr(x.userLang, "account_id", "Account" /*Lang*/, input.AccountID, consts.DefaultTextLength)
+		v.Required()
+	}
+
+	if input.Event != "" && !slices.Contains(service.NotifyEventsReported, input.Event) {
+		output.AddError("event", x.userLang.Lang("Unknown event {0}.", input.Event))
+	}
+
+	if !output.IsModelValid() {
+		meta.Status = http.StatusUnprocessableEntity // 422 validation
+		return nil
+	}
+
+	x.account, err = srv.UserAccounts().FindByID(input.AccountID)
+	if err != nil {
+		return err
+	}
+
+	if x.account == nil {
+		meta.Status = http.StatusNotFound // 404
+		return nil
+	}
+
+	return nil
+
+}
+
+func (x *NotifyAPIController) validateDTO() error {
+
+	dto := &x.DTO
+	input := &dto.Input
+
+	c := x.webCtxt
+
+	if err := c.Bind(input); err != nil {
+		return err
+	}
+
+	return x.validateDTOFields()
+
+}
+
+func (x *NotifyAPIController) handlePOST() (err error) {
+	dto := &x.DTO
+	input := &dto.Input
+	output := &dto.Output
+	srv := x.appService.AuthAdmin()
+
+	srv.Notify().Notify(input.Event, x.account, nil) // opt-out by notify.disabled
+
+	if err = srv.AuditLogs().Add("", service.AuditActionNotifyReport, x.account.ID, "event="+input.Event); err != nil {
+		return err
+	}
+
+	output.Status = consts.StatusSuccess
+	return nil
+}
+
+func (x *NotifyAPIController) handleDTO() error {
+
+	dto := &x.DTO
+	meta := &dto.Meta
+	output := &dto.Output
+
+	if meta.Status > 0 {
+		return nil // stop processing
+	}
+
+	switch {
+	case x.IsPOST:
+		return x.handlePOST()
+	default:
+		{
+			meta.Status = http.StatusMethodNotAllowed
+			output.AddError("", "method POST only")
+		}
+	}
+
+	return nil
+}
+func (x *NotifyAPIController) responseDTOAsAPI() (err error) {
+
+	dto := &x.DTO
+	meta := &dto.Meta
+	output := &dto.Output
+	c := x.webCtxt
+
+	if meta.Status == 0 {
+		meta.Status = http.StatusOK
+	}
+
+	return c.JSON(meta.Status, output)
+
+}
+
+func (x *NotifyAPIController) responseDTO() (err error) {
+	return x.responseDTOAsAPI()
+}
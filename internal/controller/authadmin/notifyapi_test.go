@@ -0,0 +1,56 @@
+package authadmin
+
+import (
+	"go-auth-admin/internal/service"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestNotifyReport(t *testing.T) {
+
+	appService := newTestAppService(t)
+	srv := appService.AuthAdmin()
+
+	target := newTestAccount(t, appService, "target1", "")
+	target.Email = "target1@example.com"
+	if err := srv.UserAccounts().Update(target); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		event     string
+		accountID string
+		status    int
+	}{
+		{"Admin event", service.NotifyEventPasswordReset, target.ID, http.StatusUnprocessableEntity},
+		{"Unknown account", service.NotifyEventMFAEnrolled, "x", http.StatusNotFound},
+		{"MFA enrolled", service.NotifyEventMFAEnrolled, target.ID, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+
+			body := `{"event":"` + tt.event + `","account_id":"` + tt.accountID + `"}`
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			if err := NewNotifyAPIController(appService, e.NewContext(req, rec)).Handler(); err != nil {
+				t.Fatal(err)
+			}
+			if rec.Code != tt.status {
+				t.Errorf("status = %v, want %v", rec.Code, tt.status)
+			}
+		})
+	}
+
+	if got := outboxKinds(t, appService, target.Email); len(got) != 1 || got[0] != "email-mfa-enrolled" {
+		t.Errorf("notifications = %v, want email-mfa-enrolled", got)
+	}
+}
@@ -0,0 +1,138 @@
+package authadmin
+
+import (
+	"go-auth-admin/internal/config"
+	controller "go-auth-admin/internal/controller"
+	"go-auth-admin/internal/util/utilpaging"
+
+	"go-auth-admin/internal/i18n"
+	"go-auth-admin/internal/service"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type OutboxDTO struct {
+	Input struct {
+		utilpaging.PagingInputDTO
+		Status    string `query:"status"` // pending sent failed
+		Kind      string `query:"kind"`
+		Recipient string `query:"recipient"`
+	}
+	Meta struct {
+		Status int
+	}
+	Output struct {
+		Message string `json:"message,omitempty"`
+		utilpaging.PagingOutputDTO[service.OutboxMessage]
+	}
+}
+
+type OutboxAPIController struct {
+	appService service.AppService
+	appConfig  *config.AppConfig
+	userLang   i18n.UserLang
+
+	IsGET bool
+
+	webCtxt echo.Context // webCtxt
+
+	DTO OutboxDTO
+}
+
+func (x *OutboxAPIController) Handler() error {
+
+	err := x.validateDTO()
+	if err != nil {
+		return err
+	}
+
+	err = x.handleDTO()
+	if err != nil {
+		return err
+	}
+
+	err = x.responseDTO()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// NewOutboxAPIController is constructor.
+func NewOutboxAPIController(appService service.AppService, c echo.Context) *OutboxAPIController {
+
+	appConfig := appService.Config()
+
+	return &OutboxAPIController{
+		appService: appService,
+		appConfig:  appConfig,
+		userLang:   controller.UserLang(c, appService),
+		IsGET:      controller.IsGET(c),
+		webCtxt:    c,
+	}
+}
+
+func (x *OutboxAPIController) validateDTO() error {
+
+	dto := &x.DTO
+	input := &dto.Input
+
+	c := x.webCtxt
+
+	if err := c.Bind(input); err != nil {
+		return err
+	}
+
+	input.Filters = utilpaging.Filters{
+		"status":    input.Status,
+		"kind":      input.Kind,
+		"recipient": input.Recipient,
+	}
+
+	return nil
+}
+
+func (x *OutboxAPIController) handleDTO() error {
+
+	dto := &x.DTO
+	input := &dto.Input
+	meta := &dto.Meta
+	output := &dto.Output
+
+	if x.IsGET {
+
+		bs := x.appService.AuthAdmin()
+
+		if err := bs.Outbox().Query(&input.PagingInputDTO, &output.PagingOutputDTO); err != nil {
+			return err
+		}
+
+	} else {
+		meta.Status = http.StatusMethodNotAllowed
+		output.Message = "method action undef"
+	}
+
+	return nil
+}
+func (x *OutboxAPIController) responseDTOAsAPI() (err error) {
+
+	dto := &x.DTO
+	meta := &dto.Meta
+	output := &dto.Output
+	c := x.webCtxt
+
+	if meta.Status == 0 {
+		meta.Status = http.StatusOK
+	}
+
+	return c.JSON(meta.Status, output)
+
+}
+
+func (x *OutboxAPIController) responseDTO() (err error) {
+
+	return x.responseDTOAsAPI()
+
+}
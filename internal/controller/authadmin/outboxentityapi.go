@@ -0,0 +1,210 @@
+package authadmin
+
+import (
+	"errors"
+	"go-auth-admin/internal/config"
+	"go-auth-admin/internal/config/consts"
+	controller "go-auth-admin/internal/controller"
+	"go-auth-admin/internal/mvc"
+
+	"go-auth-admin/internal/i18n"
+	"go-auth-admin/internal/service"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type OutboxEntityDTO struct {
+	Input struct {
+		ID string `param:"id"`
+	}
+	Meta struct {
+		Status int
+	}
+	Output struct {
+		mvc.ModelBaseDTO
+		Data *service.OutboxMessage `json:"data,omitempty"`
+	}
+}
+type OutboxEntityAPIController struct {
+	appService service.AppService
+	appConfig  *config.AppConfig
+	userLang   i18n.UserLang
+
+	IsGET   bool
+	IsRetry bool // POST /:id/retry
+
+	webCtxt echo.Context // webCtxt
+
+	userAccount *service.UserAccount // actor
+	message     *service.OutboxMessage
+
+	DTO OutboxEntityDTO
+}
+
+func (x *OutboxEntityAPIController) Handler() error {
+
+	err := x.validateDTO()
+	if err != nil {
+		return err
+	}
+
+	err = x.handleDTO()
+	if err != nil {
+		return err
+	}
+
+	err = x.responseDTO()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// NewOutboxEntityAPIController is constructor.
+func NewOutboxEntityAPIController(appService service.AppService, c echo.Context) *OutboxEntityAPIController {
+
+	appConfig := appService.Config()
+
+	return &OutboxEntityAPIController{
+		appService:  appService,
+		appConfig:   appConfig,
+		userLang:    controller.UserLang(c, appService),
+		IsGET:       controller.IsGET(c),
+		IsRetry:     controller.IsPOST(c) && c.Path() == consts.PathAuthAdminOutboxEntityRetryAPI,
+		userAccount: controller.GetAccount(c),
+		webCtxt:     c,
+	}
+}
+
+func (x *OutboxEntityAPIController) actorID() string {
+	if x.userAccount != nil {
+		return x.userAccount.ID
+	}
+	return ""
+}
+
+func (x *OutboxEntityAPIController) validateDTOFields() (err error) {
+
+	dto := &x.DTO
+	input := &dto.Input
+	output := &dto.Output
+	meta := &dto.Meta
+	srv := x.appService.AuthAdmin()
+
+	x.message, err = srv.Outbox().FindByID(input.ID)
+	if err != nil {
+		return err
+	}
+
+	if x.message == nil {
+		meta.Status = http.StatusNotFound // 404
+		return nil
+	}
+
+	if x.IsRetry && x.message.Status != service.OutboxStatusFailed {
+		meta.Status = http.StatusConflict // 409
+		output.AddError("", x.userLang.Lang("Message is not failed."))
+		return nil
+	}
+
+	return nil
+
+}
+
+func (x *OutboxEntityAPIController) validateDTO() error {
+
+	dto := &x.DTO
+	input := &dto.Input
+
+	c := x.webCtxt
+
+	if err := c.Bind(input); err != nil {
+		return err
+	}
+
+	return x.validateDTOFields()
+
+}
+func (x *OutboxEntityAPIController) handleGET() (err error) {
+	dto := &x.DTO
+	output := &dto.Output
+
+	output.Data = x.message
+
+	return nil
+}
+
+func (x *OutboxEntityAPIController) handleRetry() (err error) {
+	dto := &x.DTO
+	meta := &dto.Meta
+	output := &dto.Output
+	srv := x.appService.AuthAdmin()
+
+	data := x.message
+
+	err = srv.Outbox().Retry(data)
+	if errors.Is(err, service.ErrOutboxMessageNotFailed) {
+		meta.Status = http.StatusConflict // 409, retried meanwhile
+		output.AddError("", x.userLang.Lang("Message is not failed."))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = srv.AuditLogs().Add(x.actorID(), service.AuditActionOutboxRetry, "", "message="+data.ID+" kind="+data.Kind); err != nil {
+		return err
+	}
+
+	output.Data = data
+	output.Status = consts.StatusSuccess
+	return nil
+}
+
+func (x *OutboxEntityAPIController) handleDTO() error {
+
+	dto := &x.DTO
+	meta := &dto.Meta
+	output := &dto.Output
+
+	if meta.Status > 0 {
+		return nil // stop processing
+	}
+
+	switch {
+
+	case x.IsGET:
+		return x.handleGET()
+	case x.IsRetry:
+		return x.handleRetry()
+	default:
+		{
+			meta.Status = http.StatusMethodNotAllowed
+			output.AddError("", "method action undef")
+		}
+	}
+
+	return nil
+}
+func (x *OutboxEntityAPIController) responseDTOAsAPI() (err error) {
+
+	dto := &x.DTO
+	meta := &dto.Meta
+	output := &dto.Output
+	c := x.webCtxt
+
+	if meta.Status == 0 {
+		meta.Status = http.StatusOK
+	}
+
+	return c.JSON(meta.Status, output)
+
+}
+
+func (x *OutboxEntityAPIController) responseDTO() (err error) {
+
+	return x.responseDTOAsAPI()
+
+}
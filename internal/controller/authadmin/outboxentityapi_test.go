@@ -0,0 +1,127 @@
+package authadmin
+
+import (
+	"go-auth-admin/internal/config/consts"
+	"go-auth-admin/internal/service"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func serveOutboxEntity(t *testing.T, appService service.AppService, actor *service.UserAccount, method string, path string, id string) int {
+	t.Helper()
+
+	e := echo.New()
+
+	req := httptest.NewRequest(method, "/", nil)
+	rec := httptest.NewRecorder()
+
+	c := e.NewContext(req, rec)
+	c.SetPath(path)
+	c.SetParamNames("id")
+	c.SetParamValues(id)
+	c.Set("user_account", actor)
+
+	if err := NewOutboxEntityAPIController(appService, c).Handler(); err != nil {
+		t.Fatal(err)
+	}
+
+	return rec.Code
+}
+
+func TestOutboxDeadLetterRetry(t *testing.T) {
+
+	var down atomic.Bool
+	var received atomic.Value
+
+	messengerService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		received.Store(r.FormValue("password"))
+	}))
+	defer messengerService.Close()
+
+	t.Setenv("APP_MESSENGER_SERVICE_URL", messengerService.URL+"/{code}")
+
+	appService := newTestAppService(t)
+	appService.Config().Outbox.MaxAttempts = 2
+
+	admin := newTestAccount(t, appService, "admin1", "admin")
+	srv := appService.AuthAdmin().Outbox()
+	repo := appService.Repository()
+
+	down.Store(true)
+
+	if err := appService.Messenger().SendPasswordToEmail("secret-pass", "user1@example.com", "en"); err != nil {
+		t.Fatal(err)
+	}
+
+	data := &service.OutboxMessage{}
+	if err := repo.First(data, "recipient = ?", "user1@example.com").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for attempt := 1; attempt <= 2; attempt++ {
+
+		if err := repo.Model(data).Update("next_attempt_at", time.Now().UTC().Add(-time.Second)).Error; err != nil {
+			t.Fatal(err)
+		}
+		if sent, err := srv.DeliverDue(); err != nil || sent != 0 {
+			t.Fatalf("attempt %v sent = %v, %v, want failure", attempt, sent, err)
+		}
+	}
+
+	if data, _ = srv.FindByID(data.ID); data.Status != service.OutboxStatusFailed || data.Attempts != 2 || data.LastError == "" {
+		t.Fatalf("message = %v %v %q, want dead letter", data.Status, data.Attempts, data.LastError)
+	}
+
+	down.Store(false)
+
+	if sent, _ := srv.DeliverDue(); sent != 0 {
+		t.Errorf("dead letter delivered without retry")
+	}
+
+	if status := serveOutboxEntity(t, appService, admin, http.MethodPost, consts.PathAuthAdminOutboxEntityRetryAPI, data.ID); status != http.StatusOK {
+		t.Fatalf("retry status = %v, want %v", status, http.StatusOK)
+	}
+	if status := serveOutboxEntity(t, appService, admin, http.MethodPost, consts.PathAuthAdminOutboxEntityRetryAPI, data.ID); status != http.StatusConflict {
+		t.Errorf("retry again status = %v, want %v", status, http.StatusConflict)
+	}
+
+	if sent, err := srv.DeliverDue(); err != nil || sent != 1 {
+		t.Fatalf("sent after retry = %v, %v, want 1", sent, err)
+	}
+
+	if got := received.Load(); got != "secret-pass" {
+		t.Errorf("received password = %v, want secret-pass", got)
+	}
+
+	if data, _ = srv.FindByID(data.ID); data.Status != service.OutboxStatusSent || data.Payload != "" {
+		t.Errorf("message = %v payload %q, want sent with payload cleared", data.Status, data.Payload)
+	}
+}
+
+func TestOutboxPayloadSealed(t *testing.T) {
+
+	appService := newTestAppService(t)
+
+	if err := appService.Messenger().SendInviteToEmail("https://example.com/signup?token=secret-token", "user2@example.com", "en"); err != nil {
+		t.Fatal(err)
+	}
+
+	data := &service.OutboxMessage{}
+	if err := appService.Repository().First(data, "recipient = ?", "user2@example.com").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if data.Payload == "" || strings.Contains(data.Payload, "secret-token") {
+		t.Errorf("payload = %q, want sealed signup token", data.Payload)
+	}
+}
@@ -0,0 +1,129 @@
+package authadmin
+
+import (
+	"go-auth-admin/internal/config"
+	controller "go-auth-admin/internal/controller"
+	"go-auth-admin/internal/util/utilpaging"
+
+	"go-auth-admin/internal/i18n"
+	"go-auth-admin/internal/service"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type RolesDTO struct {
+	Input struct {
+		utilpaging.PagingInputDTO
+	}
+	Meta struct {
+		Status int
+	}
+	Output struct {
+		Message string `json:"message,omitempty"`
+		utilpaging.PagingOutputDTO[service.Role]
+	}
+}
+
+type RolesAPIController struct {
+	appService service.AppService
+	appConfig  *config.AppConfig
+	userLang   i18n.UserLang
+
+	IsGET bool
+
+	webCtxt echo.Context // webCtxt
+
+	DTO RolesDTO
+}
+
+func (x *RolesAPIController) Handler() error {
+
+	err := x.validateDTO()
+	if err != nil {
+		return err
+	}
+
+	err = x.handleDTO()
+	if err != nil {
+		return err
+	}
+
+	err = x.responseDTO()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// NewRolesAPIController is constructor.
+func NewRolesAPIController(appService service.AppService, c echo.Context) *RolesAPIController {
+
+	appConfig := appService.Config()
+
+	return &RolesAPIController{
+		appService: appService,
+		appConfig:  appConfig,
+		userLang:   controller.UserLang(c, appService),
+		IsGET:      controller.IsGET(c),
+		webCtxt:    c,
+	}
+}
+
+func (x *RolesAPIController) validateDTO() error {
+
+	dto := &x.DTO
+	input := &dto.Input
+
+	c := x.webCtxt
+
+	if err := c.Bind(input); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (x *RolesAPIController) handleDTO() error {
+
+	dto := &x.DTO
+	input := &dto.Input
+	meta := &dto.Meta
+	output := &dto.Output
+
+	if x.IsGET {
+
+		bs := x.appService.AuthAdmin()
+
+		if err := bs.Roles().Query(&input.PagingInputDTO, &output.PagingOutputDTO); err != nil {
+			return err
+		}
+
+	} else {
+		meta.Status = http.StatusMethodNotAllowed
+		output.Message = "method action undef"
+	}
+
+	return nil
+}
+func (x *RolesAPIController) responseDTOAsAPI() (err error) {
+
+	dto := &x.DTO
+	meta := &dto.Meta
+	output := &dto.Output
+	c := x.webCtxt
+
+	if meta.Status == 0 {
+		meta.Status = http.StatusOK
+	}
+
+	return c.JSON(meta.Status, output)
+
+}
+
+func (x *RolesAPIController) responseDTO() (err error) {
+
+	return x.responseDTOAsAPI()
+
+}
@@ -0,0 +1,367 @@
+package authadmin
+
+import (
+	"errors"
+	"go-auth-admin/internal/config"
+	"go-auth-admin/internal/config/consts"
+	controller "go-auth-admin/internal/controller"
+	"go-auth-admin/internal/mvc"
+	"go-auth-admin/internal/util/utilaccess"
+
+	"strings"
+
+	"go-auth-admin/internal/i18n"
+	"go-auth-admin/internal/service"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type RoleDTO struct {
+	service.Role
+}
+type RolesEntityDTO struct {
+	Input struct {
+		Code string  `param:"code"`
+		Data RoleDTO `json:"data,omitempty"`
+	}
+	Meta struct {
+		Status int
+	}
+	Output struct {
+		mvc.ModelBaseDTO
+		Data RoleDTO `json:"data,omitempty"`
+	}
+}
+type RolesEntityAPIController struct {
+	appService service.AppService
+	appConfig  *config.AppConfig
+	userLang   i18n.UserLang
+
+	IsGET    bool
+	IsPOST   bool
+	IsPUT    bool
+	IsDELETE bool
+
+	webCtxt echo.Context // webCtxt
+
+	userAccount *service.UserAccount // actor
+	role        *service.Role
+
+	DTO RolesEntityDTO
+}
+
+func (x *RolesEntityAPIController) Handler() error {
+
+	err := x.validateDTO()
+	if err != nil {
+		return err
+	}
+
+	err = x.handleDTO()
+	if err != nil {
+		return err
+	}
+
+	err = x.responseDTO()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// NewRolesEntityAPIController is constructor.
+func NewRolesEntityAPIController(appService service.AppService, c echo.Context) *RolesEntityAPIController {
+
+	appConfig := appService.Config()
+
+	return &RolesEntityAPIController{
+		appService:  appService,
+		appConfig:   appConfig,
+		userLang:    controller.UserLang(c, appService),
+		IsGET:       controller.IsGET(c),
+		IsPOST:      controller.IsPOST(c),
+		IsPUT:       controller.IsPUT(c),
+		IsDELETE:    controller.IsDELETE(c),
+		userAccount: controller.GetAccount(c),
+		webCtxt:     c,
+	}
+}
+
+func (x *RolesEntityAPIController) actorID() string {
+	if x.userAccount != nil {
+		return x.userAccount.ID
+	}
+	return ""
+}
+
+func (x *RolesEntityAPIController) validateDTOFields() (err error) {
+
+	dto := &x.DTO
+	input := &dto.Input
+	output := &dto.Output
+	meta := &dto.Meta
+	srv := x.appService.AuthAdmin()
+
+	if x.IsPOST {
+
+		{
+			input.Data.Code = strings.TrimSpace(input.Data.Code)
+		}
+
+		{
+			v := output.NewModelValidatorStr(x.userLang, "code", "Code" /*Lang*/, input.Data.Code, consts.RoleCodeMaxLength)
+			if !v.Required() && !utilaccess.IsRoleCode(input.Data.Code) {
+				output.AddError("code", x.userLang.Lang("Code '{0}' is invalid.", input.Data.Code))
+			}
+		}
+
+	}
+
+	if x.IsPOST || x.IsPUT {
+
+		{
+			input.Data.Description = strings.TrimSpace(input.Data.Description)
+		}
+
+		{
+			_ = output.NewModelValidatorStr(x.userLang, "description", "Description" /*Lang*/, input.Data.Description, consts.TitleTextLengthLarge)
+		}
+
+	}
+
+	if !output.IsModelValid() {
+		meta.Status = http.StatusUnprocessableEntity // 422 validation
+		return nil
+	}
+
+	if x.IsGET || x.IsPUT || x.IsDELETE {
+		// exists: get, update, delete
+
+		x.role, err = srv.Roles().FindByCode(input.Code)
+		if err != nil {
+			return err
+		}
+
+		if x.role == nil {
+			meta.Status = http.StatusNotFound // 404
+			return nil
+		}
+
+	}
+
+	if x.IsPOST {
+		// code dupl
+
+		val := input.Data.Code
+		data, err := srv.Roles().FindByCode(val)
+		if err != nil {
+			return err
+		}
+		if data != nil {
+			meta.Status = http.StatusConflict                                     // e.g., duplicate data 409
+			output.AddError("code", x.userLang.Lang("Duplicate entry {0}.", val)) // Lang
+			return nil
+		}
+	}
+
+	if x.IsDELETE {
+
+		if x.role.IsSystem {
+			meta.Status = http.StatusConflict // 409
+			output.AddError("", x.userLang.Lang("System role {0} cannot be deleted.", x.role.Code))
+			return nil
+		}
+
+		inUse, err := srv.Roles().InUse(x.role.Code)
+		if err != nil {
+			return err
+		}
+		if inUse {
+			meta.Status = http.StatusConflict // 409
+			output.AddError("", x.userLang.Lang("Role {0} is in use.", x.role.Code))
+			return nil
+		}
+
+	}
+
+	return nil
+
+}
+
+func (x *RolesEntityAPIController) validateDTO() error {
+
+	dto := &x.DTO
+	input := &dto.Input
+
+	c := x.webCtxt
+
+	if err := c.Bind(input); err != nil {
+		return err
+	}
+
+	return x.validateDTOFields()
+
+}
+func (x *RolesEntityAPIController) handleGET() (err error) {
+	dto := &x.DTO
+	output := &dto.Output
+
+	output.Data.Role = *x.role // copy
+
+	return nil
+}
+
+func (x *RolesEntityAPIController) handlePOST() (err error) {
+	dto := &x.DTO
+	input := &dto.Input
+	output := &dto.Output
+	srv := x.appService.AuthAdmin()
+
+	output.Data.Role = service.Role{
+		Code:        input.Data.Code,
+		Description: input.Data.Description,
+	}
+
+	if err = srv.Roles().Create(&output.Data.Role); err != nil {
+		return err
+	}
+
+	return srv.AuditLogs().Add(x.actorID(), service.AuditActionRoleCreate, output.Data.Code, "")
+
+}
+func (x *RolesEntityAPIController) handlePUT() (err error) {
+	dto := &x.DTO
+	input := &dto.Input
+	output := &dto.Output
+	srv := x.appService.AuthAdmin()
+
+	output.Data.Role = *x.role // copy
+	output.Data.Description = input.Data.Description
+
+	if err = srv.Roles().Update(&output.Data.Role); err != nil {
+		return err
+	}
+
+	return srv.AuditLogs().Add(x.actorID(), service.AuditActionRoleUpdate, output.Data.Code, "")
+
+}
+func (x *RolesEntityAPIController) handleDELETE() (err error) {
+
+	dto := &x.DTO
+	input := &dto.Input
+	output := &dto.Output
+	srv := x.appService.AuthAdmin()
+
+	output.Data.Code = input.Code
+
+	if err = srv.Roles().Delete(input.Code); err != nil {
+		return err
+	}
+
+	return srv.AuditLogs().Add(x.actorID(), service.AuditActionRoleDelete, input.Code, "")
+
+}
+func (x *RolesEntityAPIController) handleDTO() error {
+
+	dto := &x.DTO
+	meta := &dto.Meta
+	output := &dto.Output
+
+	if meta.Status > 0 {
+		return nil // stop processing
+	}
+
+	switch {
+
+	case x.IsGET:
+		return x.handleGET()
+	case x.IsPOST:
+		return x.handlePOST()
+	case x.IsPUT:
+		return x.handlePUT()
+	case x.IsDELETE:
+		return x.handleDELETE()
+	default:
+		{
+			meta.Status = http.StatusMethodNotAllowed
+			output.AddError("", "method action undef")
+		}
+	}
+
+	return nil
+}
+func (x *RolesEntityAPIController) responseDTOAsAPI() (err error) {
+
+	dto := &x.DTO
+	meta := &dto.Meta
+	output := &dto.Output
+	c := x.webCtxt
+
+	if meta.Status == 0 {
+		meta.Status = http.StatusOK
+	}
+
+	return c.JSON(meta.Status, output)
+
+}
+
+func (x *RolesEntityAPIController) responseDTO() (err error) {
+
+	return x.responseDTOAsAPI()
+
+}
+
+// validateRoles adds model error for each role code not in catalogue
+func validateRoles(appService service.AppService, userLang i18n.UserLang, output *mvc.ModelBaseDTO, roles string) error {
+
+	unknown, err := appService.AuthAdmin().Roles().Unknown(roles)
+	if err != nil {
+		return err
+	}
+
+	for _, code := range unknown {
+		output.AddError("roles", userLang.Lang("Unknown role {0}.", code))
+	}
+
+	return nil
+}
+
+// canEditRoles actor may grant and revoke roles (field permission of account roles)
+func canEditRoles(appService service.AppService, actor *service.UserAccount) bool {
+
+	permissions := utilaccess.PermissionsDTO{}
+	appService.AuthAdmin().UserAccounts().Permissions(actor, &permissions)
+
+	return permissions.CanEditField(service.FieldRoles)
+}
+
+// validateRolesChange adds model error for each granted or revoked role the actor does not hold,
+// admin may change any role
+func validateRolesChange(userLang i18n.UserLang, output *mvc.ModelBaseDTO, actor *service.UserAccount, before string, after string) {
+
+	var actorRoles []string
+	if actor != nil {
+		actorRoles = actor.EffectiveRoleCodes()
+	}
+
+	changed := utilaccess.ChangedRoles(strings.Fields(before), strings.Fields(after))
+
+	for _, code := range utilaccess.NotHeldRoles(actorRoles, changed...) {
+		output.AddError("roles", userLang.Lang("Role {0} cannot be granted or revoked without holding it.", code))
+	}
+}
+
+// isLastAdmin maps service.ErrLastAdmin to 409
+func isLastAdmin(err error, userLang i18n.UserLang, output *mvc.ModelBaseDTO, status *int) bool {
+
+	if !errors.Is(err, service.ErrLastAdmin) {
+		return false
+	}
+
+	*status = http.StatusConflict // 409
+	output.AddError("", userLang.Lang("The last admin cannot be deleted or demoted."))
+
+	return true
+}
@@ -0,0 +1,202 @@
+package authadmin
+
+import (
+	"go-auth-admin/internal/service"
+	xtoken "go-auth-admin/internal/token"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/labstack/echo/v4"
+)
+
+// newTestReplicas app services over same db and redis
+func newTestReplicas(t *testing.T) (service.AppService, service.AppService) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+
+	t.Setenv("APP_REDIS_DIALECT", "redis")
+	t.Setenv("APP_REDIS_HOST", mr.Host())
+	t.Setenv("APP_REDIS_PORT", mr.Port())
+
+	first := newTestAppService(t)
+
+	t.Setenv("APP_DB_HOST", first.Config().DB.Host) // db of first
+
+	second := service.MustNewAppServiceTesting()
+
+	for _, v := range []service.AppService{first, second} {
+		t.Cleanup(func() { _ = v.Store().Close() })
+	}
+
+	return first, second
+}
+
+func TestSharedTokenRevocation(t *testing.T) {
+
+	first, second := newTestReplicas(t)
+
+	target := newTestAccount(t, first, "target1", "")
+
+	claims := &xtoken.TokenClaimsDTO{UserID: target.ID}
+	newTestToken(t, first, claims)
+
+	if revoked, err := second.TokenRevocation().IsRevoked(claims); err != nil || revoked {
+		t.Fatalf("IsRevoked() = %v, %v, want false", revoked, err)
+	}
+
+	if err := first.AuthAdmin().UserAccounts().UpdatePassword(target.ID, "Secret-pass-123"); err != nil {
+		t.Fatal(err)
+	}
+
+	if revoked, err := second.TokenRevocation().IsRevoked(claims); err != nil || !revoked {
+		t.Errorf("IsRevoked() on other replica = %v, %v, want true after password change", revoked, err)
+	}
+
+	later := &xtoken.TokenClaimsDTO{UserID: target.ID}
+	newTestToken(t, first, later)
+	later.IssuedAt.Time = later.IssuedAt.Add(time.Second) // signed in after change
+
+	if revoked, _ := second.TokenRevocation().IsRevoked(later); revoked {
+		t.Error("IsRevoked() of later token = true, want false")
+	}
+}
+
+func TestSharedVaultKeys(t *testing.T) {
+
+	first, second := newTestReplicas(t)
+
+	ctx := t.Context()
+	go second.Vault().WatchKeys(ctx)
+
+	key, err := service.NewVaultKey() // added by auth app
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = first.Repository().Create(key).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// reload until subscription of second is made
+	for deadline := time.Now().Add(2 * time.Second); ; {
+
+		if err = first.ConfigSource().Reload(); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err = first.Vault().KeyByID(key.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err = second.Vault().KeyByID(key.ID); err == nil {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("key %v is not loaded by other replica", key.ID)
+		}
+
+		// notified once per change, removed key changes keys of first again
+		if err = first.Repository().Delete(key).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err = first.ConfigSource().Reload(); err != nil {
+			t.Fatal(err)
+		}
+		if err = first.Repository().Create(key).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func serveVerify(t *testing.T, appService service.AppService, id string, body string) int {
+	t.Helper()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id", "contact")
+	c.SetParamValues(id, service.ContactEmail)
+
+	if err := NewAccountsVerifyAPIController(appService, c).Handler(); err != nil {
+		t.Fatal(err)
+	}
+
+	return rec.Code
+}
+
+func TestSharedPasscodeReplay(t *testing.T) {
+
+	first, second := newTestReplicas(t)
+
+	target := newTestAccount(t, first, "target1", "")
+	target.Email = "target1@example.com"
+	if err := first.AuthAdmin().UserAccounts().Update(target); err != nil {
+		t.Fatal(err)
+	}
+
+	_, secret, err := first.Vault().KeyScopeAuth().CurrentKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	passcode, err := xtoken.GeneratePasscode(xtoken.NewConfigTotp(service.IssuerConfirmEmail+" "+target.Email, secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code := serveVerify(t, first, target.ID, `{"passcode":"00000000"}`); code != http.StatusUnprocessableEntity {
+		t.Errorf("wrong passcode status = %v, want %v", code, http.StatusUnprocessableEntity)
+	}
+
+	if code := serveVerify(t, first, target.ID, `{"passcode":"`+passcode+`"}`); code != http.StatusOK {
+		t.Errorf("passcode status = %v, want %v", code, http.StatusOK)
+	}
+
+	if code := serveVerify(t, second, target.ID, `{"passcode":"`+passcode+`"}`); code != http.StatusUnprocessableEntity {
+		t.Errorf("replayed passcode on other replica status = %v, want %v", code, http.StatusUnprocessableEntity)
+	}
+}
+
+func TestSharedRevocationUnavailable(t *testing.T) {
+
+	mr := miniredis.RunT(t)
+
+	t.Setenv("APP_REDIS_DIALECT", "redis")
+	t.Setenv("APP_REDIS_HOST", mr.Host())
+	t.Setenv("APP_REDIS_PORT", mr.Port())
+
+	appService := newTestAppService(t)
+	t.Cleanup(func() { _ = appService.Store().Close() })
+
+	srv := appService.AuthAdmin().UserAccounts()
+
+	target := newTestAccount(t, appService, "target1", "")
+
+	mr.Close() // store down
+
+	if err := srv.UpdatePassword(target.ID, "Secret-pass-123"); err == nil {
+		t.Error("UpdatePassword() error = nil, want error when sessions cannot be revoked")
+	}
+
+	stored, err := srv.FindByID(target.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.PasswordHash != target.PasswordHash {
+		t.Error("password is changed, sessions with old password are alive")
+	}
+
+	if err = srv.Delete(target.ID); err != nil {
+		t.Errorf("Delete() error = %v, want nil, account is deleted", err)
+	}
+}
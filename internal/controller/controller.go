@@ -29,10 +29,12 @@ func IsPOST(c echo.Context) bool {
 	return c.Request().Method == "POST"
 }
 
-// func CsrfToHeader(c echo.Context) {
-// 	csrf, _ := c.Get("_csrf").(string)
-// 	c.Response().Header().Set("X-CSRF-Token", csrf)
-// }
+// CsrfToHeader token of CsrfMiddleware, client sends it back on unsafe methods
+func CsrfToHeader(c echo.Context) {
+	if csrf, _ := c.Get(xweb.CsrfKey).(string); csrf != "" {
+		c.Response().Header().Set(xweb.CsrfHeader, csrf)
+	}
+}
 
 // func newTokenPersist(c echo.Context, appService service.AppService) xtoken.TokenPersist {
 // 	return xweb.NewTokenPersist(c, appService)
@@ -28,17 +28,30 @@ type AppLang interface {
 
 func NewAppLang(config *config.AppConfig) AppLang {
 
+	res, err := LoadAppLang(config)
+	if err != nil {
+		panic(err)
+	}
+
+	return res
+}
+
+// LoadAppLang reads lang files of config langs, used on config reload
+func LoadAppLang(config *config.AppConfig) (AppLang, error) {
+
 	res := &appLang{
 		langs: config.Lang.Langs,
 		data:  map[string]map[string]string{},
 	}
 
 	if len(res.langs) == 0 {
-		panic(fmt.Errorf("error no any lang in app config"))
+		return nil, fmt.Errorf("error no any lang in app config")
 	}
 
 	// config.ConfigPath == []string{".", os.Getenv("APP_CONFIG"), flagAppConfig}
-	res.loadFromConfigFiles(config.ConfigPath, res.langs)
+	if err := res.loadFromConfigFiles(config.ConfigPath, res.langs); err != nil {
+		return nil, err
+	}
 
 	for _, k := range res.langs {
 		name := res.data[k][k]
@@ -47,7 +60,7 @@ func NewAppLang(config *config.AppConfig) AppLang {
 
 	res.defaultLang = res.langs[0]
 
-	return res
+	return res, nil
 }
 
 type appLang struct {
@@ -81,7 +94,7 @@ func (x *appLang) UserLang(code string) UserLang {
 }
 
 // loadFromConfigFiles load lang data from resources if file exists
-func (x *appLang) loadFromConfigFiles(configPath []string, langs []string) {
+func (x *appLang) loadFromConfigFiles(configPath []string, langs []string) error {
 
 	// Initialize the result map
 	result := make(map[string]map[string]string)
@@ -97,7 +110,7 @@ func (x *appLang) loadFromConfigFiles(configPath []string, langs []string) {
 
 			err := utilconfig.LoadConfig(&fileData, dir, fileName)
 			if err != nil {
-				panic(fmt.Errorf("error reading file: %v", err))
+				return fmt.Errorf("error reading file: %v", err)
 			}
 
 			result[langCode] = fileData // override
@@ -106,6 +119,8 @@ func (x *appLang) loadFromConfigFiles(configPath []string, langs []string) {
 	}
 
 	maps.Copy(x.data, result)
+
+	return nil
 }
 
 // Lang translate en-to-es Lang(`Hello, {0}`,`Jon`) to `Hola, Jon`
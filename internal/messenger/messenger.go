@@ -1,23 +1,68 @@
 package messenger
 
 import (
+	"fmt"
 	"go-auth-admin/internal/config"
-	"go-auth-admin/internal/util/utilhttp"
 	xlog "go-auth-admin/internal/util/utillog"
-	"strings"
+	"maps"
+	"slices"
 )
 
+// message kinds, also messenger service codes and route keys
+const (
+	KindSmsPasscode   = "sms-passcode"
+	KindEmailPasscode = "email-passcode"
+	KindSmsPassword   = "sms-password"
+	KindEmailPassword = "email-password"
+	KindSmsInvite     = "sms-invite"
+	KindEmailInvite   = "email-invite"
+)
+
+// secretDataKeys form values never logged, rendered text has data too
+var secretDataKeys = []string{"password", "link", "subject", "text", "html"} // link has signup token
+
+// Outbox durable queue, messages are delivered later by Deliver
+type Outbox interface {
+	Enqueue(kind string, recipient string, formValues map[string]string) error
+}
+
 type AppMessenger interface {
 	// SendSms(text string, tel string)
 	// SendEmail(html string, subject string, email string)
 
+	// Send renders kind template in lang with data and dispatches it to recipient
+	Send(kind string, recipient string, lang string, data map[string]string) error
+	// SendNow is Send bypassing outbox, error tells the message is not delivered
+	SendNow(kind string, recipient string, lang string, data map[string]string) error
+	// Preview renders kind template without dispatch
+	Preview(kind string, lang string, data map[string]string) (*Message, error)
+	Kinds() []string
+	// Deliver sends rendered form values by transport of kind now, used by outbox
+	Deliver(kind string, formValues map[string]string) error
+	// SetOutbox makes Send enqueue messages, nil sends synchronously
+	SetOutbox(outbox Outbox)
+	// SetSigner signs requests of service and webhook transports, nil sends unsigned
+	SetSigner(signer Signer)
+
 	SendPasscodeToTel(code string, tel string, lang string)
 	SendPasscodeToEmail(code string, email string, lang string)
+
+	SendPasswordToTel(password string, tel string, lang string) error
+	SendPasswordToEmail(password string, email string, lang string) error
+
+	SendInviteToTel(link string, tel string, lang string) error
+	SendInviteToEmail(link string, email string, lang string) error
 }
 
 type defaultAppMessenger struct {
-	Debug  bool
-	config config.AppConfigMessenger
+	Debug     bool
+	config    config.AppConfigMessenger
+	templates *Templates
+	outbox    Outbox
+
+	transports map[string]Transport // by name
+	routes     map[string]string    // kind -> transport name
+	signing    requestSigner
 	// logger logger.AppLogger
 }
 
@@ -26,37 +71,149 @@ func NewAppMessenger(config *config.AppConfig,
 // logger logger.AppLogger
 ) (res AppMessenger) {
 
+	res, err := LoadAppMessenger(config)
+	if err != nil {
+		panic(err)
+	}
+
+	return res
+}
+
+// LoadAppMessenger reads templates and builds transports, used on config reload
+func LoadAppMessenger(config *config.AppConfig) (AppMessenger, error) {
+
 	// queue
 	// background task
 	// http client || tmp file
 	// config
 	// logger // TODO named logger
 
-	res = &defaultAppMessenger{
-		Debug:  config.Debug,
-		config: config.Messenger,
+	templates, err := LoadTemplates(config.ConfigPath, config.Lang.Langs)
+	if err != nil {
+		return nil, err
+	}
+
+	x := &defaultAppMessenger{
+		Debug:     config.Debug,
+		config:    config.Messenger,
+		templates: templates,
+		routes:    config.Messenger.Routes,
 		// logger: logger,
 	}
 
-	return
+	if x.transports, err = newTransports(config.Messenger, &x.signing); err != nil {
+		return nil, err
+	}
+
+	for kind := range x.routes {
+		if kind != routeAnyKind && !slices.Contains(x.templates.Kinds(), kind) {
+			return nil, fmt.Errorf("error messenger route of unknown message kind: %v", kind) // typo would silently use default
+		}
+	}
+
+	return x, nil
 }
 
-func (x *defaultAppMessenger) SendPasscodeToTel(passcode string, tel string, lang string) {
-	serviceCode := "sms-passcode" // _ -
+// transport of kind route, any kind route, messenger service
+func (x *defaultAppMessenger) transport(kind string) Transport {
 
-	formValues := map[string]string{
-		"to":       tel,
-		"passcode": passcode,
-		"lang":     lang,
+	for _, v := range []string{kind, routeAnyKind} {
+		if name, ok := x.routes[v]; ok {
+			return x.transports[name]
+		}
 	}
 
+	return x.transports[TransportService]
+}
+
+// send delivers form values by transport routed for kind
+func (x *defaultAppMessenger) send(kind string, formValues map[string]string) error {
+
 	if x.Debug || x.config.Stdout {
-		xlog.Info(serviceCode, formValues)
+		xlog.Info("%v %v", kind, logValues(formValues))
 	}
 
-	URL := strings.ReplaceAll(x.config.ServiceURL, "{code}", serviceCode)
+	return x.transport(kind).Send(newMessage(kind, formValues))
+}
+
+// logValues form values without secrets
+func logValues(formValues map[string]string) map[string]string {
+	res := maps.Clone(formValues)
+	maps.DeleteFunc(res, func(k string, _ string) bool { return slices.Contains(secretDataKeys, k) })
+	return res
+}
+
+func (x *defaultAppMessenger) SetOutbox(outbox Outbox) {
+	x.outbox = outbox
+}
+
+func (x *defaultAppMessenger) SetSigner(signer Signer) {
+	x.signing.signer = signer
+}
+
+func (x *defaultAppMessenger) Deliver(kind string, formValues map[string]string) error {
+	return x.send(kind, formValues)
+}
+
+func (x *defaultAppMessenger) Kinds() []string {
+	return x.templates.Kinds()
+}
 
-	_, err := utilhttp.PostFormURL(URL, nil, nil, formValues)
+func (x *defaultAppMessenger) Preview(kind string, lang string, data map[string]string) (*Message, error) {
+	return x.templates.Render(kind, lang, data)
+}
+
+// Send delivers data and rendered subject, text, html by transport of kind,
+// or stores them in outbox if set
+func (x *defaultAppMessenger) Send(kind string, recipient string, lang string, data map[string]string) error {
+
+	formValues, err := x.render(kind, recipient, lang, data)
+	if err != nil {
+		return err
+	}
+
+	if x.outbox != nil {
+		return x.outbox.Enqueue(kind, recipient, formValues)
+	}
+
+	return x.send(kind, formValues)
+}
+
+func (x *defaultAppMessenger) SendNow(kind string, recipient string, lang string, data map[string]string) error {
+
+	formValues, err := x.render(kind, recipient, lang, data)
+	if err != nil {
+		return err
+	}
+
+	return x.send(kind, formValues)
+}
+
+// render form values of message: data, recipient, lang and rendered subject, text, html
+func (x *defaultAppMessenger) render(kind string, recipient string, lang string, data map[string]string) (map[string]string, error) {
+
+	msg, err := x.templates.Render(kind, lang, data)
+	if err != nil {
+		return nil, err
+	}
+
+	formValues := maps.Clone(data)
+	if formValues == nil {
+		formValues = map[string]string{}
+	}
+
+	formValues["to"] = recipient
+	formValues["lang"] = lang
+	formValues["subject"] = msg.Subject
+	formValues["text"] = msg.Text
+	formValues["html"] = msg.HTML
+
+	return formValues, nil
+}
+
+func (x *defaultAppMessenger) SendPasscodeToTel(passcode string, tel string, lang string) {
+
+	err := x.Send(KindSmsPasscode, tel, lang, map[string]string{"passcode": passcode})
 
 	if err != nil {
 		xlog.Error("error from sms service: %v", err)
@@ -66,24 +223,54 @@ func (x *defaultAppMessenger) SendPasscodeToTel(passcode string, tel string, lan
 
 func (x *defaultAppMessenger) SendPasscodeToEmail(passcode string, email string, lang string) {
 
-	serviceCode := "email-passcode" // _ -
+	err := x.Send(KindEmailPasscode, email, lang, map[string]string{"passcode": passcode})
 
-	formValues := map[string]string{
-		"to":       email,
-		"passcode": passcode,
-		"lang":     lang,
+	if err != nil {
+		xlog.Error("error from sms service: %v", err)
 	}
 
-	if x.Debug || x.config.Stdout {
-		xlog.Info(serviceCode, formValues)
+}
+
+func (x *defaultAppMessenger) SendPasswordToTel(password string, tel string, lang string) error {
+
+	err := x.Send(KindSmsPassword, tel, lang, map[string]string{"password": password})
+
+	if err != nil {
+		xlog.Error("error from sms service: %v", err)
+	}
+
+	return err
+}
+
+func (x *defaultAppMessenger) SendPasswordToEmail(password string, email string, lang string) error {
+
+	err := x.Send(KindEmailPassword, email, lang, map[string]string{"password": password})
+
+	if err != nil {
+		xlog.Error("error from email service: %v", err)
 	}
 
-	URL := strings.ReplaceAll(x.config.ServiceURL, "{code}", serviceCode)
+	return err
+}
+
+func (x *defaultAppMessenger) SendInviteToTel(link string, tel string, lang string) error {
 
-	_, err := utilhttp.PostFormURL(URL, nil, nil, formValues)
+	err := x.Send(KindSmsInvite, tel, lang, map[string]string{"link": link})
 
 	if err != nil {
 		xlog.Error("error from sms service: %v", err)
 	}
 
+	return err
+}
+
+func (x *defaultAppMessenger) SendInviteToEmail(link string, email string, lang string) error {
+
+	err := x.Send(KindEmailInvite, email, lang, map[string]string{"link": link})
+
+	if err != nil {
+		xlog.Error("error from email service: %v", err)
+	}
+
+	return err
 }
@@ -0,0 +1,227 @@
+package messenger
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"go-auth-admin/internal/util/utilconfig"
+	htmltemplate "html/template"
+	"maps"
+	"slices"
+	"strings"
+	texttemplate "text/template"
+)
+
+// templateFileName template files in config dirs, next to lang.<lang>.json
+const templateFileName = "messages.%s.json"
+
+// templateLangDefault lang of embedded templates, fallback for any lang
+const templateLangDefault = "en"
+
+//go:embed messages.en.json
+var defaultTemplates []byte
+
+// TemplateSource message template of kind, sms needs text only
+type TemplateSource struct {
+	Subject string `json:"subject,omitempty"`
+	Text    string `json:"text,omitempty"`
+	HTML    string `json:"html,omitempty"`
+}
+
+// Message rendered message, ready to dispatch
+type Message struct {
+	Kind    string `json:"kind"`
+	To      string `json:"to"`
+	Lang    string `json:"lang"`
+	Subject string `json:"subject,omitempty"`
+	Text    string `json:"text,omitempty"`
+	HTML    string `json:"html,omitempty"`
+
+	Data map[string]string `json:"data,omitempty"` // template data, set on delivery only
+}
+
+type messageTemplate struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template // escapes data
+}
+
+// Templates compiled templates by lang and kind
+type Templates struct {
+	defaultLang string
+	data        map[string]map[string]*messageTemplate // lang -> kind -> template
+}
+
+// LoadTemplates embedded templates overridden per kind by messages.<lang>.json from config dirs
+func LoadTemplates(configPath []string, langs []string) (*Templates, error) {
+
+	res := &Templates{
+		defaultLang: templateLangDefault,
+		data:        map[string]map[string]*messageTemplate{},
+	}
+
+	if len(langs) > 0 {
+		res.defaultLang = langs[0]
+	}
+
+	base := map[string]TemplateSource{}
+	if err := json.Unmarshal(defaultTemplates, &base); err != nil {
+		return nil, fmt.Errorf("error on default message templates: %v", err)
+	}
+
+	for _, lang := range slices.Compact(slices.Sorted(slices.Values(append([]string{templateLangDefault}, langs...)))) {
+
+		sources := map[string]TemplateSource{}
+		if lang == templateLangDefault {
+			maps.Copy(sources, base)
+		}
+
+		fileName := fmt.Sprintf(templateFileName, lang)
+
+		for _, dir := range configPath {
+
+			if !utilconfig.HasFile(dir, fileName) {
+				continue
+			}
+
+			var fileData map[string]TemplateSource
+
+			if err := utilconfig.LoadConfig(&fileData, dir, fileName); err != nil {
+				return nil, fmt.Errorf("error reading file: %v", err)
+			}
+
+			maps.Copy(sources, fileData) // override per kind
+		}
+
+		res.data[lang] = map[string]*messageTemplate{}
+
+		for kind, src := range sources {
+			tmpl, err := compileTemplate(kind+"."+lang, src)
+			if err != nil {
+				return nil, err
+			}
+			res.data[lang][kind] = tmpl
+		}
+	}
+
+	return res, nil
+}
+
+func MustLoadTemplates(configPath []string, langs []string) *Templates {
+	res, err := LoadTemplates(configPath, langs)
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+func compileTemplate(name string, src TemplateSource) (res *messageTemplate, err error) {
+
+	if src.Text == "" && src.HTML == "" {
+		return nil, fmt.Errorf("error message template has no body: %v", name)
+	}
+
+	res = &messageTemplate{}
+
+	if src.Subject != "" {
+		if res.subject, err = texttemplate.New(name).Option("missingkey=error").Parse(src.Subject); err != nil {
+			return nil, fmt.Errorf("error message template subject %v: %v", name, err)
+		}
+	}
+	if src.Text != "" {
+		if res.text, err = texttemplate.New(name).Option("missingkey=error").Parse(src.Text); err != nil {
+			return nil, fmt.Errorf("error message template text %v: %v", name, err)
+		}
+	}
+	if src.HTML != "" {
+		if res.html, err = htmltemplate.New(name).Option("missingkey=error").Parse(src.HTML); err != nil {
+			return nil, fmt.Errorf("error message template html %v: %v", name, err)
+		}
+	}
+
+	return res, nil
+}
+
+// Kinds known message kinds of any lang
+func (x *Templates) Kinds() []string {
+
+	res := []string{}
+	for _, v := range x.data {
+		res = slices.AppendSeq(res, maps.Keys(v))
+	}
+
+	return slices.Compact(slices.Sorted(slices.Values(res)))
+}
+
+// lookup template of lang, default lang, embedded lang
+func (x *Templates) lookup(kind string, lang string) (*messageTemplate, string) {
+
+	for _, v := range []string{lang, x.defaultLang, templateLangDefault} {
+		if tmpl := x.data[v][kind]; tmpl != nil {
+			return tmpl, v
+		}
+	}
+
+	return nil, ""
+}
+
+// Render renders message of kind in lang, data keys are template fields {{.key}}
+func (x *Templates) Render(kind string, lang string, data map[string]string) (*Message, error) {
+
+	tmpl, tmplLang := x.lookup(kind, lang)
+	if tmpl == nil {
+		return nil, fmt.Errorf("error unknown message kind: %v", kind)
+	}
+
+	if data == nil {
+		data = map[string]string{}
+	}
+
+	res := &Message{
+		Kind: kind,
+		Lang: tmplLang,
+	}
+
+	var err error
+
+	if res.Subject, err = executeText(tmpl.subject, data); err != nil {
+		return nil, fmt.Errorf("error on render message %v: %v", kind, err)
+	}
+	if res.Text, err = executeText(tmpl.text, data); err != nil {
+		return nil, fmt.Errorf("error on render message %v: %v", kind, err)
+	}
+	if res.HTML, err = executeHTML(tmpl.html, data); err != nil {
+		return nil, fmt.Errorf("error on render message %v: %v", kind, err)
+	}
+
+	return res, nil
+}
+
+func executeText(tmpl *texttemplate.Template, data any) (string, error) {
+
+	if tmpl == nil {
+		return "", nil
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(buf.String()), nil
+}
+
+func executeHTML(tmpl *htmltemplate.Template, data any) (string, error) {
+
+	if tmpl == nil {
+		return "", nil
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(buf.String()), nil
+}
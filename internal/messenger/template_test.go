@@ -0,0 +1,71 @@
+package messenger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTemplatesRender(t *testing.T) {
+
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "messages.de.json"), []byte(`{
+		"email-passcode": { "subject": "Ihr Code", "text": "Code {{.passcode}}", "html": "<p>{{.passcode}}</p>" }
+	}`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	templates, err := LoadTemplates([]string{dir}, []string{"en", "de"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		kind     string
+		lang     string
+		data     map[string]string
+		wantLang string
+		wantText string
+		wantHTML string
+		wantErr  bool
+	}{
+		{"embedded", KindSmsPasscode, "en", map[string]string{"passcode": "1234"}, "en", "Your passcode is 1234.", "", false},
+		{"config file", KindEmailPasscode, "de", map[string]string{"passcode": "1234"}, "de", "Code 1234", "<p>1234</p>", false},
+		{"lang fallback", KindSmsPasscode, "de", map[string]string{"passcode": "1234"}, "en", "Your passcode is 1234.", "", false},
+		{"html escaped", KindEmailPasscode, "de", map[string]string{"passcode": "<b>"}, "de", "Code <b>", "<p>&lt;b&gt;</p>", false},
+		{"missing key", KindSmsPasscode, "en", nil, "", "", "", true},
+		{"unknown kind", "fax-passcode", "en", nil, "", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := templates.Render(tt.kind, tt.lang, tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Render() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.Lang != tt.wantLang || got.Text != tt.wantText || got.HTML != tt.wantHTML {
+				t.Errorf("Render() = %+v, want lang %v text %v html %v", got, tt.wantLang, tt.wantText, tt.wantHTML)
+			}
+		})
+	}
+}
+
+func TestLoadTemplatesInvalid(t *testing.T) {
+
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "messages.en.json"), []byte(`{
+		"sms-passcode": { "text": "{{.passcode" }
+	}`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadTemplates([]string{dir}, []string{"en"}); err == nil || !strings.Contains(err.Error(), "sms-passcode") {
+		t.Errorf("LoadTemplates() error = %v, want parse error of sms-passcode", err)
+	}
+}
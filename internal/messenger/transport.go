@@ -0,0 +1,415 @@
+package messenger
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"go-auth-admin/internal/config"
+	"go-auth-admin/internal/util/utilcrypto"
+	"go-auth-admin/internal/util/utilhttp"
+	"go-auth-admin/pkg/msgsign"
+	"io"
+	"maps"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// transport names, used by config messenger.routes
+const (
+	TransportService = "service" // form post to messenger service
+	TransportSMTP    = "smtp"
+	TransportFile    = "file"
+	TransportWebhook = "webhook"
+)
+
+// routeAnyKind route of kinds not listed
+const routeAnyKind = "*"
+
+// messageFields form values of rendered message, other values are template data
+var messageFields = []string{"to", "lang", "subject", "text", "html"}
+
+// Transport delivers rendered message
+type Transport interface {
+	Send(msg *Message) error
+}
+
+// Signer current vault key, signs requests of http transports
+type Signer interface {
+	CurrentKey() (id string, secret []byte, err error)
+}
+
+// requestSigner shared by http transports, signer is set after vault is loaded
+type requestSigner struct {
+	signer Signer
+}
+
+// headers signature headers of post to rawURL, none if signer is not set
+func (x *requestSigner) headers(rawURL string, body []byte) (map[string]string, error) {
+
+	res := map[string]string{}
+
+	if x.signer == nil {
+		return res, nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	keyID, secret, err := x.signer.CurrentKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return msgsign.Headers(keyID, msgsign.DeriveKey(secret), http.MethodPost, u.RequestURI(), body, time.Now())
+}
+
+// newMessage rendered message from form values of outbox
+func newMessage(kind string, formValues map[string]string) *Message {
+
+	res := &Message{
+		Kind:    kind,
+		To:      formValues["to"],
+		Lang:    formValues["lang"],
+		Subject: formValues["subject"],
+		Text:    formValues["text"],
+		HTML:    formValues["html"],
+		Data:    maps.Clone(formValues),
+	}
+
+	maps.DeleteFunc(res.Data, func(k string, _ string) bool { return slices.Contains(messageFields, k) })
+
+	return res
+}
+
+// formValues data and rendered fields, as posted to messenger service
+func (x *Message) formValues() map[string]string {
+
+	res := maps.Clone(x.Data)
+	if res == nil {
+		res = map[string]string{}
+	}
+
+	res["to"] = x.To
+	res["lang"] = x.Lang
+	res["subject"] = x.Subject
+	res["text"] = x.Text
+	res["html"] = x.HTML
+
+	return res
+}
+
+// newTransports transports used by routes, error on unknown or not configured transport
+func newTransports(cfg config.AppConfigMessenger, signing *requestSigner) (map[string]Transport, error) {
+
+	res := map[string]Transport{}
+
+	names := slices.Collect(maps.Values(cfg.Routes))
+	names = append(names, TransportService) // default route
+
+	for _, name := range names {
+
+		if res[name] != nil {
+			continue
+		}
+
+		switch name {
+		case TransportService:
+			res[name] = &serviceTransport{serviceURL: cfg.ServiceURL, signing: signing}
+
+		case TransportSMTP:
+			if cfg.SMTP.Host == "" || cfg.SMTP.From == "" {
+				return nil, fmt.Errorf("error messenger smtp host and from are required")
+			}
+			if _, err := mail.ParseAddress(cfg.SMTP.From); err != nil {
+				return nil, fmt.Errorf("error messenger smtp from: %v", err)
+			}
+			if cfg.SMTP.Timeout <= 0 {
+				return nil, fmt.Errorf("error messenger smtp timeout must be positive")
+			}
+			res[name] = &smtpTransport{config: cfg.SMTP, tlsConfig: &tls.Config{ServerName: cfg.SMTP.Host}}
+
+		case TransportFile:
+			if cfg.File.Dir == "" {
+				return nil, fmt.Errorf("error messenger file dir is required")
+			}
+			res[name] = &fileTransport{dir: cfg.File.Dir, from: cfg.SMTP.From}
+
+		case TransportWebhook:
+			if cfg.Webhook.URL == "" {
+				return nil, fmt.Errorf("error messenger webhook url is required")
+			}
+			res[name] = &webhookTransport{url: cfg.Webhook.URL, headers: cfg.Webhook.Headers, signing: signing}
+
+		default:
+			return nil, fmt.Errorf("error unknown messenger transport: %v", name)
+		}
+	}
+
+	return res, nil
+}
+
+// serviceTransport posts form values to messenger service, {code} of url is message kind
+type serviceTransport struct {
+	serviceURL string
+	signing    *requestSigner
+}
+
+func (x *serviceTransport) Send(msg *Message) error {
+
+	URL := strings.ReplaceAll(x.serviceURL, "{code}", msg.Kind)
+
+	form := url.Values{}
+	for k, v := range msg.formValues() {
+		form.Set(k, v)
+	}
+	body := []byte(form.Encode())
+
+	headers, err := x.signing.headers(URL, body)
+	if err != nil {
+		return err
+	}
+
+	_, err = utilhttp.PostBytes(URL, headers, "application/x-www-form-urlencoded", body)
+
+	return err
+}
+
+// webhookTransport posts message as JSON
+type webhookTransport struct {
+	url     string
+	headers map[string]string
+	signing *requestSigner
+}
+
+func (x *webhookTransport) Send(msg *Message) error {
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	signature, err := x.signing.headers(x.url, body)
+	if err != nil {
+		return err
+	}
+
+	// configured headers can not replace signature
+	headers := signature
+	for k, v := range x.headers {
+		if _, ok := signature[http.CanonicalHeaderKey(k)]; !ok {
+			headers[k] = v
+		}
+	}
+
+	_, err = utilhttp.PostBytes(x.url, headers, "application/json", body)
+
+	return err
+}
+
+// smtpTransport sends email directly to SMTP server
+type smtpTransport struct {
+	config    config.AppConfigSMTP
+	tlsConfig *tls.Config
+}
+
+func (x *smtpTransport) Send(msg *Message) error {
+
+	from, _ := mail.ParseAddress(x.config.From) // checked by newTransports
+
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("error on smtp recipient: %v", err)
+	}
+
+	data, err := buildMail(x.config.From, msg)
+	if err != nil {
+		return err
+	}
+
+	timeout := time.Duration(x.config.Timeout) * time.Second
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(x.config.Host, x.config.Port), timeout)
+	if err != nil {
+		return fmt.Errorf("error on smtp dial: %v", err)
+	}
+
+	// silent server does not hold outbox worker or request
+	if err = conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("error on smtp dial: %v", err)
+	}
+
+	c, err := smtp.NewClient(conn, x.config.Host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("error on smtp dial: %v", err)
+	}
+	defer c.Close()
+
+	if x.config.StartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("error smtp server has no STARTTLS")
+		}
+		if err := c.StartTLS(x.tlsConfig); err != nil {
+			return fmt.Errorf("error on smtp STARTTLS: %v", err)
+		}
+	}
+
+	if x.config.User != "" {
+		if err := c.Auth(smtp.PlainAuth("", x.config.User, x.config.Password, x.config.Host)); err != nil {
+			return fmt.Errorf("error on smtp auth: %v", err)
+		}
+	}
+
+	if err := c.Mail(from.Address); err != nil {
+		return fmt.Errorf("error on smtp MAIL: %v", err)
+	}
+	if err := c.Rcpt(to.Address); err != nil {
+		return fmt.Errorf("error on smtp RCPT: %v", err)
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("error on smtp DATA: %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("error on smtp DATA: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("error on smtp DATA: %v", err)
+	}
+
+	return c.Quit()
+}
+
+// fileTransport writes messages to maildir, new/ has delivered files
+type fileTransport struct {
+	dir  string
+	from string
+}
+
+func (x *fileTransport) Send(msg *Message) error {
+
+	data, err := buildMail(x.from, msg)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(x.dir, v), 0o700); err != nil {
+			return err
+		}
+	}
+
+	id, err := utilcrypto.RandomCryptoBase32(10)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%d.%s.%s", time.Now().UnixNano(), id, msg.Kind)
+
+	tmp := filepath.Join(x.dir, "tmp", name)
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, filepath.Join(x.dir, "new", name)) // atomic for readers of new/
+}
+
+// buildMail RFC 5322 message, multipart/alternative if message has text and html
+func buildMail(from string, msg *Message) ([]byte, error) {
+
+	to := strings.Join(strings.Fields(msg.To), "") // tel of sms to file drop, no header injection
+	if addr, err := mail.ParseAddress(msg.To); err == nil {
+		to = addr.String()
+	}
+
+	var buf bytes.Buffer
+
+	header := textproto.MIMEHeader{}
+	if from != "" {
+		header.Set("From", from)
+	}
+	header.Set("To", to)
+	header.Set("Subject", mime.QEncoding.Encode("utf-8", strings.Join(strings.Fields(msg.Subject), " "))) // no header injection
+	header.Set("Date", time.Now().UTC().Format(time.RFC1123Z))
+	header.Set("MIME-Version", "1.0")
+
+	writeHeader := func(h textproto.MIMEHeader) {
+		for _, k := range slices.Sorted(maps.Keys(h)) {
+			fmt.Fprintf(&buf, "%s: %s\r\n", k, h.Get(k))
+		}
+		buf.WriteString("\r\n")
+	}
+
+	if msg.Text != "" && msg.HTML != "" {
+
+		mw := multipart.NewWriter(&buf)
+
+		header.Set("Content-Type", "multipart/alternative; boundary="+mw.Boundary())
+		writeHeader(header)
+
+		for _, part := range []struct{ contentType, body string }{
+			{"text/plain; charset=utf-8", msg.Text},
+			{"text/html; charset=utf-8", msg.HTML},
+		} {
+			pw, err := mw.CreatePart(textproto.MIMEHeader{
+				"Content-Type":              {part.contentType},
+				"Content-Transfer-Encoding": {"quoted-printable"},
+			})
+			if err != nil {
+				return nil, err
+			}
+			if err := writeQuotedPrintable(pw, part.body); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := mw.Close(); err != nil {
+			return nil, err
+		}
+
+		return buf.Bytes(), nil
+	}
+
+	body, contentType := msg.Text, "text/plain; charset=utf-8"
+	if body == "" {
+		body, contentType = msg.HTML, "text/html; charset=utf-8"
+	}
+
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+	writeHeader(header)
+
+	if err := writeQuotedPrintable(&buf, body); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeQuotedPrintable(w io.Writer, body string) error {
+
+	qw := quotedprintable.NewWriter(w)
+
+	if _, err := qw.Write([]byte(body)); err != nil {
+		return err
+	}
+
+	return qw.Close()
+}
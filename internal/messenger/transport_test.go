@@ -0,0 +1,471 @@
+package messenger
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"go-auth-admin/internal/config"
+	"go-auth-admin/pkg/msgsign"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testSMTPServer local SMTP stand-in, STARTTLS and AUTH PLAIN, keeps received mails
+type testSMTPServer struct {
+	listener  net.Listener
+	tlsConfig *tls.Config
+	rootCAs   *x509.CertPool
+
+	mu    sync.Mutex
+	auth  []string // user:password
+	rcpt  []string
+	mails []string
+}
+
+func newTestSMTPServer(t *testing.T) *testSMTPServer {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := &testSMTPServer{
+		listener:  listener,
+		tlsConfig: &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}},
+		rootCAs:   x509.NewCertPool(),
+	}
+	res.rootCAs.AddCert(cert)
+
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go res.serve(conn)
+		}
+	}()
+
+	return res
+}
+
+func (x *testSMTPServer) port() string {
+	return strings.TrimPrefix(x.listener.Addr().String(), "127.0.0.1:")
+}
+
+func (x *testSMTPServer) serve(conn net.Conn) {
+
+	defer conn.Close()
+
+	r, w := bufio.NewReader(conn), conn
+	reply := func(line string) { _, _ = w.Write([]byte(line + "\r\n")) }
+
+	isTLS := false
+
+	reply("220 localhost ESMTP")
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		switch cmd {
+		case "EHLO":
+			if isTLS {
+				reply("250-localhost")
+				reply("250 AUTH PLAIN")
+			} else {
+				reply("250-localhost")
+				reply("250 STARTTLS")
+			}
+		case "STARTTLS":
+			reply("220 ready")
+			tlsConn := tls.Server(conn, x.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn, r, w, isTLS = tlsConn, bufio.NewReader(tlsConn), tlsConn, true
+		case "AUTH":
+			raw, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(line, "AUTH PLAIN "))
+			parts := strings.Split(string(raw), "\x00")
+			x.mu.Lock()
+			x.auth = append(x.auth, strings.Join(parts[1:], ":"))
+			x.mu.Unlock()
+			reply("235 ok")
+		case "MAIL":
+			reply("250 ok")
+		case "RCPT":
+			x.mu.Lock()
+			x.rcpt = append(x.rcpt, line)
+			x.mu.Unlock()
+			reply("250 ok")
+		case "DATA":
+			reply("354 go")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			x.mu.Lock()
+			x.mails = append(x.mails, data.String())
+			x.mu.Unlock()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func testMessengerConfig() *config.AppConfig {
+	res := config.NewAppConfig()
+	res.ConfigPath = []string{}
+	return res
+}
+
+func TestSMTPTransport(t *testing.T) {
+
+	server := newTestSMTPServer(t)
+
+	cfg := testMessengerConfig()
+	cfg.Messenger.Routes = map[string]string{KindEmailPassword: TransportSMTP}
+	cfg.Messenger.SMTP = config.AppConfigSMTP{
+		Host:     "127.0.0.1",
+		Port:     server.port(),
+		User:     "mailer",
+		Password: "mailer-pass",
+		From:     "Auth <auth@example.com>",
+		StartTLS: true,
+		Timeout:  5,
+	}
+
+	msg := NewAppMessenger(cfg).(*defaultAppMessenger)
+	msg.transports[TransportSMTP].(*smtpTransport).tlsConfig.RootCAs = server.rootCAs
+
+	if err := msg.SendPasswordToEmail("secret-pass", "user1@example.com", "en"); err != nil {
+		t.Fatal(err)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	if len(server.auth) != 1 || server.auth[0] != "mailer:mailer-pass" {
+		t.Errorf("auth = %v, want mailer:mailer-pass", server.auth)
+	}
+	if len(server.rcpt) != 1 || !strings.Contains(server.rcpt[0], "<user1@example.com>") {
+		t.Errorf("rcpt = %v, want user1@example.com", server.rcpt)
+	}
+	if len(server.mails) != 1 {
+		t.Fatalf("mails = %v, want 1", len(server.mails))
+	}
+
+	m, err := mail.ReadMessage(strings.NewReader(server.mails[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Header.Get("Subject"); got != "Your new password" {
+		t.Errorf("subject = %q, want Your new password", got)
+	}
+	if got := m.Header.Get("Content-Type"); !strings.HasPrefix(got, "multipart/alternative") {
+		t.Errorf("content type = %q, want multipart/alternative", got)
+	}
+	if !strings.Contains(server.mails[0], "secret-pass") {
+		t.Errorf("mail has no password")
+	}
+}
+
+func TestSMTPTransportRequiresStartTLS(t *testing.T) {
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		_, _ = conn.Write([]byte("220 localhost ESMTP\r\n"))
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if strings.HasPrefix(strings.ToUpper(line), "EHLO") {
+				_, _ = conn.Write([]byte("250 localhost\r\n")) // no STARTTLS
+			} else {
+				_, _ = conn.Write([]byte("250 ok\r\n"))
+			}
+		}
+	}()
+
+	transport := &smtpTransport{
+		config: config.AppConfigSMTP{
+			Host:     "127.0.0.1",
+			Port:     strings.TrimPrefix(listener.Addr().String(), "127.0.0.1:"),
+			From:     "auth@example.com",
+			StartTLS: true,
+			Timeout:  5,
+		},
+		tlsConfig: &tls.Config{ServerName: "127.0.0.1"},
+	}
+
+	err = transport.Send(&Message{Kind: KindEmailPasscode, To: "user1@example.com", Text: "1234"})
+	if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Errorf("Send() error = %v, want STARTTLS error", err)
+	}
+}
+
+func TestSMTPTransportTimeout(t *testing.T) {
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	// accepts, never greets
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { _ = conn.Close() })
+		}
+	}()
+
+	transport := &smtpTransport{
+		config: config.AppConfigSMTP{
+			Host:    "127.0.0.1",
+			Port:    strings.TrimPrefix(listener.Addr().String(), "127.0.0.1:"),
+			From:    "auth@example.com",
+			Timeout: 1,
+		},
+		tlsConfig: &tls.Config{ServerName: "127.0.0.1"},
+	}
+
+	start := time.Now()
+
+	err = transport.Send(&Message{Kind: KindEmailPasscode, To: "user1@example.com", Text: "1234"})
+	if err == nil || !strings.Contains(err.Error(), "timeout") {
+		t.Errorf("Send() error = %v, want timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Send() took %v, want about timeout", elapsed)
+	}
+}
+
+func TestFileTransport(t *testing.T) {
+
+	dir := t.TempDir()
+
+	cfg := testMessengerConfig()
+	cfg.Messenger.Routes = map[string]string{routeAnyKind: TransportFile}
+	cfg.Messenger.File.Dir = dir
+
+	msg := NewAppMessenger(cfg)
+
+	if err := msg.Send(KindSmsPasscode, "+123456", "en", map[string]string{"passcode": "1234"}); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "new", "*"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("new files = %v, %v, want 1", files, err)
+	}
+
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "To: +123456") || !strings.Contains(string(data), "Your passcode is 1234.") {
+		t.Errorf("file = %q, want sms to tel", data)
+	}
+}
+
+func TestWebhookTransport(t *testing.T) {
+
+	var got Message
+	var auth string
+
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer hook.Close()
+
+	cfg := testMessengerConfig()
+	cfg.Messenger.Routes = map[string]string{KindEmailInvite: TransportWebhook}
+	cfg.Messenger.Webhook = config.AppConfigWebhook{URL: hook.URL, Headers: map[string]string{"Authorization": "Bearer hook-token"}}
+
+	msg := NewAppMessenger(cfg)
+
+	if err := msg.SendInviteToEmail("https://example.com/signup?token=t1", "user1@example.com", "en"); err != nil {
+		t.Fatal(err)
+	}
+
+	if auth != "Bearer hook-token" {
+		t.Errorf("authorization = %q, want Bearer hook-token", auth)
+	}
+	if got.Kind != KindEmailInvite || got.To != "user1@example.com" || got.Data["link"] != "https://example.com/signup?token=t1" || got.Subject == "" {
+		t.Errorf("webhook message = %+v, want invite", got)
+	}
+}
+
+func TestWebhookTransportSignature(t *testing.T) {
+
+	verifier := msgsign.NewVerifier(map[string][]byte{"k1": msgsign.DeriveKey([]byte("vault-auth-key"))})
+
+	var auth string
+
+	hook := httptest.NewServer(verifier.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+	})))
+	defer hook.Close()
+
+	cfg := testMessengerConfig()
+	cfg.Messenger.Routes = map[string]string{KindEmailInvite: TransportWebhook}
+	cfg.Messenger.Webhook = config.AppConfigWebhook{URL: hook.URL, Headers: map[string]string{
+		"Authorization": "Bearer hook-token",
+		"X-Signature":   "static", // configured header does not replace signature
+	}}
+
+	msg := NewAppMessenger(cfg)
+	msg.SetSigner(testSigner{})
+
+	if err := msg.SendInviteToEmail("https://example.com/signup?token=t1", "user1@example.com", "en"); err != nil {
+		t.Fatal(err)
+	}
+	if auth != "Bearer hook-token" {
+		t.Errorf("authorization = %q, want Bearer hook-token", auth)
+	}
+}
+
+func TestTransportRoutes(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		routes map[string]string
+		file   string
+		panic  bool
+	}{
+		{"default", map[string]string{}, "", false},
+		{"unknown transport", map[string]string{KindSmsInvite: "fax"}, "", true},
+		{"unknown kind", map[string]string{"sms-invites": TransportService}, "", true},
+		{"file without dir", map[string]string{routeAnyKind: TransportFile}, "", true},
+		{"file", map[string]string{routeAnyKind: TransportFile}, "maildir", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); (r != nil) != tt.panic {
+					t.Errorf("NewAppMessenger() panic = %v, want %v", r, tt.panic)
+				}
+			}()
+
+			cfg := testMessengerConfig()
+			cfg.Messenger.Routes = tt.routes
+			cfg.Messenger.File.Dir = tt.file
+
+			NewAppMessenger(cfg)
+		})
+	}
+}
+
+type testSigner struct{}
+
+func (testSigner) CurrentKey() (string, []byte, error) {
+	return "k1", []byte("vault-auth-key"), nil
+}
+
+func TestServiceTransportSigned(t *testing.T) {
+
+	verifier := msgsign.NewVerifier(map[string][]byte{"k1": msgsign.DeriveKey([]byte("vault-auth-key"))})
+
+	var got url.Values
+
+	service := httptest.NewServer(verifier.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		got = r.PostForm
+	})))
+	defer service.Close()
+
+	cfg := testMessengerConfig()
+	cfg.Messenger.ServiceURL = service.URL + "/sys/api/messenger/{code}"
+
+	msg := NewAppMessenger(cfg)
+
+	if err := msg.Send(KindSmsPasscode, "+123456", "en", map[string]string{"passcode": "1234"}); err == nil {
+		t.Errorf("unsigned Send() error = nil, want 401")
+	}
+
+	msg.SetSigner(testSigner{})
+
+	if err := msg.Send(KindSmsPasscode, "+123456", "en", map[string]string{"passcode": "1234"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Get("passcode") != "1234" || got.Get("to") != "+123456" {
+		t.Errorf("form = %v, want passcode to +123456", got)
+	}
+}
@@ -24,6 +24,8 @@ func Init(e *echo.Echo, appService service.AppService) {
 
 	e.HTTPErrorHandler = newHTTPErrorHandler(appService)
 
+	initRedirect(e, appService)
+
 	e.Use(middleware.Recover()) //!!!
 
 	if appConfig.HTTPServer.AccessLog {
@@ -38,8 +40,9 @@ func Init(e *echo.Echo, appService service.AppService) {
 	e.Use(xweb.UserLangMiddleware(appService))
 	e.Use(xweb.TokenParserMiddleware(appService))
 
-	//
-	// e.Use(xweb.CsrfMiddleware(appService))
+	initRateLimit(e, appService) // keyed by user of parsed token
+
+	e.Use(xweb.CsrfMiddleware(appService)) // cookie auth of /auth-admin/api
 
 	initSys(e, appService)
 }
@@ -92,8 +95,8 @@ func AssetsContentsMiddleware(e *echo.Echo, appService service.AppService,
 			}
 		},
 			xweb.AuthorizeMiddlewareWithConfig(xweb.AuthorizeMiddlewareConfig{
-				Service:      appService,
-				IfAnyOfRoles: authadmin.RolesForAssets,
+				Service:  appService,
+				IfPolicy: authadmin.PolicyForRoute(appService),
 			}),
 		)
 
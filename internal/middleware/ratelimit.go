@@ -0,0 +1,192 @@
+package middleware
+
+import (
+	"go-auth-admin/internal/config"
+	"go-auth-admin/internal/config/consts"
+	"go-auth-admin/internal/service"
+	"go-auth-admin/internal/store"
+	xlog "go-auth-admin/internal/util/utillog"
+	xweb "go-auth-admin/internal/web"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/time/rate"
+)
+
+const (
+	rateLimitDefault = "default"    // metric label of rate_limit
+	rateLimitKey     = "ratelimit:" // + limit name + identifier
+)
+
+// rateLimitRejected denied requests by limit, route key of rate_limit_routes or default
+var rateLimitRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "http_rate_limit_rejected_total",
+	Help: "Requests rejected by rate limit.",
+}, []string{"limit"})
+
+// rateLimitLocal requests limited by replica memory while shared store is unavailable
+var rateLimitLocal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "http_rate_limit_local_total",
+	Help: "Requests limited by local memory while rate limit store is unavailable.",
+}, []string{"limit"})
+
+// initRateLimit limits of rate_limit_routes and rate_limit on /auth-admin/api, after routing and token parser,
+// limiters are rebuilt on config reload
+func initRateLimit(e *echo.Echo, appService service.AppService) {
+
+	limits := newRateLimit(appService.Config().HTTPServer, appService.Store())
+
+	appService.ConfigSource().OnReload(limits.reload)
+
+	e.Use(limits.Middleware)
+}
+
+// rateLimit limiters of current config
+type rateLimit struct {
+	shared store.Store
+	chain  atomic.Pointer[echo.MiddlewareFunc]
+}
+
+func newRateLimit(cfg config.AppConfigHTTPServer, shared store.Store) *rateLimit {
+
+	res := &rateLimit{shared: shared}
+	res.set(cfg)
+
+	return res
+}
+
+func (x *rateLimit) set(cfg config.AppConfigHTTPServer) {
+
+	mws := rateLimitMiddlewares(cfg, x.shared)
+
+	var chain echo.MiddlewareFunc = func(next echo.HandlerFunc) echo.HandlerFunc {
+		for i := len(mws) - 1; i >= 0; i-- {
+			next = mws[i](next)
+		}
+		return next
+	}
+
+	x.chain.Store(&chain)
+}
+
+// reload swaps limiters with config, counters stay in shared store
+func (x *rateLimit) reload(next *config.AppConfig) (func(), error) {
+	return func() { x.set(next.HTTPServer) }, nil
+}
+
+// Middleware limiters of config loaded last
+func (x *rateLimit) Middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return (*x.chain.Load())(next)(c)
+	}
+}
+
+// rateLimitMiddlewares one limiter per route of rate_limit_routes, routes of rate_limit_routes are skipped by default limiter,
+// counters are kept in shared store
+func rateLimitMiddlewares(cfg config.AppConfigHTTPServer, shared store.Store) []echo.MiddlewareFunc {
+
+	res := []echo.MiddlewareFunc{}
+
+	for _, key := range slices.Sorted(maps.Keys(cfg.RateLimitRoutes)) {
+		res = append(res, rateLimiter(shared, key, cfg.RateLimitRoutes[key], func(c echo.Context) bool {
+			return rateLimitRouteKey(c) != key
+		}))
+	}
+
+	res = append(res, rateLimiter(shared, rateLimitDefault, config.AppConfigRateLimit{
+		Rate:  cfg.RateLimit,
+		Burst: cfg.RateBurst,
+	}, func(c echo.Context) bool {
+		_, ok := cfg.RateLimitRoutes[rateLimitRouteKey(c)]
+		return ok || !strings.HasPrefix(c.Request().URL.Path, consts.PathAuthAdminAPI+"/")
+	}))
+
+	return res
+}
+
+// rateLimitRouteKey "METHOD /path" of matched route, as key of rate_limit_routes
+func rateLimitRouteKey(c echo.Context) string {
+	return c.Request().Method + " " + c.Path()
+}
+
+// rateLimitIdentifier signed in user, ip otherwise
+func rateLimitIdentifier(c echo.Context) (string, error) {
+	if userID := xweb.UserID(c); userID != "" {
+		return "user:" + userID, nil
+	}
+	return "ip:" + c.RealIP(), nil
+}
+
+// rateLimiter 429 with Retry-After when limit of name is exceeded, 0 rate no limit
+func rateLimiter(shared store.Store, name string, limit config.AppConfigRateLimit, skipper middleware.Skipper) echo.MiddlewareFunc {
+
+	if limit.Rate == 0 {
+		return func(next echo.HandlerFunc) echo.HandlerFunc { return next }
+	}
+
+	// time of one token, client may retry after it
+	retryAfter := strconv.Itoa(int(math.Max(1, math.Ceil(1/limit.Rate))))
+
+	return middleware.RateLimiterWithConfig(middleware.RateLimiterConfig{
+		Skipper:             skipper,
+		IdentifierExtractor: rateLimitIdentifier,
+		Store:               newRateLimitStore(shared, name, limit),
+		DenyHandler: func(c echo.Context, _ string, _ error) error {
+			rateLimitRejected.WithLabelValues(name).Inc()
+			c.Response().Header().Set("Retry-After", retryAfter)
+			return echo.NewHTTPError(http.StatusTooManyRequests, "rate limit exceeded")
+		},
+	})
+}
+
+// rateLimitStore buckets of one limit in shared store, in replica memory while store is unavailable
+type rateLimitStore struct {
+	shared store.Store
+	local  middleware.RateLimiterStore
+	name   string
+	prefix string
+	rate   float64
+	burst  int
+}
+
+func newRateLimitStore(shared store.Store, name string, limit config.AppConfigRateLimit) middleware.RateLimiterStore {
+
+	burst := limit.Burst
+	if burst == 0 {
+		burst = int(math.Max(1, math.Ceil(limit.Rate))) // one second of requests
+	}
+
+	return &rateLimitStore{
+		shared: shared,
+		local: middleware.NewRateLimiterMemoryStoreWithConfig(middleware.RateLimiterMemoryStoreConfig{
+			Rate:  rate.Limit(limit.Rate),
+			Burst: burst,
+		}),
+		name:   name,
+		prefix: rateLimitKey + name + ":",
+		rate:   limit.Rate,
+		burst:  burst,
+	}
+}
+
+// Allow request is limited by replica memory while store is unavailable, limit is per replica then
+func (x *rateLimitStore) Allow(identifier string) (bool, error) {
+
+	ok, err := x.shared.Allow(x.prefix+identifier, x.rate, x.burst)
+	if err != nil {
+		xlog.Error("error on rate limit store: %v", err)
+		rateLimitLocal.WithLabelValues(x.name).Inc()
+		return x.local.Allow(identifier)
+	}
+
+	return ok, nil
+}
@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"go-auth-admin/internal/config"
+	"go-auth-admin/internal/store"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func Test_rateLimitMiddlewares(t *testing.T) {
+
+	e := echo.New()
+	e.Use(rateLimitMiddlewares(config.AppConfigHTTPServer{
+		RateLimit: 1,
+		RateBurst: 2,
+		RateLimitRoutes: map[string]config.AppConfigRateLimit{
+			"POST /auth-admin/api/accounts/:id/password": {Rate: 0.1, Burst: 1},
+		},
+	}, store.NewMemoryStore())...)
+
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+	e.GET("/auth-admin/api/accounts", ok)
+	e.POST("/auth-admin/api/accounts/:id/password", ok)
+	e.GET("/auth-admin/accounts", ok)
+
+	do := func(method string, target string, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set(echo.HeaderXRealIP, ip)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rejected := testutil.ToFloat64(rateLimitRejected.WithLabelValues(rateLimitDefault))
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		ip         string
+		want       int
+		retryAfter string
+	}{
+		{name: "burst 1", method: http.MethodGet, target: "/auth-admin/api/accounts", ip: "10.0.0.1", want: http.StatusOK},
+		{name: "burst 2", method: http.MethodGet, target: "/auth-admin/api/accounts", ip: "10.0.0.1", want: http.StatusOK},
+		{name: "exceeded", method: http.MethodGet, target: "/auth-admin/api/accounts", ip: "10.0.0.1", want: http.StatusTooManyRequests, retryAfter: "1"},
+		{name: "other client", method: http.MethodGet, target: "/auth-admin/api/accounts", ip: "10.0.0.2", want: http.StatusOK},
+		{name: "not api", method: http.MethodGet, target: "/auth-admin/accounts", ip: "10.0.0.1", want: http.StatusOK},
+		{name: "route own limit", method: http.MethodPost, target: "/auth-admin/api/accounts/1/password", ip: "10.0.0.1", want: http.StatusOK},
+		{name: "route exceeded", method: http.MethodPost, target: "/auth-admin/api/accounts/2/password", ip: "10.0.0.1", want: http.StatusTooManyRequests, retryAfter: "10"},
+	}
+
+	for _, tt := range tests {
+		rec := do(tt.method, tt.target, tt.ip)
+		if rec.Code != tt.want {
+			t.Errorf("%v: code = %v, want %v", tt.name, rec.Code, tt.want)
+		}
+		if got := rec.Header().Get("Retry-After"); got != tt.retryAfter {
+			t.Errorf("%v: Retry-After = %q, want %q", tt.name, got, tt.retryAfter)
+		}
+	}
+
+	if got := testutil.ToFloat64(rateLimitRejected.WithLabelValues(rateLimitDefault)) - rejected; got != 1 {
+		t.Errorf("rejected = %v, want 1", got)
+	}
+}
+
+func Test_rateLimitMiddlewaresShared(t *testing.T) {
+
+	mr := miniredis.RunT(t)
+
+	// replicas with own connections to same redis
+	replica := func() *echo.Echo {
+		shared, err := store.New(config.Database{Dialect: store.DialectRedis, Host: mr.Host(), Port: mr.Port()}, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = shared.Close() })
+
+		e := echo.New()
+		e.Use(rateLimitMiddlewares(config.AppConfigHTTPServer{RateLimit: 0.1, RateBurst: 2}, shared)...)
+		e.GET("/auth-admin/api/accounts", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+		return e
+	}
+
+	replicas := []*echo.Echo{replica(), replica()}
+
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		req := httptest.NewRequest(http.MethodGet, "/auth-admin/api/accounts", nil)
+		req.Header.Set(echo.HeaderXRealIP, "10.0.0.1")
+		rec := httptest.NewRecorder()
+
+		replicas[i%2].ServeHTTP(rec, req)
+
+		if rec.Code != want {
+			t.Errorf("request #%v code = %v, want %v", i, rec.Code, want)
+		}
+	}
+
+	mr.Close() // store unavailable, requests are limited by replica memory
+
+	local := testutil.ToFloat64(rateLimitLocal.WithLabelValues(rateLimitDefault))
+
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		req := httptest.NewRequest(http.MethodGet, "/auth-admin/api/accounts", nil)
+		req.Header.Set(echo.HeaderXRealIP, "10.0.0.1")
+		rec := httptest.NewRecorder()
+
+		replicas[0].ServeHTTP(rec, req)
+
+		if rec.Code != want {
+			t.Errorf("request #%v without store code = %v, want %v", i, rec.Code, want)
+		}
+	}
+
+	if got := testutil.ToFloat64(rateLimitLocal.WithLabelValues(rateLimitDefault)) - local; got != 3 {
+		t.Errorf("local limited = %v, want 3", got)
+	}
+}
+
+func Test_rateLimitReload(t *testing.T) {
+
+	limits := newRateLimit(config.AppConfigHTTPServer{}, store.NewMemoryStore())
+
+	e := echo.New()
+	e.Use(limits.Middleware)
+	e.GET("/auth-admin/api/accounts", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+
+	do := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/auth-admin/api/accounts", nil)
+		req.Header.Set(echo.HeaderXRealIP, "10.0.0.2")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	reload := func(cfg config.AppConfigHTTPServer) {
+		next := config.NewAppConfig()
+		next.HTTPServer = cfg
+		commit, err := limits.reload(next)
+		if err != nil {
+			t.Fatal(err)
+		}
+		commit()
+	}
+
+	for i := range 3 {
+		if got := do(); got != http.StatusOK {
+			t.Errorf("request #%v without limit code = %v, want %v", i, got, http.StatusOK)
+		}
+	}
+
+	reload(config.AppConfigHTTPServer{RateLimit: 0.1, RateBurst: 1})
+
+	for i, want := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		if got := do(); got != want {
+			t.Errorf("request #%v after reload code = %v, want %v", i, got, want)
+		}
+	}
+
+	reload(config.AppConfigHTTPServer{})
+
+	if got := do(); got != http.StatusOK {
+		t.Errorf("code after limit removed = %v, want %v", got, http.StatusOK)
+	}
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"go-auth-admin/internal/service"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+const (
+	pathACMEChallenge = "/.well-known/acme-challenge/"
+	pathSys           = "/sys/"
+)
+
+// initRedirect ACME http challenge, https and www redirects, before routing
+func initRedirect(e *echo.Echo, appService service.AppService) {
+
+	appConfig := appService.Config()
+
+	if appConfig.HTTPServer.AutoTLS {
+		// http-01 on plain listener, tls-alpn-01 works on TLS listener itself
+		challenge := e.AutoTLSManager.HTTPHandler(nil)
+
+		e.Pre(func(next echo.HandlerFunc) echo.HandlerFunc {
+			return func(c echo.Context) error {
+				if strings.HasPrefix(c.Request().URL.Path, pathACMEChallenge) {
+					challenge.ServeHTTP(c.Response(), c.Request())
+					return nil
+				}
+				return next(c)
+			}
+		})
+	}
+
+	if appConfig.HTTPServer.RedirectHTTPS {
+		e.Pre(httpsRedirect(appConfig.HTTPServer.ListenTLS))
+	}
+
+	if appConfig.HTTPServer.RedirectWWW {
+		e.Pre(middleware.WWWRedirectWithConfig(middleware.RedirectConfig{
+			Skipper: func(c echo.Context) bool {
+				return skipRedirect(c) || isHostIP(c.Request().Host) // no www. for ip, localhost
+			},
+			Code: http.StatusMovedPermanently,
+		}))
+	}
+}
+
+// skipRedirect sys api is called by scrapers and scripts, ACME challenge must be served as is
+func skipRedirect(c echo.Context) bool {
+	path := c.Request().URL.Path
+	return strings.HasPrefix(path, pathSys) || strings.HasPrefix(path, pathACMEChallenge)
+}
+
+func isHostIP(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	return host == "localhost" || net.ParseIP(host) != nil
+}
+
+// httpsRedirect moves plain request to port of listenTLS,
+// request of TLS proxy with X-Forwarded-Proto https is not moved
+func httpsRedirect(listenTLS string) echo.MiddlewareFunc {
+
+	_, port, _ := net.SplitHostPort(listenTLS)
+	if port == "443" || port == "https" {
+		port = "" // default
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+
+			if c.Scheme() == "https" || skipRedirect(c) {
+				return next(c)
+			}
+
+			req := c.Request()
+
+			host := req.Host
+			if h, _, err := net.SplitHostPort(host); err == nil {
+				host = h
+			}
+			host = strings.Trim(host, "[]")
+
+			switch {
+			case port != "":
+				host = net.JoinHostPort(host, port)
+			case strings.Contains(host, ":"):
+				host = "[" + host + "]" // ipv6
+			}
+
+			return c.Redirect(http.StatusMovedPermanently, "https://"+host+req.RequestURI)
+		}
+	}
+}
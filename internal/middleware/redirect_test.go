@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func Test_httpsRedirect(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		listenTLS string
+		host      string
+		target    string
+		header    string
+		want      string
+	}{
+		{name: "port", listenTLS: ":10283", host: "example.com:10280", target: "/a?b=1", want: "https://example.com:10283/a?b=1"},
+		{name: "default port", listenTLS: ":443", host: "example.com", target: "/a", want: "https://example.com/a"},
+		{name: "ipv6", listenTLS: ":443", host: "[::1]:80", target: "/", want: "https://[::1]/"},
+		{name: "tls proxy", listenTLS: ":443", host: "example.com", target: "/a", header: "https", want: ""},
+		{name: "sys api", listenTLS: ":443", host: "example.com", target: "/sys/api/metrics", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			e := echo.New()
+			e.Pre(httpsRedirect(tt.listenTLS))
+			e.Any("/*", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			req.Host = tt.host
+			if tt.header != "" {
+				req.Header.Set(echo.HeaderXForwardedProto, tt.header)
+			}
+			rec := httptest.NewRecorder()
+
+			e.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get(echo.HeaderLocation); got != tt.want {
+				t.Errorf("location = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_isHostIP(t *testing.T) {
+
+	for host, want := range map[string]bool{
+		"example.com":     false,
+		"example.com:80":  false,
+		"localhost:10280": true,
+		"127.0.0.1":       true,
+		"[::1]:443":       true,
+		"www.example.com": false,
+		"10.0.0.1:10280":  true,
+	} {
+		if got := isHostIP(host); got != want {
+			t.Errorf("isHostIP(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package policy
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-auth-admin/internal/util/utilaccess"
+	"go-auth-admin/internal/util/utilconfig"
+	xlog "go-auth-admin/internal/util/utillog"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// FileName policy file in config dirs, next to config.<env>.json
+const FileName = "policy.json"
+
+//go:embed policy.json
+var defaultPolicy []byte
+
+// Rule roles required to reach route.
+// Admin passes all_of and any_of, but not deny.
+type Rule struct {
+	AllOf  []string `json:"all_of,omitempty"` // must have each
+	AnyOf  []string `json:"any_of,omitempty"` // must have one
+	Deny   []string `json:"deny,omitempty"`   // refused if has one, admin too
+	Public bool     `json:"public,omitempty"` // no role check
+}
+
+// Allow checks rule against user roles
+func (x *Rule) Allow(userRoles []string) bool {
+
+	if x == nil {
+		return false
+	}
+
+	for _, r := range x.Deny {
+		if slices.Contains(userRoles, r) {
+			return false
+		}
+	}
+
+	if x.Public {
+		return true
+	}
+
+	if len(x.AllOf) == 0 && len(x.AnyOf) == 0 {
+		return false
+	}
+
+	if len(x.AllOf) > 0 && !utilaccess.HasAllRoles(userRoles, x.AllOf...) {
+		return false
+	}
+
+	if len(x.AnyOf) > 0 && !utilaccess.HasAnyOfRoles(userRoles, x.AnyOf...) {
+		return false
+	}
+
+	return true
+}
+
+// String describes who can reach route, e.g. "admin | (auth_edit & auth_view) ; deny: auth_locked"
+func (x *Rule) String() string {
+
+	if x == nil {
+		return "none"
+	}
+
+	res := ""
+
+	switch {
+	case x.Public:
+		res = "public"
+	case len(x.AllOf) > 0 && len(x.AnyOf) > 0:
+		res = fmt.Sprintf("%v | (%v & (%v))", utilaccess.RoleAdmin, strings.Join(x.AllOf, " & "), strings.Join(x.AnyOf, " | "))
+	case len(x.AllOf) > 0:
+		res = fmt.Sprintf("%v | (%v)", utilaccess.RoleAdmin, strings.Join(x.AllOf, " & "))
+	case len(x.AnyOf) > 0:
+		res = fmt.Sprintf("%v | %v", utilaccess.RoleAdmin, strings.Join(x.AnyOf, " | "))
+	default:
+		res = "none"
+	}
+
+	if len(x.Deny) > 0 {
+		res += " ; deny: " + strings.Join(x.Deny, " ")
+	}
+
+	return res
+}
+
+func (x *Rule) validate() error {
+
+	if x == nil {
+		return fmt.Errorf("error rule is empty")
+	}
+
+	if x.Public && (len(x.AllOf) > 0 || len(x.AnyOf) > 0) {
+		return fmt.Errorf("error public rule with roles")
+	}
+
+	if !x.Public && len(x.AllOf) == 0 && len(x.AnyOf) == 0 {
+		return fmt.Errorf("error rule has no all_of or any_of")
+	}
+
+	for _, r := range slices.Concat(x.AllOf, x.AnyOf, x.Deny) {
+		if !utilaccess.IsRoleCode(r) {
+			return fmt.Errorf("error role code is invalid: %v", r)
+		}
+	}
+
+	return nil
+}
+
+// Policy route-to-roles map
+type Policy struct {
+	Routes map[string]*Rule `json:"routes"` // "METHOD /path" as registered in router
+}
+
+// Key route key "METHOD /path"
+func Key(method string, path string) string {
+	return method + " " + path
+}
+
+// Rule returns rule for route, nil if not found
+func (x *Policy) Rule(method string, path string) *Rule {
+	return x.Routes[Key(method, path)]
+}
+
+func (x *Policy) validate() error {
+
+	errs := []error{}
+
+	for _, k := range slices.Sorted(maps.Keys(x.Routes)) {
+		if err := x.Routes[k].validate(); err != nil {
+			errs = append(errs, fmt.Errorf("%v: %w", k, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Check every route under prefix has rule, reports all missing
+func (x *Policy) Check(routes []*echo.Route, prefix string) error {
+
+	errs := []error{}
+	known := map[string]bool{}
+
+	for _, r := range Routes(routes, prefix) {
+		k := Key(r.Method, r.Path)
+		known[k] = true
+		if x.Routes[k] == nil {
+			errs = append(errs, fmt.Errorf("error route has no policy: %v", k))
+		}
+	}
+
+	for k := range x.Routes {
+		if !known[k] {
+			xlog.Warn("policy for unknown route: %v", k)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Routes under prefix sorted by path and method, skips not-found catch-all routes
+func Routes(routes []*echo.Route, prefix string) []*echo.Route {
+
+	res := slices.DeleteFunc(slices.Clone(routes), func(r *echo.Route) bool {
+		return r.Method == echo.RouteNotFound ||
+			(r.Path != prefix && !strings.HasPrefix(r.Path, prefix+"/"))
+	})
+
+	slices.SortFunc(res, func(a, b *echo.Route) int {
+		return strings.Compare(a.Path+" "+a.Method, b.Path+" "+b.Method)
+	})
+
+	return slices.CompactFunc(res, func(a, b *echo.Route) bool {
+		return a.Path == b.Path && a.Method == b.Method
+	})
+}
+
+// Load embedded default policy, then policy.json of each config dir overrides routes
+func Load(configPath []string) (*Policy, error) {
+
+	res := &Policy{}
+
+	if err := json.Unmarshal(defaultPolicy, res); err != nil {
+		return nil, err
+	}
+
+	for _, dir := range configPath {
+
+		if !utilconfig.HasFile(dir, FileName) {
+			continue
+		}
+
+		if err := utilconfig.LoadConfig(res /*pointer*/, dir, FileName); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := res.validate(); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func MustLoad(configPath []string) *Policy {
+
+	res, err := Load(configPath)
+	if err != nil {
+		panic(err)
+	}
+
+	return res
+}
@@ -0,0 +1,111 @@
+package policy
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestRuleAllow(t *testing.T) {
+	tests := []struct {
+		name      string
+		rule      *Rule
+		userRoles []string
+		want      bool
+	}{
+		{"Nil rule", nil, []string{"admin"}, false},
+		{"Empty rule", &Rule{}, []string{"admin"}, false},
+		{"Public", &Rule{Public: true}, nil, true},
+		{"Any of", &Rule{AnyOf: []string{"a", "b"}}, []string{"b"}, true},
+		{"Any of no match", &Rule{AnyOf: []string{"a", "b"}}, []string{"c"}, false},
+		{"All of", &Rule{AllOf: []string{"a", "b"}}, []string{"a", "b"}, true},
+		{"All of partial", &Rule{AllOf: []string{"a", "b"}}, []string{"a"}, false},
+		{"All of and any of", &Rule{AllOf: []string{"a"}, AnyOf: []string{"b", "c"}}, []string{"a", "c"}, true},
+		{"Deny", &Rule{AnyOf: []string{"a"}, Deny: []string{"d"}}, []string{"a", "d"}, false},
+		{"Admin", &Rule{AllOf: []string{"a", "b"}}, []string{"admin"}, true},
+		{"Admin deny", &Rule{AnyOf: []string{"a"}, Deny: []string{"admin"}}, []string{"admin"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.Allow(tt.userRoles); got != tt.want {
+				t.Errorf("Allow() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+
+	res, err := Load(nil)
+	if err != nil {
+		t.Fatalf("default policy: %v", err)
+	}
+
+	if res.Rule(http.MethodGet, "/auth-admin/api/accounts") == nil {
+		t.Fatalf("default policy has no accounts route")
+	}
+
+	dir := t.TempDir()
+	data := `{"routes":{"DELETE /auth-admin/api/accounts/:id":{"all_of":["auth_edit","auth_delete"]}}}`
+	if err := os.WriteFile(filepath.Join(dir, FileName), []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err = Load([]string{dir, t.TempDir() /*no file*/})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rule := res.Rule(http.MethodDelete, "/auth-admin/api/accounts/:id")
+	if rule == nil || len(rule.AllOf) != 2 || len(rule.AnyOf) != 0 {
+		t.Errorf("override not applied: %v", rule)
+	}
+
+	if res.Rule(http.MethodGet, "/auth-admin/api/accounts") == nil {
+		t.Errorf("default routes lost on override")
+	}
+
+	data = `{"routes":{"GET /auth-admin/api/x":{"deny":["auth_view"]}}}`
+	if err := os.WriteFile(filepath.Join(dir, FileName), []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = Load([]string{dir}); err == nil {
+		t.Errorf("rule without all_of or any_of accepted")
+	}
+}
+
+func TestCheck(t *testing.T) {
+
+	e := echo.New()
+	g := e.Group("/auth-admin")
+	g.Use(func(next echo.HandlerFunc) echo.HandlerFunc { return next })
+	g.GET("/a", func(c echo.Context) error { return nil })
+	g.GET("/b", func(c echo.Context) error { return nil })
+	g.POST("/b", func(c echo.Context) error { return nil })
+	e.GET("/-/health", func(c echo.Context) error { return nil })
+
+	p := &Policy{Routes: map[string]*Rule{
+		"GET /auth-admin/a": {AnyOf: []string{"x"}},
+	}}
+
+	err := p.Check(e.Routes(), "/auth-admin")
+	if err == nil {
+		t.Fatalf("missing routes not reported")
+	}
+
+	msg := err.Error()
+	for _, k := range []string{"GET /auth-admin/b", "POST /auth-admin/b"} {
+		if !strings.Contains(msg, k) {
+			t.Errorf("missing route %v not reported: %v", k, msg)
+		}
+	}
+	if strings.Contains(msg, "health") || strings.Contains(msg, "GET /auth-admin/a") {
+		t.Errorf("unexpected route reported: %v", msg)
+	}
+}
@@ -227,24 +227,10 @@ func (rep *repository) AutoMigrate(value interface{}) error {
 // Transaction start a transaction as a block.
 // If it is failed, will rollback and return error.
 // If it is sccuessed, will commit.
-// ref: https://github.com/jinzhu/gorm/blob/master/main.go#L533
+// Nested call on tx runs in savepoint.
 func (rep *repository) Transaction(fc func(tx AppRepository) error) (err error) {
-	panicked := true
-	tx := rep.db.Begin()
-	defer func() {
-		if panicked || err != nil {
-			tx.Rollback()
-		}
-	}()
-
-	txrep := &repository{}
-	txrep.db = tx
-	err = fc(txrep)
-
-	if err == nil {
-		err = tx.Commit().Error
-	}
 
-	panicked = false
-	return
+	return rep.db.Transaction(func(tx *gorm.DB) error {
+		return fc(&repository{db: tx})
+	})
 }
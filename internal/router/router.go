@@ -17,13 +17,15 @@ import (
 	"go-auth-admin/internal/service"
 	webfs "go-auth-admin/web"
 
+	"go-auth-admin/internal/util/utilhttp"
 	xlog "go-auth-admin/internal/util/utillog"
 	xweb "go-auth-admin/internal/web"
 
 	"github.com/labstack/echo/v4/middleware"
 )
 
-func Init(e *echo.Echo, appService service.AppService) {
+// Init adds routes, returns sys api server if it has own listener, caller starts and stops it
+func Init(e *echo.Echo, appService service.AppService) (sys *echo.Echo) {
 
 	e.Renderer = mustNewRenderer()
 
@@ -32,10 +34,17 @@ func Init(e *echo.Echo, appService service.AppService) {
 	initAuthAdminController(e, appService)
 	initDebugController(e, appService)
 
-	initSys(e, appService)
+	return initSys(e, appService)
 }
 
-func initSys(e *echo.Echo, appService service.AppService) {
+// InitPolicyRoutes adds admin routes of Init without service, for policy check without db
+func InitPolicyRoutes(e *echo.Echo) {
+
+	initAuthAdminController(e, nil)
+	initDebugController(e, nil)
+}
+
+func initSys(e *echo.Echo, appService service.AppService) *echo.Echo {
 
 	// !!! DANGER for private(non-public) services only
 	// or use non-public port via echo.New()
@@ -45,23 +54,26 @@ func initSys(e *echo.Echo, appService service.AppService) {
 	listen := appConfig.HTTPServer.Listen
 	listenSys := appConfig.HTTPServer.ListenSys
 	sysMetrics := appConfig.HTTPServer.SysMetrics
-	hasAnyService := sysMetrics
+	sysReload := appConfig.HTTPServer.SysReload
+	sysInvites := appConfig.HTTPServer.SysInvites
+	sysNotify := appConfig.HTTPServer.SysNotify
+	hasAnyService := sysMetrics || sysReload || sysInvites || sysNotify
 	sysAPIKey := appConfig.HTTPServer.SysAPIKey
 	hasAPIKey := sysAPIKey != ""
 	hasListenSys := listenSys != ""
 	startNewListener := listenSys != listen
 
 	if !hasListenSys {
-		return
+		return nil
 	}
 
 	if !hasAnyService {
-		return
+		return nil
 	}
 
 	if !hasAPIKey {
 		xlog.Panic("sys api key is empty")
-		return
+		return nil
 	}
 
 	if startNewListener {
@@ -91,25 +103,48 @@ func initSys(e *echo.Echo, appService service.AppService) {
 
 	}
 
-	if startNewListener {
-
-		// start as async task
-		go func() {
-			xlog.Info("sys api serve on: %v main: %v", listenSys, listen)
-
-			if err := e.Start(listenSys); err != nil {
-				if err != http.ErrServerClosed {
+	if sysReload {
+		e.POST(
+			consts.PathSysConfigReloadAPI,
+			func(c echo.Context) error {
+				if err := appService.ConfigSource().Reload(); err != nil {
 					xlog.Error("%v", err)
-				} else {
-					xlog.Info("shutting down the server")
+					return c.JSON(http.StatusUnprocessableEntity, map[string]string{"status": "error", "message": err.Error()})
 				}
-			}
-		}()
+				return c.JSON(http.StatusOK, map[string]string{"status": consts.StatusSuccess})
+			},
+			sysAPIAccessAuthMW,
+		) // current config stays on error
+	}
 
-	} else {
-		xlog.Info("sys api server serve on main listener: %v", listen)
+	if sysInvites {
+		e.POST(
+			consts.PathSysInvitesRedeemAPI,
+			func(c echo.Context) error {
+				return authadmin.NewInvitesRedeemAPIController(appService, c).Handler()
+			},
+			sysAPIAccessAuthMW,
+		) // signup with invite token gets invite roles
+	}
+
+	if sysNotify {
+		e.POST(
+			consts.PathSysNotifyAPI,
+			func(c echo.Context) error {
+				return authadmin.NewNotifyAPIController(appService, c).Handler()
+			},
+			sysAPIAccessAuthMW,
+		) // security events of auth app, e.g. mfa enrollment
 	}
 
+	if startNewListener {
+		xlog.Info("sys api serve on: %v main: %v", listenSys, listen)
+		return e
+	}
+
+	xlog.Info("sys api server serve on main listener: %v", listen)
+
+	return nil
 }
 
 type tmplRenderer struct {
@@ -146,17 +181,32 @@ func mustNewRenderer() echo.Renderer {
 
 }
 
-func initCORSConfig(e *echo.Echo, _ service.AppService) {
-
-	// CorsEnabled := true
-	// if CorsEnabled {
-	// 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-	// 		AllowCredentials:                         true,
-	// 		UnsafeWildcardOriginWithAllowCredentials: true,
-	// 		AllowOrigins:                             []string{"*"},
-	// 		MaxAge:                                   86400,
-	// 	}))
-	// }
+func initCORSConfig(e *echo.Echo, appService service.AppService) {
+
+	cfg := appService.Config().HTTPServer.CORS
+
+	if len(cfg.AllowOrigins) == 0 {
+		return
+	}
+
+	allowOrigin, err := utilhttp.OriginMatcher(cfg.AllowOrigins)
+	if err != nil {
+		xlog.Panic("cors allow origins: %v", err)
+		return
+	}
+
+	// pre, preflight and rejected (429, 403) responses of api get headers too
+	e.Pre(middleware.CORSWithConfig(middleware.CORSConfig{
+		Skipper: func(c echo.Context) bool {
+			return !strings.HasPrefix(c.Request().URL.Path, consts.PathAuthAdminAPI+"/")
+		},
+		AllowOriginFunc:  allowOrigin,
+		AllowMethods:     cfg.AllowMethods,
+		AllowHeaders:     cfg.AllowHeaders,
+		ExposeHeaders:    cfg.ExposeHeaders,
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           cfg.MaxAge,
+	}))
 }
 
 func initDebugController(e *echo.Echo, _ service.AppService) {
@@ -194,8 +244,8 @@ func initAuthAdminController(e *echo.Echo, appService service.AppService) {
 			{
 				// auth
 				group.Use(xweb.AuthorizeMiddlewareWithConfig(xweb.AuthorizeMiddlewareConfig{
-					Service:      appService,
-					IfAnyOfRoles: authadmin.RolesForAPI,
+					Service:  appService,
+					IfPolicy: authadmin.PolicyForRoute(appService),
 				}))
 			}
 
@@ -229,6 +279,7 @@ func initAuthAdminController(e *echo.Echo, appService service.AppService) {
 					}
 
 					group.GET(path(consts.PathAuthAdminAccountsAPI), handler)
+					group.GET(path(consts.PathAuthAdminRolesEntityAccountsAPI), handler) // filter by role
 
 				}
 				{
@@ -254,6 +305,191 @@ func initAuthAdminController(e *echo.Echo, appService service.AppService) {
 					group.POST(path(consts.PathAuthAdminAccountsEntityPasswordAPI), handler)
 
 				}
+				{
+
+					handler := func(c echo.Context) error {
+						ctrl := authadmin.NewAccountsPasswordGenerateAPIController(appService, c)
+						return ctrl.Handler()
+					}
+
+					group.POST(path(consts.PathAuthAdminAccountsEntityPasswordGenerateAPI), handler)
+
+				}
+				{
+
+					handler := func(c echo.Context) error {
+						ctrl := authadmin.NewAccountsVerifyAPIController(appService, c)
+						return ctrl.Handler()
+					}
+
+					group.POST(path(consts.PathAuthAdminAccountsEntityVerifyAPI), handler)
+					group.DELETE(path(consts.PathAuthAdminAccountsEntityVerifyAPI), handler)
+					group.POST(path(consts.PathAuthAdminAccountsEntityVerifyPasscodeAPI), handler)
+
+				}
+				{
+
+					handler := func(c echo.Context) error {
+						ctrl := authadmin.NewAccountsImpersonateAPIController(appService, c)
+						return ctrl.Handler()
+					}
+
+					group.POST(path(consts.PathAuthAdminAccountsEntityImpersonateAPI), handler)
+					group.DELETE(path(consts.PathAuthAdminImpersonateAPI), handler)
+
+				}
+
+			}
+
+			{
+				{
+					handler := func(c echo.Context) error {
+						ctrl := authadmin.NewInvitesAPIController(appService, c)
+						return ctrl.Handler()
+					}
+
+					group.GET(path(consts.PathAuthAdminInvitesAPI), handler)
+
+				}
+				{
+					handler := func(c echo.Context) error {
+						ctrl := authadmin.NewInvitesEntityAPIController(appService, c)
+						return ctrl.Handler()
+					}
+
+					group.GET(path(consts.PathAuthAdminInvitesEntityAPI), handler)
+					group.POST(path(consts.PathAuthAdminInvitesAPI), handler) // no :id
+					group.POST(path(consts.PathAuthAdminInvitesEntityResendAPI), handler)
+					group.DELETE(path(consts.PathAuthAdminInvitesEntityAPI), handler)
+
+				}
+
+			}
+
+			{
+				{
+					handler := func(c echo.Context) error {
+						ctrl := authadmin.NewRolesAPIController(appService, c)
+						return ctrl.Handler()
+					}
+
+					group.GET(path(consts.PathAuthAdminRolesAPI), handler)
+
+				}
+				{
+					handler := func(c echo.Context) error {
+						ctrl := authadmin.NewRolesEntityAPIController(appService, c)
+						return ctrl.Handler()
+					}
+
+					group.GET(path(consts.PathAuthAdminRolesEntityAPI), handler)
+					group.POST(path(consts.PathAuthAdminRolesAPI), handler) // no :code
+					group.PUT(path(consts.PathAuthAdminRolesEntityAPI), handler)
+					group.DELETE(path(consts.PathAuthAdminRolesEntityAPI), handler)
+
+				}
+
+			}
+
+			{
+				{
+					handler := func(c echo.Context) error {
+						ctrl := authadmin.NewGroupsAPIController(appService, c)
+						return ctrl.Handler()
+					}
+
+					group.GET(path(consts.PathAuthAdminGroupsAPI), handler)
+
+				}
+				{
+					handler := func(c echo.Context) error {
+						ctrl := authadmin.NewGroupsEntityAPIController(appService, c)
+						return ctrl.Handler()
+					}
+
+					group.GET(path(consts.PathAuthAdminGroupsEntityAPI), handler)
+					group.POST(path(consts.PathAuthAdminGroupsAPI), handler) // no :id
+					group.PUT(path(consts.PathAuthAdminGroupsEntityAPI), handler)
+					group.DELETE(path(consts.PathAuthAdminGroupsEntityAPI), handler)
+
+				}
+				{
+					handler := func(c echo.Context) error {
+						ctrl := authadmin.NewAccountsAPIController(appService, c)
+						return ctrl.Handler()
+					}
+
+					group.GET(path(consts.PathAuthAdminGroupsEntityMembersAPI), handler) // filter by group
+
+				}
+				{
+					handler := func(c echo.Context) error {
+						ctrl := authadmin.NewGroupsMembersAPIController(appService, c)
+						return ctrl.Handler()
+					}
+
+					group.POST(path(consts.PathAuthAdminGroupsEntityMembersAPI), handler)
+					group.DELETE(path(consts.PathAuthAdminGroupsEntityMembersEntityAPI), handler)
+
+				}
+
+			}
+
+			{
+				{
+					handler := func(c echo.Context) error {
+						ctrl := authadmin.NewApprovalsAPIController(appService, c)
+						return ctrl.Handler()
+					}
+
+					group.GET(path(consts.PathAuthAdminApprovalsAPI), handler)
+
+				}
+				{
+					handler := func(c echo.Context) error {
+						ctrl := authadmin.NewApprovalsEntityAPIController(appService, c)
+						return ctrl.Handler()
+					}
+
+					group.GET(path(consts.PathAuthAdminApprovalsEntityAPI), handler)
+					group.POST(path(consts.PathAuthAdminApprovalsEntityApproveAPI), handler)
+					group.POST(path(consts.PathAuthAdminApprovalsEntityRejectAPI), handler)
+					group.POST(path(consts.PathAuthAdminApprovalsExpireAPI), handler) // no :id
+
+				}
+
+			}
+
+			{
+				handler := func(c echo.Context) error {
+					ctrl := authadmin.NewMessagesPreviewAPIController(appService, c)
+					return ctrl.Handler()
+				}
+
+				group.POST(path(consts.PathAuthAdminMessagesPreviewAPI), handler)
+
+			}
+
+			{
+				{
+					handler := func(c echo.Context) error {
+						ctrl := authadmin.NewOutboxAPIController(appService, c)
+						return ctrl.Handler()
+					}
+
+					group.GET(path(consts.PathAuthAdminOutboxAPI), handler)
+
+				}
+				{
+					handler := func(c echo.Context) error {
+						ctrl := authadmin.NewOutboxEntityAPIController(appService, c)
+						return ctrl.Handler()
+					}
+
+					group.GET(path(consts.PathAuthAdminOutboxEntityAPI), handler)
+					group.POST(path(consts.PathAuthAdminOutboxEntityRetryAPI), handler)
+
+				}
 
 			}
 		}
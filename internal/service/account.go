@@ -4,6 +4,8 @@ import (
 	"go-auth-admin/internal/util/utilaccess"
 	"go-auth-admin/internal/util/utilcrypto"
 	utilstring "go-auth-admin/internal/util/utilstring"
+	"slices"
+	"strings"
 
 	"time"
 
@@ -22,6 +24,17 @@ const (
 const (
 	SecurityStampLenDefault = 16
 )
+const (
+	ContactEmail = "email"
+	ContactTel   = "tel"
+)
+
+// field names of UserAccount for field permissions, json names
+const (
+	FieldEmail = "email"
+	FieldTel   = "tel"
+	FieldRoles = "roles"
+)
 
 // UserAccount Username,Email,NormalizedEmail are uniqueIndex with condition "not empty"
 type UserAccount struct {
@@ -29,18 +42,48 @@ type UserAccount struct {
 	Username string `json:"username,omitempty" gorm:"size:255;uniqueIndex:,where:username != ''"`
 	Tel      string `json:"tel,omitempty" gorm:"size:255;uniqueIndex:,where:tel != ''"`
 	// Email           string `json:"email,omitempty" gorm:"size:255"`
-	Email string `json:"-" gorm:"size:255;uniqueIndex:,where:email != ''"` // use this on search
+	Email string `json:"email,omitempty" gorm:"size:255;uniqueIndex:,where:email != ''"` // use this on search, masked by field permissions
 	// use this on emailing and show
 	// NormalizedEmail string `json:"-" gorm:"size:255;uniqueIndex:,where:normalized_email != ''"` // use this on search
 	// SecurityStamp   string // Key := Base32(Random(32))  HMACSHA1(Key)  Key == VTOQQ2PQKD7A2KTSXU7OFLKUNI7QEZRJ
 	PasswordHash string    `json:"-" gorm:"size:255"`
 	CreatedAt    time.Time `json:"-"`
-	UpdatedAt    time.Time `json:"-"` // auto-updated
-	Roles        string    `json:"roles,omitempty" gorm:"size:255"`
+	UpdatedAt    time.Time `json:"-"`                        // auto-updated
+	Roles        string    `json:"roles,omitempty" gorm:"-"` // space-separated, stored in account_roles
+	// EffectiveRoles direct and group roles, read-only
+	EffectiveRoles string `json:"effective_roles,omitempty" gorm:"-"`
+
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"` // nil if not verified
+	TelVerifiedAt   *time.Time `json:"tel_verified_at,omitempty"`   // nil if not verified
+}
+
+func (x *UserAccount) RoleCodes() []string {
+	return strings.Fields(x.Roles)
+}
+
+// EffectiveRoleCodes union of direct and group roles
+func (x *UserAccount) EffectiveRoleCodes() []string {
+	codes := strings.Fields(x.Roles + " " + x.EffectiveRoles)
+	return slices.Compact(slices.Sorted(slices.Values(codes)))
 }
 
 func (x *UserAccount) HasAnyOfRoles(roles ...string) bool {
-	return utilaccess.HasAnyOfRoles(x.Roles, roles...)
+	return utilaccess.HasAnyOfRoles(x.EffectiveRoleCodes(), roles...)
+}
+
+// MaskFields masks fields caller may not view
+func (x *UserAccount) MaskFields(permissions *utilaccess.PermissionsDTO) {
+
+	if !permissions.CanViewField(FieldEmail) {
+		x.Email = utilstring.MaskEmail(x.Email)
+	}
+	if !permissions.CanViewField(FieldTel) {
+		x.Tel = utilstring.MaskTel(x.Tel)
+	}
+	if !permissions.CanViewField(FieldRoles) {
+		x.Roles = ""
+		x.EffectiveRoles = ""
+	}
 }
 
 func (x *UserAccount) SetUsername(value string) {
@@ -130,11 +173,17 @@ func (x defaultAccountService) FindByID(id string) (*UserAccount, error) {
 
 	data := new(UserAccount)
 
-	result := x.appService.Repository().Find(data, "id = ?", id)
+	repo := x.appService.Repository()
+
+	result := repo.Find(data, "id = ?", id)
 
 	if result.Error != nil || result.RowsAffected == 0 {
 		return nil, result.Error
 	}
 
+	if err := loadAccountRoles(repo, data); err != nil {
+		return nil, err
+	}
+
 	return data, nil
 }
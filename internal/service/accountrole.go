@@ -0,0 +1,251 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go-auth-admin/internal/config/consts"
+	"go-auth-admin/internal/repository"
+	xlog "go-auth-admin/internal/util/utillog"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// AccountRole links account to role, source of UserAccount.Roles
+type AccountRole struct {
+	AccountID string `gorm:"size:255;primaryKey"`
+	RoleCode  string `gorm:"size:255;primaryKey;index"`
+	CreatedAt time.Time
+}
+
+// loadAccountRoles fills Roles of accounts from account_roles,
+// EffectiveRoles adds roles of account groups
+func loadAccountRoles(repo repository.AppRepository, accounts ...*UserAccount) error {
+
+	ids := make([]string, 0, len(accounts))
+	for _, v := range accounts {
+		if v != nil {
+			ids = append(ids, v.ID)
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	rows := []AccountRole{}
+
+	res := repo.Where("account_id in ?", ids).Order("role_code").Find(&rows)
+	if res.Error != nil {
+		return res.Error
+	}
+
+	byAccount := map[string][]string{}
+	for _, v := range rows {
+		byAccount[v.AccountID] = append(byAccount[v.AccountID], v.RoleCode)
+	}
+
+	// group roles of memberships
+	groupRows := []AccountRole{}
+
+	res = repo.Raw("select m.account_id, r.role_code from account_group_members m"+
+		" join account_group_roles r on r.group_id = m.group_id"+
+		" where m.account_id in ?", ids).Scan(&groupRows)
+	if res.Error != nil {
+		return res.Error
+	}
+
+	effective := map[string][]string{}
+	for _, v := range slices.Concat(rows, groupRows) {
+		effective[v.AccountID] = append(effective[v.AccountID], v.RoleCode)
+	}
+
+	for _, v := range accounts {
+		if v != nil {
+			v.Roles = strings.Join(byAccount[v.ID], " ")
+			v.EffectiveRoles = strings.Join(slices.Compact(slices.Sorted(slices.Values(effective[v.ID]))), " ")
+		}
+	}
+
+	return nil
+}
+
+// saveAccountRoles replaces account roles with space-separated roles
+func saveAccountRoles(repo repository.AppRepository, accountID string, roles string) error {
+
+	res := repo.Where("account_id = ?", accountID).Delete(&AccountRole{})
+	if res.Error != nil {
+		return res.Error
+	}
+
+	codes := slices.Compact(slices.Sorted(slices.Values(strings.Fields(roles))))
+
+	if len(codes) > 0 {
+
+		now := time.Now().UTC()
+		rows := make([]AccountRole, 0, len(codes))
+		for _, v := range codes {
+			rows = append(rows, AccountRole{AccountID: accountID, RoleCode: v, CreatedAt: now})
+		}
+
+		if err := repo.Create(&rows).Error; err != nil {
+			return err
+		}
+	}
+
+	return syncLegacyRoles(repo, accountID, codes)
+}
+
+// addAccountRoles adds roles to current direct roles of account
+func addAccountRoles(repo repository.AppRepository, accountID string, roles []string) error {
+
+	acc := &UserAccount{}
+
+	res := repo.Select("id").Find(acc, "id = ?", accountID)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("error account not found: %v", accountID)
+	}
+
+	if err := loadAccountRoles(repo, acc); err != nil {
+		return err
+	}
+
+	return saveAccountRoles(repo, accountID, acc.Roles+" "+strings.Join(roles, " "))
+}
+
+// legacyRolesSize of user_accounts.roles, roles over it are left out of the column
+const legacyRolesSize = 255
+
+// syncLegacyRoles writes direct roles to user_accounts.roles, auth app still reads the column
+func syncLegacyRoles(repo repository.AppRepository, accountID string, codes []string) error {
+
+	if !repo.Driver().Migrator().HasColumn(&UserAccount{}, "roles") {
+		return nil
+	}
+
+	value := ""
+	for _, v := range codes {
+		if len(value)+len(v)+1 > legacyRolesSize {
+			xlog.Warn("role %v of account %v does not fit user_accounts.roles", v, accountID)
+			continue
+		}
+		value = strings.TrimSpace(value + " " + v)
+	}
+
+	return repo.Exec("update user_accounts set roles = ? where id = ?", value, accountID).Error
+}
+
+// ErrLastAdmin change would leave no account with admin role
+var ErrLastAdmin = errors.New("error last admin cannot be deleted or demoted")
+
+// sqlAdmins where of accounts with admin role, direct or by group
+const sqlAdmins = "id in (select account_id from account_roles where role_code = ?)" +
+	" or id in (select m.account_id from account_group_members m" +
+	" join account_group_roles r on r.group_id = m.group_id where r.role_code = ?)"
+
+// countAdmins accounts with admin role, direct or by group
+func countAdmins(repo repository.AppRepository) (int64, error) {
+
+	var count int64
+
+	res := repo.Model(&UserAccount{}).
+		Where(sqlAdmins, consts.RoleAdmin, consts.RoleAdmin).
+		Count(&count)
+
+	return count, res.Error
+}
+
+// lockAdmins locks accounts with admin role until transaction end,
+// concurrent demotions wait and count admins left by this one
+func lockAdmins(tx repository.AppRepository) error {
+
+	ids := []string{}
+
+	res := tx.Model(&UserAccount{}).
+		Clauses(clause.Locking{Strength: "UPDATE"}). // no aggregate with for update, select rows
+		Where(sqlAdmins, consts.RoleAdmin, consts.RoleAdmin).
+		Pluck("id", &ids)
+
+	return res.Error
+}
+
+// keepAdmin runs fc in transaction with admin accounts locked, rolls back with ErrLastAdmin if fc removes last admin
+func keepAdmin(repo repository.AppRepository, fc func(tx repository.AppRepository) error) error {
+
+	return repo.Transaction(func(tx repository.AppRepository) error {
+
+		if err := lockAdmins(tx); err != nil {
+			return err
+		}
+
+		before, err := countAdmins(tx)
+		if err != nil {
+			return err
+		}
+
+		if err = fc(tx); err != nil {
+			return err
+		}
+
+		if before == 0 {
+			return nil // no admin yet, nothing to keep
+		}
+
+		after, err := countAdmins(tx)
+		if err != nil {
+			return err
+		}
+
+		if after == 0 {
+			return ErrLastAdmin
+		}
+
+		return nil
+	})
+}
+
+// migrateLegacyRoles copies space-separated user_accounts.roles to account_roles
+// for accounts without account_roles, the column stays in sync by saveAccountRoles
+func migrateLegacyRoles(appService AppService) error {
+
+	repo := appService.Repository()
+
+	if !repo.Driver().Migrator().HasColumn(&UserAccount{}, "roles") {
+		return nil
+	}
+
+	type legacyRow struct {
+		ID    string
+		Roles string
+	}
+
+	rows := []legacyRow{}
+
+	res := repo.Raw("select id, roles from user_accounts where roles is not null and roles != ''" +
+		" and id not in (select account_id from account_roles)").Scan(&rows)
+	if res.Error != nil {
+		return res.Error
+	}
+
+	if len(rows) == 0 {
+		return nil
+	}
+
+	xlog.Info("migrating legacy roles: %v accounts", len(rows))
+
+	return repo.Transaction(func(tx repository.AppRepository) error {
+
+		for _, v := range rows {
+			if err := saveAccountRoles(tx, v.ID, v.Roles); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
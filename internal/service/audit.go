@@ -0,0 +1,73 @@
+package service
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	AuditActionPasswordGenerate = "account.password_generate"
+
+	AuditActionContactVerify   = "account.contact_verify"
+	AuditActionContactUnverify = "account.contact_unverify"
+	AuditActionContactPasscode = "account.contact_passcode"
+
+	AuditActionImpersonateStart = "account.impersonate_start"
+	AuditActionImpersonateEnd   = "account.impersonate_end"
+
+	AuditActionInviteCreate = "invite.create"
+	AuditActionInviteResend = "invite.resend"
+	AuditActionInviteRevoke = "invite.revoke"
+	AuditActionInviteRedeem = "invite.redeem"
+
+	AuditActionRoleCreate = "role.create"
+	AuditActionRoleUpdate = "role.update"
+	AuditActionRoleDelete = "role.delete"
+
+	AuditActionGroupCreate       = "group.create"
+	AuditActionGroupUpdate       = "group.update"
+	AuditActionGroupDelete       = "group.delete"
+	AuditActionGroupMemberAdd    = "group.member_add"
+	AuditActionGroupMemberRemove = "group.member_remove"
+
+	AuditActionApprovalRequest = "approval.request"
+	AuditActionApprovalApprove = "approval.approve"
+	AuditActionApprovalReject  = "approval.reject"
+	AuditActionApprovalExpire  = "approval.expire"
+
+	AuditActionOutboxRetry = "outbox.retry"
+
+	AuditActionNotifyReport = "notify.report" // security event reported by auth app
+)
+
+// AuditLog is an append-only record of admin actions
+type AuditLog struct {
+	ID        string    `json:"id" gorm:"size:255;primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+	ActorID   string    `json:"actor_id,omitempty" gorm:"size:255;index"`  // who
+	Action    string    `json:"action" gorm:"size:255;index"`              // what
+	TargetID  string    `json:"target_id,omitempty" gorm:"size:255;index"` // whom
+	Details   string    `json:"details,omitempty" gorm:"size:1024"`        // never store secrets here
+}
+
+type AuditLogDAO struct {
+	appService AppService
+}
+
+// Add appends a new record to the audit trail
+func (x *AuditLogDAO) Add(actorID string, action string, targetID string, details string) error {
+
+	data := &AuditLog{
+		ID:        uuid.New().String(),
+		CreatedAt: time.Now().UTC(),
+		ActorID:   actorID,
+		Action:    action,
+		TargetID:  targetID,
+		Details:   details,
+	}
+
+	repo := x.appService.Repository()
+	res := repo.Create(data)
+	return res.Error
+}
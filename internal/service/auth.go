@@ -1,10 +1,19 @@
 package service
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"go-auth-admin/internal/config/consts"
+	"go-auth-admin/internal/messenger"
+	"go-auth-admin/internal/repository"
+	"go-auth-admin/internal/token"
 	"go-auth-admin/internal/util/utilaccess"
+	xlog "go-auth-admin/internal/util/utillog"
 	"go-auth-admin/internal/util/utilpaging"
 	"go-auth-admin/internal/util/utilstring"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -26,6 +35,9 @@ func (x *UserAccount) Fill() {
 	// }
 }
 
+// passcodeUsedKey + hash of scope and passcode, replay protection
+const passcodeUsedKey = "passcode:used:"
+
 type UserAccountDAO struct {
 	appService AppService
 }
@@ -59,8 +71,32 @@ func (x *defaultAuthService) UserAccounts() *UserAccountDAO {
 func (x *UserAccountDAO) Check(filter *utilpaging.PagingInputDTO) {
 	filter.Limit = min(filter.Limit, 10) // validate
 }
+
+// Permissions resource and field permissions of actor,
+// contacts need view or edit, roles need auth_roles to change
 func (x *UserAccountDAO) Permissions(userAccount *UserAccount, dto *utilaccess.PermissionsDTO) {
-	dto.Fill(userAccount.Roles, consts.AuthRolePrefix)
+
+	if userAccount == nil {
+		return
+	}
+
+	roles := userAccount.EffectiveRoleCodes()
+
+	dto.Fill(strings.Join(roles, " "), consts.AuthRolePrefix)
+
+	contacts := utilaccess.FieldPermissionsDTO{
+		View: utilaccess.HasAnyOfRoles(roles, consts.AuthRoleView, consts.AuthRoleEdit),
+		Edit: utilaccess.HasAnyOfRoles(roles, consts.AuthRoleAdd, consts.AuthRoleEdit),
+	}
+
+	dto.Fields = map[string]utilaccess.FieldPermissionsDTO{
+		FieldEmail: contacts,
+		FieldTel:   contacts,
+		FieldRoles: {
+			View: dto.Access,
+			Edit: utilaccess.HasAnyOfRoles(roles, consts.AuthRoleRoles),
+		},
+	}
 }
 
 func (x *UserAccountDAO) Where(filter *utilpaging.PagingInputDTO) (whereCondition string, whereArgs []any, err error) {
@@ -72,6 +108,30 @@ func (x *UserAccountDAO) Where(filter *utilpaging.PagingInputDTO) (whereConditio
 		whereArgs = append(whereArgs, "%"+v+"%", "%"+v+"%", "%"+v+"%")
 	}
 
+	switch filter.Filters["email_verified"] {
+	case "true":
+		whereCondition += " and email_verified_at is not null"
+	case "false":
+		whereCondition += " and email_verified_at is null"
+	}
+
+	switch filter.Filters["tel_verified"] {
+	case "true":
+		whereCondition += " and tel_verified_at is not null"
+	case "false":
+		whereCondition += " and tel_verified_at is null"
+	}
+
+	if v := filter.Filters["role"]; v != "" {
+		whereCondition += " and id in (select account_id from account_roles where role_code = ?)"
+		whereArgs = append(whereArgs, v)
+	}
+
+	if v := filter.Filters["group"]; v != "" {
+		whereCondition += " and id in (select account_id from account_group_members where group_id = ?)"
+		whereArgs = append(whereArgs, v)
+	}
+
 	if whereCondition == "1=1" {
 		whereCondition = ""
 	}
@@ -138,7 +198,7 @@ func (x *UserAccountDAO) Query(filter *utilpaging.PagingInputDTO, output *utilpa
 		return err
 	}
 
-	return err
+	return loadAccountRoles(repo, output.Data...)
 }
 
 func (x *UserAccountDAO) FindByID(id string) (*UserAccount, error) {
@@ -148,12 +208,18 @@ func (x *UserAccountDAO) FindByID(id string) (*UserAccount, error) {
 
 	data := new(UserAccount)
 
-	result := x.appService.Repository().Find(data, "id = ?", id)
+	repo := x.appService.Repository()
+
+	result := repo.Find(data, "id = ?", id)
 
 	if result.Error != nil || result.RowsAffected == 0 {
 		return nil, result.Error
 	}
 
+	if err := loadAccountRoles(repo, data); err != nil {
+		return nil, err
+	}
+
 	return data, nil
 }
 func (x *UserAccountDAO) FindByCode(code string) (*UserAccount, error) {
@@ -163,12 +229,18 @@ func (x *UserAccountDAO) FindByCode(code string) (*UserAccount, error) {
 
 	data := new(UserAccount)
 
-	result := x.appService.Repository().Find(data, "code = ?", code)
+	repo := x.appService.Repository()
+
+	result := repo.Find(data, "code = ?", code)
 
 	if result.Error != nil || result.RowsAffected == 0 {
 		return nil, result.Error
 	}
 
+	if err := loadAccountRoles(repo, data); err != nil {
+		return nil, err
+	}
+
 	return data, nil
 }
 func (x *UserAccountDAO) ID(id string) (string, error) {
@@ -238,22 +310,65 @@ func (x *UserAccountDAO) Tel(tel string) (string, error) {
 
 func (x *UserAccountDAO) Create(data *UserAccount) error {
 
-	repo := x.appService.Repository()
 	data.Fill()
-	res := repo.Model(data).Omit(userAccountOmit...).Create(data)
-	return res.Error
+	data.EmailVerifiedAt, data.TelVerifiedAt = nil, nil // use SetVerified
+
+	return x.appService.Repository().Transaction(func(tx repository.AppRepository) error {
+
+		res := tx.Model(data).Omit(userAccountOmit...).Create(data)
+		if res.Error != nil {
+			return res.Error
+		}
+
+		return saveAccountRoles(tx, data.ID, data.Roles)
+	})
 
 }
 func (x *UserAccountDAO) Update(data *UserAccount) error {
 	repo := x.appService.Repository()
 
+	var rolesBefore []string
+
+	{
+		// keep verification unless contact changed, use SetVerified
+		curr, err := x.FindByID(data.ID)
+		if err != nil {
+			return err
+		}
+
+		if curr != nil {
+			rolesBefore = curr.EffectiveRoleCodes()
+		}
+
+		data.EmailVerifiedAt, data.TelVerifiedAt = nil, nil
+
+		if curr != nil && curr.Email == data.Email {
+			data.EmailVerifiedAt = curr.EmailVerifiedAt
+		}
+		if curr != nil && curr.Tel == data.Tel {
+			data.TelVerifiedAt = curr.TelVerifiedAt
+		}
+	}
+
 	// res := repo.Model(data).Omit(userAccountOmit...).Updates(data) // .Updates() ignores zero-fields
 
 	// res := repo.Model(data).Omit(userAccountOmit...).Save(data)
 
-	res := repo.Model(data).Select("*" /*over all columns*/).Omit(userAccountOmit...).Updates(data)
+	err := keepAdmin(repo, func(tx repository.AppRepository) error {
 
-	return res.Error
+		res := tx.Model(data).Select("*" /*over all columns*/).Omit(userAccountOmit...).Updates(data)
+		if res.Error != nil {
+			return res.Error
+		}
+
+		return saveAccountRoles(tx, data.ID, data.Roles)
+	})
+
+	if err == nil {
+		x.appService.AuthAdmin().Notify().notifyRolesChanged(data.ID, rolesBefore)
+	}
+
+	return err
 }
 func (x *UserAccountDAO) UpdatePassword(id string, pw string) error {
 
@@ -261,9 +376,14 @@ func (x *UserAccountDAO) UpdatePassword(id string, pw string) error {
 	if err := data.SetPassword(pw); err != nil {
 		return err
 	}
+	// signed in sessions end with old password, revoked first: no new password with old sessions alive
+	if err := x.appService.TokenRevocation().RevokeUser(id); err != nil {
+		return err
+	}
 	//
 	repo := x.appService.Repository()
 	res := repo.Model(data).Select("password_hash" /*over all columns*/).Updates(data)
+
 	return res.Error
 }
 func (x *UserAccountDAO) Delete(id string) error {
@@ -272,7 +392,141 @@ func (x *UserAccountDAO) Delete(id string) error {
 		return nil
 	}
 
+	if err := deleteAccount(x.appService.Repository(), id); err != nil {
+		return err
+	}
+
+	x.revokeDeleted(id)
+
+	return nil
+}
+
+// deleteAccount deletes account with its roles and memberships, repo may be transaction
+func deleteAccount(repo repository.AppRepository, id string) error {
+
+	return keepAdmin(repo, func(tx repository.AppRepository) error {
+
+		res := tx.Where("account_id = ?", id).Delete(&AccountRole{})
+		if res.Error != nil {
+			return res.Error
+		}
+
+		res = tx.Where("account_id = ?", id).Delete(&AccountGroupMember{})
+		if res.Error != nil {
+			return res.Error
+		}
+
+		return tx.Delete(&UserAccount{ID: id}).Error
+	})
+}
+
+// revokeDeleted revokes tokens of deleted account,
+// tokens of it fail on account lookup, revoke is not an error of delete
+func (x *UserAccountDAO) revokeDeleted(id string) {
+
+	if err := x.appService.TokenRevocation().RevokeUser(id); err != nil {
+		xlog.Error("error revoking tokens of deleted account %v: %v", id, err)
+	}
+}
+
+func verifiedColumn(contact string) (string, error) {
+	switch contact {
+	case ContactEmail:
+		return "email_verified_at", nil
+	case ContactTel:
+		return "tel_verified_at", nil
+	}
+	return "", fmt.Errorf("error unknown contact type: %v", contact)
+}
+
+// SetVerified marks email or tel as verified (now) or not verified (null)
+func (x *UserAccountDAO) SetVerified(id string, contact string, verified bool) (*time.Time, error) {
+
+	column, err := verifiedColumn(contact)
+	if err != nil {
+		return nil, err
+	}
+
+	var value *time.Time
+	if verified {
+		now := time.Now().UTC()
+		value = &now
+	}
+
 	repo := x.appService.Repository()
-	res := repo.Delete(&UserAccount{ID: id})
-	return res.Error
+	res := repo.Model(&UserAccount{ID: id}).Update(column, value)
+	return value, res.Error
+}
+
+// verifyPasscodeScope issuer + contact value
+func verifyPasscodeScope(data *UserAccount, contact string) (scope string, value string, err error) {
+
+	var issuer string
+
+	switch contact {
+	case ContactEmail:
+		issuer, value = IssuerConfirmEmail, data.Email
+	case ContactTel:
+		issuer, value = IssuerConfirmTel, data.Tel
+	default:
+		return "", "", fmt.Errorf("error unknown contact type: %v", contact)
+	}
+
+	if value == "" {
+		return "", "", fmt.Errorf("error contact is empty: %v", contact)
+	}
+
+	return issuer + " " + value, value, nil
+}
+
+// SendVerifyPasscode sends passcode to email or tel, passcode scope is issuer + contact value
+func (x *UserAccountDAO) SendVerifyPasscode(data *UserAccount, contact string, lang string) error {
+
+	scope, value, err := verifyPasscodeScope(data, contact)
+	if err != nil {
+		return err
+	}
+
+	_, secret, err := x.appService.Vault().KeyScopeAuth().CurrentKey()
+	if err != nil {
+		return err
+	}
+
+	passcode, err := token.GeneratePasscode(token.NewConfigTotp(scope, secret))
+	if err != nil {
+		return err
+	}
+
+	kind := messenger.KindSmsPasscode
+	if contact == ContactEmail {
+		kind = messenger.KindEmailPasscode
+	}
+
+	// not sent passcode is an error, admin waits for it otherwise
+	return x.appService.Messenger().Send(kind, value, lang, map[string]string{"passcode": passcode})
+}
+
+// ValidateVerifyPasscode passcode of SendVerifyPasscode, each passcode is accepted once in every replica
+func (x *UserAccountDAO) ValidateVerifyPasscode(data *UserAccount, contact string, passcode string) (bool, error) {
+
+	scope, _, err := verifyPasscodeScope(data, contact)
+	if err != nil {
+		return false, err
+	}
+
+	_, secret, err := x.appService.Vault().KeyScopeAuth().CurrentKey()
+	if err != nil {
+		return false, err
+	}
+
+	ok, err := token.ValidatePasscode(passcode, token.NewConfigTotp(scope, secret))
+	if err != nil || !ok {
+		return false, err
+	}
+
+	// passcode is valid for period and skew before and after
+	ttl := 3 * time.Duration(token.PasscodeLifetimeDefault) * time.Second
+	hash := sha256.Sum256([]byte(scope + " " + passcode))
+
+	return x.appService.Store().SetNX(passcodeUsedKey+hex.EncodeToString(hash[:]), "1", ttl)
 }
@@ -2,11 +2,25 @@ package service
 
 type AuthAdminService interface {
 	UserAccounts() *UserAccountDAO
+	AuditLogs() *AuditLogDAO
+	AccountInvites() *AccountInviteDAO
+	Roles() *RoleDAO
+	Groups() *AccountGroupDAO
+	ChangeRequests() *ChangeRequestDAO
+	Outbox() *OutboxDAO
+	Notify() *NotifyDAO
 }
 
 type defaultAuthAdminService struct {
 	appService AppService
 	account    UserAccountDAO
+	auditLog   AuditLogDAO
+	invite     AccountInviteDAO
+	role       RoleDAO
+	group      AccountGroupDAO
+	change     ChangeRequestDAO
+	outbox     OutboxDAO
+	notify     NotifyDAO
 }
 
 func newAuthAdminService(appService AppService) AuthAdminService {
@@ -17,6 +31,27 @@ func newAuthAdminService(appService AppService) AuthAdminService {
 		account: UserAccountDAO{
 			appService: appService,
 		},
+		auditLog: AuditLogDAO{
+			appService: appService,
+		},
+		invite: AccountInviteDAO{
+			appService: appService,
+		},
+		role: RoleDAO{
+			appService: appService,
+		},
+		group: AccountGroupDAO{
+			appService: appService,
+		},
+		change: ChangeRequestDAO{
+			appService: appService,
+		},
+		outbox: OutboxDAO{
+			appService: appService,
+		},
+		notify: NotifyDAO{
+			appService: appService,
+		},
 	}
 
 	return res
@@ -25,3 +60,31 @@ func newAuthAdminService(appService AppService) AuthAdminService {
 func (x *defaultAuthAdminService) UserAccounts() *UserAccountDAO {
 	return &x.account
 }
+
+func (x *defaultAuthAdminService) AuditLogs() *AuditLogDAO {
+	return &x.auditLog
+}
+
+func (x *defaultAuthAdminService) AccountInvites() *AccountInviteDAO {
+	return &x.invite
+}
+
+func (x *defaultAuthAdminService) Roles() *RoleDAO {
+	return &x.role
+}
+
+func (x *defaultAuthAdminService) Groups() *AccountGroupDAO {
+	return &x.group
+}
+
+func (x *defaultAuthAdminService) ChangeRequests() *ChangeRequestDAO {
+	return &x.change
+}
+
+func (x *defaultAuthAdminService) Outbox() *OutboxDAO {
+	return &x.outbox
+}
+
+func (x *defaultAuthAdminService) Notify() *NotifyDAO {
+	return &x.notify
+}
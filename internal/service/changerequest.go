@@ -0,0 +1,406 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-auth-admin/internal/config"
+	"go-auth-admin/internal/repository"
+	"go-auth-admin/internal/util/utilpaging"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ChangeRequestStatusPending  = "pending"
+	ChangeRequestStatusApproved = "approved"
+	ChangeRequestStatusRejected = "rejected"
+	ChangeRequestStatusExpired  = "expired"
+)
+
+// change request action types, enabled by config approval.actions
+const (
+	ChangeActionAccountGrantAdmin = "account.grant_admin"
+	ChangeActionAccountDelete     = "account.delete"
+)
+
+// ChangeActionGroupGrantAdmin group roles adding admin, grants admin to members,
+// enabled by account.grant_admin
+const ChangeActionGroupGrantAdmin = "group.grant_admin"
+
+var ChangeActions = []string{
+	ChangeActionAccountGrantAdmin,
+	ChangeActionAccountDelete,
+}
+
+var ErrChangeRequestNotPending = errors.New("error change request is not pending")
+
+// ChangeRequest is a sensitive admin action waiting for approval of a second admin
+type ChangeRequest struct {
+	ID        string     `json:"id" gorm:"size:255;primaryKey"`
+	Action    string     `json:"action" gorm:"size:255;index"`
+	TargetID  string     `json:"target_id" gorm:"size:255;index"`
+	Payload   string     `json:"payload,omitempty" gorm:"size:4096"` // ChangeRequestPayload json
+	Status    string     `json:"status" gorm:"size:32;index"`
+	CreatedBy string     `json:"created_by,omitempty" gorm:"size:255"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	DecidedBy string     `json:"decided_by,omitempty" gorm:"size:255"`
+	DecidedAt *time.Time `json:"decided_at,omitempty"`
+}
+
+// ChangeRequestPayload requested change, applied on approve over current state of target
+type ChangeRequestPayload struct {
+	Roles   []string `json:"roles,omitempty"`    // roles added to account or group
+	GroupID string   `json:"group_id,omitempty"` // group membership
+}
+
+func (x *ChangeRequest) IsPending() bool {
+	return x.Status == ChangeRequestStatusPending && time.Now().UTC().Before(x.ExpiresAt)
+}
+
+func (x *ChangeRequest) SetPayload(payload *ChangeRequestPayload) error {
+
+	if payload == nil {
+		x.Payload = ""
+		return nil
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	x.Payload = string(data)
+	return nil
+}
+
+func (x *ChangeRequest) GetPayload() (*ChangeRequestPayload, error) {
+
+	res := &ChangeRequestPayload{}
+
+	if x.Payload == "" {
+		return res, nil
+	}
+
+	if err := json.Unmarshal([]byte(x.Payload), res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// checkApproval fails on unknown action type, typo would silently disable approval
+func checkApproval(appConfig *config.AppConfig, errs *config.ValidationError) {
+
+	for i, v := range appConfig.Approval.Actions {
+		if !slices.Contains(ChangeActions, v) {
+			errs.Add(fmt.Sprintf("approval.actions[%d]", i), "unknown approval action %q", v)
+		}
+	}
+
+	if len(appConfig.Approval.Actions) > 0 && appConfig.Approval.MaxAge <= 0 {
+		errs.Add("approval.max_age", "must be positive")
+	}
+}
+
+type ChangeRequestDAO struct {
+	appService AppService
+}
+
+// IsRequired checks if action type needs approval
+func (x *ChangeRequestDAO) IsRequired(action string) bool {
+
+	if action == ChangeActionGroupGrantAdmin {
+		action = ChangeActionAccountGrantAdmin
+	}
+
+	return slices.Contains(x.appService.Config().Approval.Actions, action)
+}
+
+func (x *ChangeRequestDAO) Check(filter *utilpaging.PagingInputDTO) {
+	filter.Limit = min(filter.Limit, 10) // validate
+}
+
+func (x *ChangeRequestDAO) Where(filter *utilpaging.PagingInputDTO) (whereCondition string, whereArgs []any, err error) {
+	whereCondition = "1=1"
+	whereArgs = []any{}
+
+	status := filter.Filters["status"]
+	if status == "" {
+		status = ChangeRequestStatusPending // default list pending only
+	}
+	whereCondition += " and status = ?"
+	whereArgs = append(whereArgs, status)
+
+	if v := filter.Filters["action"]; v != "" {
+		whereCondition += " and action = ?"
+		whereArgs = append(whereArgs, v)
+	}
+
+	if v := filter.Filters["target"]; v != "" {
+		whereCondition += " and target_id = ?"
+		whereArgs = append(whereArgs, v)
+	}
+
+	return whereCondition, whereArgs, err
+}
+
+func (x *ChangeRequestDAO) Sort(filter *utilpaging.PagingInputDTO) (sqlSort string, err error) {
+
+	sqlSort = "created_at desc"
+	switch filter.Sort {
+	case "-created_at":
+		sqlSort = "created_at desc"
+	case "created_at":
+		sqlSort = "created_at asc"
+	default:
+		filter.Sort = "-created_at"
+	}
+
+	return sqlSort, err
+}
+
+func (x *ChangeRequestDAO) Query(filter *utilpaging.PagingInputDTO, output *utilpaging.PagingOutputDTO[ChangeRequest]) (err error) {
+
+	x.Check(filter)
+
+	repo := x.appService.Repository()
+
+	sqlWhere, sqlWhereArgs, err := x.Where(filter)
+	if err != nil {
+		return err
+	}
+	sqlSort, err := x.Sort(filter)
+	if err != nil {
+		return err
+	}
+	var count int64
+
+	err = repo.Model(&ChangeRequest{}).
+		Where(sqlWhere, sqlWhereArgs...).
+		Count(&count).Error
+
+	if err != nil {
+		return err
+	}
+
+	info := filter.Info(int(count))
+	output.Fill(filter, info)
+	output.Data = make([]*ChangeRequest, 0, info.Limit)
+
+	err = repo.
+		Where(sqlWhere, sqlWhereArgs...).
+		Order(sqlSort).
+		Limit(info.Limit).
+		Offset(info.Offset).
+		Find(&output.Data).Error
+
+	return err
+}
+
+func (x *ChangeRequestDAO) FindByID(id string) (*ChangeRequest, error) {
+	if id == "" {
+		return nil, nil // fmt.Errorf("id cannot be empty")
+	}
+
+	data := new(ChangeRequest)
+
+	result := x.appService.Repository().Find(data, "id = ?", id)
+
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil, result.Error
+	}
+
+	return data, nil
+}
+
+// Pending returns ID of pending request for action and target
+func (x *ChangeRequestDAO) Pending(action string, targetID string) (string, error) {
+
+	data := new(ChangeRequest)
+
+	result := x.appService.Repository().Select("id").Limit(1).Find(data,
+		"status = ? and expires_at > ? and action = ? and target_id = ?",
+		ChangeRequestStatusPending, time.Now().UTC(), action, targetID)
+
+	if result.Error != nil || result.RowsAffected == 0 {
+		return "", result.Error
+	}
+
+	return data.ID, nil
+}
+
+func (x *ChangeRequestDAO) Create(data *ChangeRequest) error {
+	return x.create(x.appService.Repository(), data)
+}
+
+// create stores pending request, repo may be transaction
+func (x *ChangeRequestDAO) create(repo repository.AppRepository, data *ChangeRequest) error {
+
+	maxAge := time.Duration(x.appService.Config().Approval.MaxAge) * time.Second
+
+	data.ID = uuid.New().String()
+	data.Status = ChangeRequestStatusPending
+	data.CreatedAt = time.Now().UTC()
+	data.ExpiresAt = data.CreatedAt.Add(maxAge)
+
+	res := repo.Create(data)
+	return res.Error
+}
+
+// decide moves pending request to status, only one decision wins, repo may be transaction
+func (x *ChangeRequestDAO) decide(repo repository.AppRepository, data *ChangeRequest, status string, actorID string) error {
+
+	now := time.Now().UTC()
+
+	res := repo.Model(data).
+		Where("status = ? and expires_at > ?", ChangeRequestStatusPending, now). // protect from concurrent decision
+		Updates(map[string]any{"status": status, "decided_by": actorID, "decided_at": &now})
+
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrChangeRequestNotPending
+	}
+
+	data.Status = status
+	data.DecidedBy = actorID
+	data.DecidedAt = &now
+
+	return nil
+}
+
+// Approve marks request approved and applies requested change in one transaction,
+// request stays pending if change fails
+func (x *ChangeRequestDAO) Approve(data *ChangeRequest, actorID string) error {
+
+	payload, err := data.GetPayload()
+	if err != nil {
+		return err
+	}
+
+	rolesBefore, err := x.rolesBefore(data, payload)
+	if err != nil {
+		return err
+	}
+
+	stored := *data
+
+	err = x.appService.Repository().Transaction(func(tx repository.AppRepository) error {
+
+		if err := x.decide(tx, data, ChangeRequestStatusApproved, actorID); err != nil {
+			return err
+		}
+
+		return x.apply(tx, data, payload)
+	})
+	if err != nil {
+		*data = stored // rolled back
+		return err
+	}
+
+	srv := x.appService.AuthAdmin()
+
+	if data.Action == ChangeActionAccountDelete {
+		srv.UserAccounts().revokeDeleted(data.TargetID)
+	}
+
+	srv.Groups().notifyMembers(rolesBefore)
+
+	return nil
+}
+
+func (x *ChangeRequestDAO) Reject(data *ChangeRequest, actorID string) error {
+	return x.decide(x.appService.Repository(), data, ChangeRequestStatusRejected, actorID)
+}
+
+// Expire marks overdue pending requests expired, returns their IDs
+func (x *ChangeRequestDAO) Expire() ([]string, error) {
+
+	now := time.Now().UTC()
+
+	repo := x.appService.Repository()
+
+	rows := []ChangeRequest{}
+
+	res := repo.Select("id").
+		Where("status = ? and expires_at <= ?", ChangeRequestStatusPending, now).
+		Find(&rows)
+
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	ids := make([]string, 0, len(rows))
+	for _, v := range rows {
+		ids = append(ids, v.ID)
+	}
+
+	if len(ids) == 0 {
+		return ids, nil
+	}
+
+	res = repo.Model(&ChangeRequest{}).
+		Where("id in ? and status = ?", ids, ChangeRequestStatusPending).
+		Updates(map[string]any{"status": ChangeRequestStatusExpired, "decided_at": &now})
+
+	return ids, res.Error
+}
+
+// apply requested change in transaction, only payload is applied, other fields of target stay current
+func (x *ChangeRequestDAO) apply(tx repository.AppRepository, data *ChangeRequest, payload *ChangeRequestPayload) error {
+
+	switch data.Action {
+	case ChangeActionAccountDelete:
+		return deleteAccount(tx, data.TargetID)
+
+	case ChangeActionAccountGrantAdmin:
+
+		if payload.GroupID != "" {
+			return addGroupMember(tx, payload.GroupID, data.TargetID)
+		}
+
+		if len(payload.Roles) == 0 {
+			return fmt.Errorf("error change request payload is empty: %v", data.ID)
+		}
+
+		return addAccountRoles(tx, data.TargetID, payload.Roles)
+
+	case ChangeActionGroupGrantAdmin:
+
+		if len(payload.Roles) == 0 {
+			return fmt.Errorf("error change request payload is empty: %v", data.ID)
+		}
+
+		return addGroupRoles(tx, data.TargetID, payload.Roles)
+	}
+
+	return fmt.Errorf("error unknown change request action: %v", data.Action)
+}
+
+// rolesBefore effective roles of accounts the request changes, to notify after approve
+func (x *ChangeRequestDAO) rolesBefore(data *ChangeRequest, payload *ChangeRequestPayload) (map[string][]string, error) {
+
+	srv := x.appService.AuthAdmin()
+
+	switch data.Action {
+	case ChangeActionAccountGrantAdmin:
+
+		roles, err := srv.Groups().memberRoles(data.TargetID)
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string][]string{data.TargetID: roles}, nil
+
+	case ChangeActionGroupGrantAdmin:
+		return srv.Groups().membersRoles(data.TargetID)
+	}
+
+	return nil, nil
+}
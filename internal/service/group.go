@@ -0,0 +1,406 @@
+package service
+
+import (
+	"fmt"
+	"go-auth-admin/internal/repository"
+	"go-auth-admin/internal/util/utilpaging"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AccountGroup is a named set of roles, members get group roles
+type AccountGroup struct {
+	ID          string    `json:"id" gorm:"size:255;primaryKey"`
+	Name        string    `json:"name" gorm:"size:255;uniqueIndex"`
+	Description string    `json:"description,omitempty" gorm:"size:1024"`
+	Roles       string    `json:"roles,omitempty" gorm:"-"` // space-separated, stored in account_group_roles
+	CreatedAt   time.Time `json:"-"`
+	UpdatedAt   time.Time `json:"-"`
+}
+
+type AccountGroupRole struct {
+	GroupID  string `gorm:"size:255;primaryKey"`
+	RoleCode string `gorm:"size:255;primaryKey;index"`
+}
+
+type AccountGroupMember struct {
+	GroupID   string `gorm:"size:255;primaryKey"`
+	AccountID string `gorm:"size:255;primaryKey;index"`
+	CreatedAt time.Time
+}
+
+type AccountGroupDAO struct {
+	appService AppService
+}
+
+func (x *AccountGroupDAO) Check(filter *utilpaging.PagingInputDTO) {
+	filter.Limit = min(filter.Limit, 100) // validate
+}
+
+func (x *AccountGroupDAO) Where(filter *utilpaging.PagingInputDTO) (whereCondition string, whereArgs []any, err error) {
+	whereCondition = "1=1"
+	whereArgs = []any{}
+
+	if v := filter.Search; v != "" {
+		whereCondition += " and (lower(name) like lower(?) or lower(description) like lower(?))"
+		whereArgs = append(whereArgs, "%"+v+"%", "%"+v+"%")
+	}
+
+	if whereCondition == "1=1" {
+		whereCondition = ""
+	}
+
+	return whereCondition, whereArgs, err
+}
+
+func (x *AccountGroupDAO) Sort(filter *utilpaging.PagingInputDTO) (sqlSort string, err error) {
+
+	sqlSort = "name asc"
+	switch filter.Sort {
+	case "-name":
+		sqlSort = "name desc"
+	case "name":
+		sqlSort = "name asc"
+	default:
+		filter.Sort = "name"
+	}
+
+	return sqlSort, err
+}
+
+func (x *AccountGroupDAO) Query(filter *utilpaging.PagingInputDTO, output *utilpaging.PagingOutputDTO[AccountGroup]) (err error) {
+
+	x.Check(filter)
+
+	repo := x.appService.Repository()
+
+	sqlWhere, sqlWhereArgs, err := x.Where(filter)
+	if err != nil {
+		return err
+	}
+	sqlSort, err := x.Sort(filter)
+	if err != nil {
+		return err
+	}
+	var count int64
+
+	err = repo.Model(&AccountGroup{}).
+		Where(sqlWhere, sqlWhereArgs...).
+		Count(&count).Error
+
+	if err != nil {
+		return err
+	}
+
+	info := filter.Info(int(count))
+	output.Fill(filter, info)
+	output.Data = make([]*AccountGroup, 0, info.Limit)
+
+	err = repo.
+		Where(sqlWhere, sqlWhereArgs...).
+		Order(sqlSort).
+		Limit(info.Limit).
+		Offset(info.Offset).
+		Find(&output.Data).Error
+
+	if err != nil {
+		return err
+	}
+
+	return loadGroupRoles(repo, output.Data...)
+}
+
+func (x *AccountGroupDAO) FindByID(id string) (*AccountGroup, error) {
+	if id == "" {
+		return nil, nil // fmt.Errorf("id cannot be empty")
+	}
+
+	data := new(AccountGroup)
+
+	repo := x.appService.Repository()
+
+	result := repo.Find(data, "id = ?", id)
+
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil, result.Error
+	}
+
+	if err := loadGroupRoles(repo, data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (x *AccountGroupDAO) Name(name string) (string, error) {
+	if name == "" {
+		return "", nil // fmt.Errorf("name cannot be empty")
+	}
+
+	data := new(AccountGroup)
+
+	result := x.appService.Repository().Select("id").Find(data, "name = ?", name)
+
+	if result.Error != nil || result.RowsAffected == 0 {
+		return "", result.Error
+	}
+
+	return data.ID, nil
+}
+
+func (x *AccountGroupDAO) Create(data *AccountGroup) error {
+
+	data.ID = uuid.New().String()
+
+	return x.appService.Repository().Transaction(func(tx repository.AppRepository) error {
+
+		if err := tx.Create(data).Error; err != nil {
+			return err
+		}
+
+		return saveGroupRoles(tx, data.ID, data.Roles)
+	})
+}
+
+func (x *AccountGroupDAO) Update(data *AccountGroup) error {
+
+	rolesBefore, err := x.membersRoles(data.ID)
+	if err != nil {
+		return err
+	}
+
+	err = keepAdmin(x.appService.Repository(), func(tx repository.AppRepository) error {
+
+		res := tx.Model(data).Select("name", "description", "updated_at").Updates(data)
+		if res.Error != nil {
+			return res.Error
+		}
+
+		return saveGroupRoles(tx, data.ID, data.Roles)
+	})
+	if err != nil {
+		return err
+	}
+
+	x.notifyMembers(rolesBefore)
+
+	return nil
+}
+
+func (x *AccountGroupDAO) Delete(id string) error {
+
+	if id == "" {
+		return nil
+	}
+
+	rolesBefore, err := x.membersRoles(id)
+	if err != nil {
+		return err
+	}
+
+	err = keepAdmin(x.appService.Repository(), func(tx repository.AppRepository) error {
+
+		if err := tx.Where("group_id = ?", id).Delete(&AccountGroupMember{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("group_id = ?", id).Delete(&AccountGroupRole{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&AccountGroup{ID: id}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	x.notifyMembers(rolesBefore)
+
+	return nil
+}
+
+// IsMember checks if account is member of group
+func (x *AccountGroupDAO) IsMember(groupID string, accountID string) (bool, error) {
+
+	var count int64
+
+	res := x.appService.Repository().Model(&AccountGroupMember{}).
+		Where("group_id = ? and account_id = ?", groupID, accountID).
+		Count(&count)
+
+	return count > 0, res.Error
+}
+
+func (x *AccountGroupDAO) AddMember(groupID string, accountID string) error {
+
+	rolesBefore, err := x.memberRoles(accountID)
+	if err != nil {
+		return err
+	}
+
+	if err = addGroupMember(x.appService.Repository(), groupID, accountID); err != nil {
+		return err
+	}
+
+	x.appService.AuthAdmin().Notify().notifyRolesChanged(accountID, rolesBefore)
+
+	return nil
+}
+
+func (x *AccountGroupDAO) RemoveMember(groupID string, accountID string) error {
+
+	rolesBefore, err := x.memberRoles(accountID)
+	if err != nil {
+		return err
+	}
+
+	err = keepAdmin(x.appService.Repository(), func(tx repository.AppRepository) error {
+
+		res := tx.Where("group_id = ? and account_id = ?", groupID, accountID).
+			Delete(&AccountGroupMember{})
+		return res.Error
+	})
+	if err != nil {
+		return err
+	}
+
+	x.appService.AuthAdmin().Notify().notifyRolesChanged(accountID, rolesBefore)
+
+	return nil
+}
+
+// addGroupMember makes account member of group, repo may be transaction
+func addGroupMember(repo repository.AppRepository, groupID string, accountID string) error {
+
+	data := &AccountGroupMember{
+		GroupID:   groupID,
+		AccountID: accountID,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	return repo.Create(data).Error
+}
+
+// addGroupRoles adds roles to current roles of group
+func addGroupRoles(repo repository.AppRepository, groupID string, roles []string) error {
+
+	group := &AccountGroup{}
+
+	res := repo.Select("id").Find(group, "id = ?", groupID)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("error group not found: %v", groupID)
+	}
+
+	if err := loadGroupRoles(repo, group); err != nil {
+		return err
+	}
+
+	return saveGroupRoles(repo, groupID, group.Roles+" "+strings.Join(roles, " "))
+}
+
+// memberRoles effective roles of account, to notify on change
+func (x *AccountGroupDAO) memberRoles(accountID string) ([]string, error) {
+
+	acc, err := x.appService.AuthAdmin().UserAccounts().FindByID(accountID)
+	if err != nil || acc == nil {
+		return nil, err
+	}
+
+	return acc.EffectiveRoleCodes(), nil
+}
+
+// membersRoles effective roles of each group member by account id, to notify on group change
+func (x *AccountGroupDAO) membersRoles(groupID string) (map[string][]string, error) {
+
+	if !x.appService.AuthAdmin().Notify().isRolesEnabled() {
+		return nil, nil
+	}
+
+	ids := []string{}
+
+	res := x.appService.Repository().Model(&AccountGroupMember{}).
+		Where("group_id = ?", groupID).
+		Pluck("account_id", &ids)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	byAccount := map[string][]string{}
+
+	for _, id := range ids {
+		roles, err := x.memberRoles(id)
+		if err != nil {
+			return nil, err
+		}
+		byAccount[id] = roles
+	}
+
+	return byAccount, nil
+}
+
+// notifyMembers tells each member of membersRoles its effective roles if they changed
+func (x *AccountGroupDAO) notifyMembers(rolesBefore map[string][]string) {
+
+	for id, roles := range rolesBefore {
+		x.appService.AuthAdmin().Notify().notifyRolesChanged(id, roles)
+	}
+}
+
+// loadGroupRoles fills Roles of groups from account_group_roles
+func loadGroupRoles(repo repository.AppRepository, groups ...*AccountGroup) error {
+
+	ids := make([]string, 0, len(groups))
+	for _, v := range groups {
+		ids = append(ids, v.ID)
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	rows := []AccountGroupRole{}
+
+	res := repo.Where("group_id in ?", ids).Order("role_code").Find(&rows)
+	if res.Error != nil {
+		return res.Error
+	}
+
+	byGroup := map[string][]string{}
+	for _, v := range rows {
+		byGroup[v.GroupID] = append(byGroup[v.GroupID], v.RoleCode)
+	}
+
+	for _, v := range groups {
+		v.Roles = strings.Join(byGroup[v.ID], " ")
+	}
+
+	return nil
+}
+
+// saveGroupRoles replaces group roles with space-separated roles
+func saveGroupRoles(repo repository.AppRepository, groupID string, roles string) error {
+
+	res := repo.Where("group_id = ?", groupID).Delete(&AccountGroupRole{})
+	if res.Error != nil {
+		return res.Error
+	}
+
+	codes := slices.Compact(slices.Sorted(slices.Values(strings.Fields(roles))))
+
+	if len(codes) == 0 {
+		return nil
+	}
+
+	rows := make([]AccountGroupRole, 0, len(codes))
+	for _, v := range codes {
+		rows = append(rows, AccountGroupRole{GroupID: groupID, RoleCode: v})
+	}
+
+	return repo.Create(&rows).Error
+}
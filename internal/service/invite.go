@@ -0,0 +1,328 @@
+package service
+
+import (
+	"errors"
+	"go-auth-admin/internal/config/consts"
+	"go-auth-admin/internal/repository"
+	"go-auth-admin/internal/token"
+	"go-auth-admin/internal/util/utilaccess"
+	"go-auth-admin/internal/util/utilhttp"
+	"go-auth-admin/internal/util/utilpaging"
+	"go-auth-admin/internal/util/utilstring"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	InviteStatusPending  = "pending"
+	InviteStatusAccepted = "accepted"
+	InviteStatusRevoked  = "revoked"
+)
+
+// AccountInvite is a pending signup for email or tel with preset roles
+type AccountInvite struct {
+	ID        string    `json:"id" gorm:"size:255;primaryKey"`
+	Email     string    `json:"email,omitempty" gorm:"size:255;index"`
+	Tel       string    `json:"tel,omitempty" gorm:"size:255;index"`
+	Roles     string    `json:"roles,omitempty" gorm:"size:255"`
+	Status    string    `json:"status" gorm:"size:32;index"`
+	TokenID   string    `json:"-" gorm:"size:255"` // jti of last issued token, rotated on resend (single-use)
+	CreatedBy string    `json:"created_by,omitempty" gorm:"size:255"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"-"`
+	ExpiresAt time.Time `json:"expires_at"`
+	SentAt    time.Time `json:"sent_at"`
+	SentCount int       `json:"sent_count"`
+}
+
+func (x *AccountInvite) IsPending() bool {
+	return x.Status == InviteStatusPending && time.Now().UTC().Before(x.ExpiresAt)
+}
+
+func (x *AccountInvite) issuer() string {
+	if x.Email != "" {
+		return IssuerConfirmEmail
+	}
+	return IssuerConfirmTel
+}
+
+// isFor checks invite contact is contact of account
+func (x *AccountInvite) isFor(acc *UserAccount) bool {
+
+	if x.Email != "" {
+		return utilstring.NormalizeEmail(x.Email) == utilstring.NormalizeEmail(acc.Email)
+	}
+
+	return x.Tel != "" && utilstring.NormalizeTel(x.Tel) == utilstring.NormalizeTel(acc.Tel)
+}
+
+type AccountInviteDAO struct {
+	appService AppService
+}
+
+func (x *AccountInviteDAO) Check(filter *utilpaging.PagingInputDTO) {
+	filter.Limit = min(filter.Limit, 10) // validate
+}
+
+func (x *AccountInviteDAO) Where(filter *utilpaging.PagingInputDTO) (whereCondition string, whereArgs []any, err error) {
+	whereCondition = "1=1"
+	whereArgs = []any{}
+
+	status := filter.Filters["status"]
+	if status == "" {
+		status = InviteStatusPending // default list pending only
+	}
+	whereCondition += " and status = ?"
+	whereArgs = append(whereArgs, status)
+
+	if v := filter.Search; v != "" {
+		whereCondition += " and (lower(email) like lower(?) or lower(tel) like lower(?))"
+		whereArgs = append(whereArgs, "%"+v+"%", "%"+v+"%")
+	}
+
+	return whereCondition, whereArgs, err
+}
+
+func (x *AccountInviteDAO) Sort(filter *utilpaging.PagingInputDTO) (sqlSort string, err error) {
+
+	sqlSort = "created_at desc"
+	switch filter.Sort {
+	case "-created_at":
+		sqlSort = "created_at desc"
+	case "created_at":
+		sqlSort = "created_at asc"
+	default:
+		filter.Sort = "-created_at"
+	}
+
+	return sqlSort, err
+}
+
+func (x *AccountInviteDAO) Query(filter *utilpaging.PagingInputDTO, output *utilpaging.PagingOutputDTO[AccountInvite]) (err error) {
+
+	x.Check(filter)
+
+	repo := x.appService.Repository()
+
+	sqlWhere, sqlWhereArgs, err := x.Where(filter)
+	if err != nil {
+		return err
+	}
+	sqlSort, err := x.Sort(filter)
+	if err != nil {
+		return err
+	}
+	var count int64
+
+	err = repo.Model(&AccountInvite{}).
+		Where(sqlWhere, sqlWhereArgs...).
+		Count(&count).Error
+
+	if err != nil {
+		return err
+	}
+
+	info := filter.Info(int(count))
+	output.Fill(filter, info)
+	output.Data = make([]*AccountInvite, 0, info.Limit)
+
+	err = repo.
+		Where(sqlWhere, sqlWhereArgs...).
+		Order(sqlSort).
+		Limit(info.Limit).
+		Offset(info.Offset).
+		Find(&output.Data).Error
+
+	return err
+}
+
+func (x *AccountInviteDAO) FindByID(id string) (*AccountInvite, error) {
+	if id == "" {
+		return nil, nil // fmt.Errorf("id cannot be empty")
+	}
+
+	data := new(AccountInvite)
+
+	result := x.appService.Repository().Find(data, "id = ?", id)
+
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil, result.Error
+	}
+
+	return data, nil
+}
+
+// Pending returns ID of pending invite for email or tel
+func (x *AccountInviteDAO) Pending(email string, tel string) (string, error) {
+	if email == "" && tel == "" {
+		return "", nil
+	}
+
+	data := new(AccountInvite)
+
+	result := x.appService.Repository().Select("id").Limit(1).Find(data,
+		"status = ? and expires_at > ? and ((email = ? and email != '') or (tel = ? and tel != ''))",
+		InviteStatusPending, time.Now().UTC(), email, tel)
+
+	if result.Error != nil || result.RowsAffected == 0 {
+		return "", result.Error
+	}
+
+	return data.ID, nil
+}
+
+func (x *AccountInviteDAO) Create(data *AccountInvite) error {
+
+	data.ID = uuid.New().String()
+	data.Status = InviteStatusPending
+
+	repo := x.appService.Repository()
+	res := repo.Create(data)
+	return res.Error
+}
+
+// Send issues a new signup token (previous one becomes invalid) and delivers it via messenger
+func (x *AccountInviteDAO) Send(data *AccountInvite, lang string) error {
+
+	appConfig := x.appService.Config()
+
+	maxAge := time.Duration(appConfig.Identity.InviteMaxAge) * time.Second
+
+	claims := &token.TokenClaimsDTO{
+		Email: data.Email,
+		Tel:   data.Tel,
+	}
+	claims.ID = uuid.New().String() // jti
+	claims.Subject = data.ID
+	claims.SetIssuer(data.issuer())
+	claims.AddScope(token.ScopeSignup)
+	claims.SetLifetime(maxAge)
+
+	tokenString, err := token.CreateToken(claims, x.appService.Vault().KeyScopeAuth())
+	if err != nil {
+		return err
+	}
+
+	data.TokenID = claims.ID
+	data.ExpiresAt = claims.ExpiresAt.Time
+	data.SentAt = time.Now().UTC()
+	data.SentCount++
+
+	repo := x.appService.Repository()
+	res := repo.Model(data).Select("token_id", "expires_at", "sent_at", "sent_count").Updates(data)
+	if res.Error != nil {
+		return res.Error
+	}
+
+	link := strings.ReplaceAll(appConfig.Identity.InviteURL, "{token}", utilhttp.URLEncode(tokenString))
+
+	msg := x.appService.Messenger()
+
+	if data.Email != "" {
+		return msg.SendInviteToEmail(link, data.Email, lang)
+	}
+
+	return msg.SendInviteToTel(link, data.Tel, lang)
+}
+
+func (x *AccountInviteDAO) Revoke(id string) error {
+
+	repo := x.appService.Repository()
+	res := repo.Model(&AccountInvite{ID: id}).
+		Where("status = ?", InviteStatusPending).
+		Updates(map[string]any{"status": InviteStatusRevoked, "token_id": ""})
+	return res.Error
+}
+
+// ErrInviteInvalid signup token is invalid, expired, used, or not of the account
+var ErrInviteInvalid = errors.New("error invite token is invalid, expired or used")
+
+// Redeem validates signup token of account signed up with it, marks invite accepted and adds invite roles
+// to account, token can be used once. Admin role waits for approval if account.grant_admin needs it,
+// requested by invite creator, change request is returned then.
+func (x *AccountInviteDAO) Redeem(tokenString string, accountID string) (*AccountInvite, *ChangeRequest, error) {
+
+	claims, err := token.ParseToken(tokenString, x.appService.Vault().KeyScopeAuth())
+	if err != nil {
+		return nil, nil, errors.Join(ErrInviteInvalid, err)
+	}
+
+	if claims == nil || !claims.HasScope(token.ScopeSignup) || claims.ID == "" {
+		return nil, nil, ErrInviteInvalid
+	}
+
+	data, err := x.FindByID(claims.Subject)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if data == nil || !data.IsPending() || data.TokenID != claims.ID || !claims.IsIssuedBy(data.issuer()) {
+		return nil, nil, ErrInviteInvalid
+	}
+
+	srv := x.appService.AuthAdmin()
+
+	acc, err := srv.UserAccounts().FindByID(accountID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if acc == nil || !data.isFor(acc) {
+		return nil, nil, ErrInviteInvalid
+	}
+
+	roles := strings.Fields(data.Roles)
+
+	var changeRequest *ChangeRequest
+
+	if slices.Contains(roles, consts.RoleAdmin) && srv.ChangeRequests().IsRequired(ChangeActionAccountGrantAdmin) {
+
+		roles = slices.DeleteFunc(roles, utilaccess.IsAdmin)
+
+		changeRequest = &ChangeRequest{
+			Action:    ChangeActionAccountGrantAdmin,
+			TargetID:  acc.ID,
+			CreatedBy: data.CreatedBy, // four-eyes: inviter cannot approve
+		}
+		if err = changeRequest.SetPayload(&ChangeRequestPayload{Roles: []string{consts.RoleAdmin}}); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	err = x.appService.Repository().Transaction(func(tx repository.AppRepository) error {
+
+		res := tx.Model(data).
+			Where("status = ? and token_id = ?", InviteStatusPending, claims.ID). // protect from concurrent redeem
+			Updates(map[string]any{"status": InviteStatusAccepted, "token_id": ""})
+
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrInviteInvalid
+		}
+
+		if len(roles) > 0 {
+			if err := addAccountRoles(tx, acc.ID, roles); err != nil {
+				return err
+			}
+		}
+
+		if changeRequest != nil {
+			return srv.ChangeRequests().create(tx, changeRequest)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data.Status = InviteStatusAccepted
+	data.TokenID = ""
+
+	return data, changeRequest, nil
+}
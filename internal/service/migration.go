@@ -2,10 +2,46 @@ package service
 
 func mustCreateRepository(appService AppService) {
 
+	mustMigrateRepository(appService)
+
 	mustInitRepositoryMasterData(appService) // not full inited
 
 }
 
+// mustMigrateRepository creates tables owned by admin app
+func mustMigrateRepository(appService AppService) {
+
+	repo := appService.Repository()
+
+	for _, v := range []any{
+		&UserAccount{}, // add missing columns only
+		&AuditLog{},
+		&AccountInvite{},
+		&Role{},
+		&AccountRole{},
+		&AccountGroup{},
+		&AccountGroupRole{},
+		&AccountGroupMember{},
+		&ChangeRequest{},
+		&OutboxMessage{},
+	} {
+		if err := repo.AutoMigrate(v); err != nil {
+			panic(err)
+		}
+	}
+
+	if err := migrateLegacyRoles(appService); err != nil {
+		panic(err)
+	}
+
+}
+
 func mustInitRepositoryMasterData(appService AppService) {
 
+	roles := &RoleDAO{appService: appService}
+
+	if err := roles.seedSystemRoles(); err != nil {
+		panic(err)
+	}
+
 }
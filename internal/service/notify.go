@@ -0,0 +1,124 @@
+package service
+
+import (
+	"fmt"
+	"go-auth-admin/internal/util/utilaccess"
+	xlog "go-auth-admin/internal/util/utillog"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"go-auth-admin/internal/config"
+)
+
+// security events told to affected account, opt-out by config notify.disabled
+const (
+	NotifyEventPasswordReset = "password_reset" // password set by admin
+	NotifyEventRolesChanged  = "roles_changed"  // effective roles changed
+	NotifyEventAccountLocked = "account_locked" // auth_locked role gained
+	NotifyEventMFAEnrolled   = "mfa_enrolled"   // reported by auth app on sys api
+)
+
+var NotifyEvents = []string{
+	NotifyEventPasswordReset,
+	NotifyEventRolesChanged,
+	NotifyEventAccountLocked,
+	NotifyEventMFAEnrolled,
+}
+
+// NotifyEventsReported events of auth app, sent on its report
+var NotifyEventsReported = []string{
+	NotifyEventMFAEnrolled,
+}
+
+// checkNotify fails on unknown event, typo would silently keep notification on
+func checkNotify(appConfig *config.AppConfig, errs *config.ValidationError) {
+
+	for i, v := range appConfig.Notify.Disabled {
+		if !slices.Contains(NotifyEvents, v) {
+			errs.Add(fmt.Sprintf("notify.disabled[%d]", i), "unknown notify event %q", v)
+		}
+	}
+}
+
+type NotifyDAO struct {
+	appService AppService
+}
+
+func (x *NotifyDAO) IsEnabled(event string) bool {
+	return slices.Contains(NotifyEvents, event) && !slices.Contains(x.appService.Config().Notify.Disabled, event)
+}
+
+// Notify sends event message to account email, or tel if account has no email,
+// errors are logged only, changed account stays changed
+func (x *NotifyDAO) Notify(event string, acc *UserAccount, data map[string]string) {
+
+	if acc == nil || !x.IsEnabled(event) {
+		return
+	}
+
+	kind, recipient := "", ""
+
+	switch {
+	case acc.Email != "":
+		kind, recipient = "email-", acc.Email
+	case acc.Tel != "":
+		kind, recipient = "sms-", acc.Tel
+	default:
+		return // no contact
+	}
+
+	kind += strings.ReplaceAll(event, "_", "-") // e.g. email-password-reset
+
+	lang := ""
+	if langs := x.appService.Config().Lang.Langs; len(langs) > 0 {
+		lang = langs[0]
+	}
+
+	values := map[string]string{
+		"username": acc.Username,
+		"time":     time.Now().UTC().Format(time.RFC1123),
+	}
+	maps.Copy(values, data)
+
+	if err := x.appService.Messenger().Send(kind, recipient, lang, values); err != nil {
+		xlog.Error("error on notify %v %v: %v", event, acc.ID, err)
+	}
+}
+
+// isRolesEnabled any event of role change is on
+func (x *NotifyDAO) isRolesEnabled() bool {
+	return x.IsEnabled(NotifyEventRolesChanged) || x.IsEnabled(NotifyEventAccountLocked)
+}
+
+// notifyRolesChanged tells account its effective roles if they differ from before,
+// gained auth_locked is told as account_locked instead
+func (x *NotifyDAO) notifyRolesChanged(accountID string, before []string) {
+
+	if !x.isRolesEnabled() {
+		return
+	}
+
+	acc, err := x.appService.AuthAdmin().UserAccounts().FindByID(accountID)
+	if err != nil {
+		xlog.Error("error on notify %v %v: %v", NotifyEventRolesChanged, accountID, err)
+		return
+	}
+	if acc == nil {
+		return
+	}
+
+	after := acc.EffectiveRoleCodes()
+	if slices.Equal(before, after) {
+		return
+	}
+
+	if !slices.Contains(before, utilaccess.RoleLocked) && slices.Contains(after, utilaccess.RoleLocked) &&
+		x.IsEnabled(NotifyEventAccountLocked) {
+		x.Notify(NotifyEventAccountLocked, acc, nil)
+		return
+	}
+
+	x.Notify(NotifyEventRolesChanged, acc, map[string]string{"roles": strings.Join(after, ", ")})
+}
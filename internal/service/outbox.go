@@ -0,0 +1,440 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-auth-admin/internal/config"
+	"go-auth-admin/internal/util/utilcrypto"
+	xlog "go-auth-admin/internal/util/utillog"
+	"go-auth-admin/internal/util/utilpaging"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	OutboxStatusPending = "pending"
+	OutboxStatusSent    = "sent"
+	OutboxStatusFailed  = "failed" // dead letter, retried manually only
+)
+
+// outboxSealPurpose derives payload key from vault auth key
+const outboxSealPurpose = "outbox"
+
+var ErrOutboxMessageNotFailed = errors.New("error outbox message is not failed")
+
+// OutboxMessage is a rendered message waiting for delivery to the messenger service
+type OutboxMessage struct {
+	ID            string     `json:"id" gorm:"size:255;primaryKey"`
+	Kind          string     `json:"kind" gorm:"size:255;index"`
+	Recipient     string     `json:"recipient" gorm:"size:255;index"`
+	Payload       string     `json:"-" gorm:"size:16384"` // sealed form values, has secrets, cleared on delivery
+	KeyID         string     `json:"-" gorm:"size:255"`   // vault key of payload
+	Status        string     `json:"status" gorm:"size:32;index"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" gorm:"index"`
+	LastError     string     `json:"last_error,omitempty" gorm:"size:1024"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+}
+
+// checkOutbox fails on values which stop delivery
+func checkOutbox(appConfig *config.AppConfig, errs *config.ValidationError) {
+
+	x := appConfig.Outbox
+
+	if !x.Enabled {
+		return
+	}
+
+	for _, v := range []struct {
+		path  string
+		value int
+	}{
+		{"outbox.workers", x.Workers},
+		{"outbox.batch_size", x.BatchSize},
+		{"outbox.poll_interval", x.PollInterval},
+		{"outbox.max_attempts", x.MaxAttempts},
+		{"outbox.backoff", x.Backoff},
+		{"outbox.lease", x.Lease},
+	} {
+		if v.value <= 0 {
+			errs.Add(v.path, "must be positive")
+		}
+	}
+
+	if x.BackoffMax < x.Backoff {
+		errs.Add("outbox.backoff_max", "must not be less than backoff")
+	}
+}
+
+type OutboxDAO struct {
+	appService AppService
+}
+
+// backoff delay after attempt n (1-based), doubled each attempt up to BackoffMax
+func (x *OutboxDAO) backoff(n int) time.Duration {
+
+	cfg := x.appService.Config().Outbox
+
+	delay := time.Duration(cfg.Backoff) * time.Second
+	limit := time.Duration(cfg.BackoffMax) * time.Second
+
+	for i := 1; i < n && delay < limit; i++ {
+		delay *= 2
+	}
+
+	return min(delay, limit)
+}
+
+func (x *OutboxDAO) seal(data *OutboxMessage, formValues map[string]string) error {
+
+	raw, err := json.Marshal(formValues)
+	if err != nil {
+		return err
+	}
+
+	keyID, secret, err := x.appService.Vault().KeyScopeAuth().CurrentKey()
+	if err != nil {
+		return err
+	}
+
+	data.KeyID = keyID
+	data.Payload, err = utilcrypto.Seal(secret, outboxSealPurpose, raw)
+
+	return err
+}
+
+func (x *OutboxDAO) open(data *OutboxMessage) (map[string]string, error) {
+
+	secret, err := x.appService.Vault().KeyScopeAuth().KeyByID(data.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := utilcrypto.Open(secret, outboxSealPurpose, data.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	res := map[string]string{}
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// Enqueue stores message for delivery, implements messenger.Outbox
+func (x *OutboxDAO) Enqueue(kind string, recipient string, formValues map[string]string) error {
+
+	now := time.Now().UTC()
+
+	data := &OutboxMessage{
+		ID:            uuid.New().String(),
+		Kind:          kind,
+		Recipient:     recipient,
+		Status:        OutboxStatusPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+
+	if err := x.seal(data, formValues); err != nil {
+		return err
+	}
+
+	repo := x.appService.Repository()
+	res := repo.Create(data)
+	return res.Error
+}
+
+func (x *OutboxDAO) Check(filter *utilpaging.PagingInputDTO) {
+	filter.Limit = min(filter.Limit, 10) // validate
+}
+
+func (x *OutboxDAO) Where(filter *utilpaging.PagingInputDTO) (whereCondition string, whereArgs []any, err error) {
+	whereCondition = "1=1"
+	whereArgs = []any{}
+
+	status := filter.Filters["status"]
+	if status == "" {
+		status = OutboxStatusFailed // default list dead letters only
+	}
+	whereCondition += " and status = ?"
+	whereArgs = append(whereArgs, status)
+
+	if v := filter.Filters["kind"]; v != "" {
+		whereCondition += " and kind = ?"
+		whereArgs = append(whereArgs, v)
+	}
+
+	if v := filter.Filters["recipient"]; v != "" {
+		whereCondition += " and recipient = ?"
+		whereArgs = append(whereArgs, v)
+	}
+
+	return whereCondition, whereArgs, err
+}
+
+func (x *OutboxDAO) Sort(filter *utilpaging.PagingInputDTO) (sqlSort string, err error) {
+
+	sqlSort = "created_at desc"
+	switch filter.Sort {
+	case "-created_at":
+		sqlSort = "created_at desc"
+	case "created_at":
+		sqlSort = "created_at asc"
+	default:
+		filter.Sort = "-created_at"
+	}
+
+	return sqlSort, err
+}
+
+func (x *OutboxDAO) Query(filter *utilpaging.PagingInputDTO, output *utilpaging.PagingOutputDTO[OutboxMessage]) (err error) {
+
+	x.Check(filter)
+
+	repo := x.appService.Repository()
+
+	sqlWhere, sqlWhereArgs, err := x.Where(filter)
+	if err != nil {
+		return err
+	}
+	sqlSort, err := x.Sort(filter)
+	if err != nil {
+		return err
+	}
+	var count int64
+
+	err = repo.Model(&OutboxMessage{}).
+		Where(sqlWhere, sqlWhereArgs...).
+		Count(&count).Error
+
+	if err != nil {
+		return err
+	}
+
+	info := filter.Info(int(count))
+	output.Fill(filter, info)
+	output.Data = make([]*OutboxMessage, 0, info.Limit)
+
+	err = repo.
+		Where(sqlWhere, sqlWhereArgs...).
+		Order(sqlSort).
+		Limit(info.Limit).
+		Offset(info.Offset).
+		Find(&output.Data).Error
+
+	return err
+}
+
+func (x *OutboxDAO) FindByID(id string) (*OutboxMessage, error) {
+	if id == "" {
+		return nil, nil // fmt.Errorf("id cannot be empty")
+	}
+
+	data := new(OutboxMessage)
+
+	result := x.appService.Repository().Find(data, "id = ?", id)
+
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil, result.Error
+	}
+
+	return data, nil
+}
+
+// Retry moves failed message back to queue with attempts reset
+func (x *OutboxDAO) Retry(data *OutboxMessage) error {
+
+	now := time.Now().UTC()
+
+	repo := x.appService.Repository()
+	res := repo.Model(data).
+		Where("status = ?", OutboxStatusFailed).
+		Updates(map[string]any{"status": OutboxStatusPending, "attempts": 0, "next_attempt_at": now})
+
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrOutboxMessageNotFailed
+	}
+
+	data.Status = OutboxStatusPending
+	data.Attempts = 0
+	data.NextAttemptAt = now
+
+	return nil
+}
+
+// claim takes due messages for one delivery attempt, other workers
+// skip them until lease ends, so a message of a dead worker is retried
+func (x *OutboxDAO) claim(limit int) ([]*OutboxMessage, error) {
+
+	now := time.Now().UTC()
+	lease := now.Add(time.Duration(x.appService.Config().Outbox.Lease) * time.Second)
+
+	repo := x.appService.Repository()
+
+	rows := []*OutboxMessage{}
+
+	res := repo.
+		Where("status = ? and next_attempt_at <= ?", OutboxStatusPending, now).
+		Order("next_attempt_at asc").
+		Limit(limit).
+		Find(&rows)
+
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	claimed := make([]*OutboxMessage, 0, len(rows))
+
+	for _, v := range rows {
+
+		res := repo.Model(&OutboxMessage{}).
+			Where("id = ? and status = ? and next_attempt_at <= ?", v.ID, OutboxStatusPending, now). // protect from concurrent claim
+			Update("next_attempt_at", lease)
+
+		if res.Error != nil {
+			return claimed, res.Error
+		}
+		if res.RowsAffected == 0 {
+			continue // claimed by other worker
+		}
+
+		v.NextAttemptAt = lease
+		claimed = append(claimed, v)
+	}
+
+	return claimed, nil
+}
+
+// deliver makes one attempt, failed message is rescheduled with backoff or dead-lettered
+func (x *OutboxDAO) deliver(data *OutboxMessage) error {
+
+	formValues, err := x.open(data)
+	if err == nil {
+		err = x.appService.Messenger().Deliver(data.Kind, formValues)
+	}
+
+	now := time.Now().UTC()
+	attempts := data.Attempts + 1
+
+	updates := map[string]any{"attempts": attempts}
+
+	if err == nil {
+		updates["status"] = OutboxStatusSent
+		updates["payload"] = "" // secrets are not kept
+		updates["last_error"] = ""
+		updates["sent_at"] = &now
+	} else {
+		xlog.Error("error on outbox delivery %v %v attempt %v: %v", data.Kind, data.ID, attempts, err)
+
+		lastError := err.Error()
+		if len(lastError) > 1024 {
+			lastError = lastError[:1024]
+		}
+		updates["last_error"] = lastError
+
+		if attempts >= x.appService.Config().Outbox.MaxAttempts {
+			updates["status"] = OutboxStatusFailed
+		} else {
+			updates["next_attempt_at"] = now.Add(x.backoff(attempts))
+		}
+	}
+
+	repo := x.appService.Repository()
+	res := repo.Model(data).Updates(updates)
+
+	if res.Error != nil {
+		return res.Error
+	}
+
+	data.Attempts = attempts
+	if v, ok := updates["status"].(string); ok {
+		data.Status = v
+	}
+
+	return nil
+}
+
+// DeliverDue makes one delivery attempt of due messages, returns count of sent ones
+func (x *OutboxDAO) DeliverDue() (sent int, err error) {
+
+	rows, err := x.claim(x.appService.Config().Outbox.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, v := range rows {
+		if err := x.deliver(v); err != nil {
+			return sent, err
+		}
+		if v.Status == OutboxStatusSent {
+			sent++
+		}
+	}
+
+	return sent, nil
+}
+
+// Run delivers due messages by worker pool until ctx is done
+func (x *OutboxDAO) Run(ctx context.Context) {
+
+	cfg := x.appService.Config().Outbox
+
+	jobs := make(chan *OutboxMessage)
+
+	var wg sync.WaitGroup
+
+	for range cfg.Workers {
+		wg.Go(func() {
+			for v := range jobs {
+				if err := x.deliver(v); err != nil {
+					xlog.Error("error on outbox update: %v", err)
+				}
+			}
+		})
+	}
+
+	ticker := time.NewTicker(time.Duration(cfg.PollInterval) * time.Second)
+	defer ticker.Stop()
+
+	xlog.Info("outbox started: %v workers", cfg.Workers)
+
+	for {
+
+		rows, err := x.claim(cfg.BatchSize)
+		if err != nil {
+			xlog.Error("error on outbox claim: %v", err)
+		}
+
+	dispatch:
+		for _, v := range rows {
+			select {
+			case jobs <- v:
+			case <-ctx.Done():
+				break dispatch // rest is retried after lease
+			}
+		}
+
+		if len(rows) == cfg.BatchSize && ctx.Err() == nil {
+			continue // more due messages
+		}
+
+		select {
+		case <-ctx.Done():
+			close(jobs)
+			wg.Wait()
+			xlog.Info("outbox stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
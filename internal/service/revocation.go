@@ -0,0 +1,90 @@
+package service
+
+import (
+	"go-auth-admin/internal/token"
+	"strconv"
+	"time"
+)
+
+const (
+	revokedUserKey  = "revoked:user:"  // + user id, unix time, tokens issued until it are revoked
+	revokedTokenKey = "revoked:token:" // + jti
+)
+
+// TokenRevocationService tokens revoked before expiry, kept in store shared by replicas
+type TokenRevocationService interface {
+	// RevokeUser every token of user issued until now, e.g. on password change (security stamp)
+	RevokeUser(userID string) error
+	// RevokeToken one token by jti, e.g. ended impersonation
+	RevokeToken(claims *token.TokenClaimsDTO) error
+	// IsRevoked token or its user or actor is revoked
+	IsRevoked(claims *token.TokenClaimsDTO) (bool, error)
+}
+
+type defaultTokenRevocationService struct {
+	appService AppService
+}
+
+func newTokenRevocationService(appService AppService) TokenRevocationService {
+	return &defaultTokenRevocationService{appService: appService}
+}
+
+func (x *defaultTokenRevocationService) RevokeUser(userID string) error {
+
+	// older tokens are expired anyway
+	ttl := time.Duration(x.appService.Config().Identity.TokenMaxAge) * time.Second
+
+	return x.appService.Store().Set(revokedUserKey+userID, strconv.FormatInt(time.Now().Unix(), 10), ttl)
+}
+
+func (x *defaultTokenRevocationService) RevokeToken(claims *token.TokenClaimsDTO) error {
+
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return nil // no jti, user may be revoked
+	}
+
+	ttl := time.Until(claims.ExpiresAt.Time)
+	if ttl <= 0 {
+		return nil // expired
+	}
+
+	return x.appService.Store().Set(revokedTokenKey+claims.ID, "1", ttl)
+}
+
+func (x *defaultTokenRevocationService) IsRevoked(claims *token.TokenClaimsDTO) (bool, error) {
+
+	store := x.appService.Store()
+
+	if claims.ID != "" {
+		if _, ok, err := store.Get(revokedTokenKey + claims.ID); err != nil || ok {
+			return ok, err
+		}
+	}
+
+	userIDs := []string{claims.UserID}
+	if claims.IsImpersonated() {
+		userIDs = append(userIDs, claims.Act.UserID) // actor signed out, impersonation ends too
+	}
+
+	for _, userID := range userIDs {
+		if userID == "" {
+			continue
+		}
+
+		value, ok, err := store.Get(revokedUserKey + userID)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			continue
+		}
+
+		// same second is revoked, iat has seconds precision
+		revokedAt, _ := strconv.ParseInt(value, 10, 64)
+		if claims.IssuedAt == nil || claims.IssuedAt.Unix() <= revokedAt {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"go-auth-admin/internal/config/consts"
+	"go-auth-admin/internal/util/utilpaging"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// Role is a catalogue entry for codes in AccountRole
+type Role struct {
+	Code        string    `json:"code" gorm:"size:255;primaryKey"`
+	Description string    `json:"description,omitempty" gorm:"size:1024"`
+	IsSystem    bool      `json:"system,omitempty"` // seeded, cannot be deleted
+	CreatedAt   time.Time `json:"-"`
+	UpdatedAt   time.Time `json:"-"`
+}
+
+// systemRoles seeded on migration
+var systemRoles = []Role{
+	{Code: consts.RoleAdmin, Description: "Full access"},
+	{Code: consts.AuthRoleAccess, Description: "Access to auth admin"},
+	{Code: consts.AuthRoleView, Description: "View accounts"},
+	{Code: consts.AuthRoleAdd, Description: "Add accounts"},
+	{Code: consts.AuthRoleEdit, Description: "Edit accounts"},
+	{Code: consts.AuthRoleDelete, Description: "Delete accounts"},
+	{Code: consts.AuthRolePublish, Description: "Publish"},
+	{Code: consts.AuthRoleRoles, Description: "Grant and revoke roles"},
+	{Code: consts.AuthRoleApprove, Description: "Approve change requests"},
+	{Code: consts.AuthRoleImpersonate, Description: "Sign in as another account"},
+}
+
+type RoleDAO struct {
+	appService AppService
+}
+
+func (x *RoleDAO) Check(filter *utilpaging.PagingInputDTO) {
+	filter.Limit = min(filter.Limit, 100) // validate
+}
+
+func (x *RoleDAO) Where(filter *utilpaging.PagingInputDTO) (whereCondition string, whereArgs []any, err error) {
+	whereCondition = "1=1"
+	whereArgs = []any{}
+
+	if v := filter.Search; v != "" {
+		whereCondition += " and (lower(code) like lower(?) or lower(description) like lower(?))"
+		whereArgs = append(whereArgs, "%"+v+"%", "%"+v+"%")
+	}
+
+	if whereCondition == "1=1" {
+		whereCondition = ""
+	}
+
+	return whereCondition, whereArgs, err
+}
+
+func (x *RoleDAO) Sort(filter *utilpaging.PagingInputDTO) (sqlSort string, err error) {
+
+	sqlSort = "code asc"
+	switch filter.Sort {
+	case "-code":
+		sqlSort = "code desc"
+	case "code":
+		sqlSort = "code asc"
+	default:
+		filter.Sort = "code"
+	}
+
+	return sqlSort, err
+}
+
+func (x *RoleDAO) Query(filter *utilpaging.PagingInputDTO, output *utilpaging.PagingOutputDTO[Role]) (err error) {
+
+	x.Check(filter)
+
+	repo := x.appService.Repository()
+
+	sqlWhere, sqlWhereArgs, err := x.Where(filter)
+	if err != nil {
+		return err
+	}
+	sqlSort, err := x.Sort(filter)
+	if err != nil {
+		return err
+	}
+	var count int64
+
+	err = repo.Model(&Role{}).
+		Where(sqlWhere, sqlWhereArgs...).
+		Count(&count).Error
+
+	if err != nil {
+		return err
+	}
+
+	info := filter.Info(int(count))
+	output.Fill(filter, info)
+	output.Data = make([]*Role, 0, info.Limit)
+
+	err = repo.
+		Where(sqlWhere, sqlWhereArgs...).
+		Order(sqlSort).
+		Limit(info.Limit).
+		Offset(info.Offset).
+		Find(&output.Data).Error
+
+	return err
+}
+
+func (x *RoleDAO) FindByCode(code string) (*Role, error) {
+	if code == "" {
+		return nil, nil // fmt.Errorf("code cannot be empty")
+	}
+
+	data := new(Role)
+
+	result := x.appService.Repository().Find(data, "code = ?", code)
+
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil, result.Error
+	}
+
+	return data, nil
+}
+
+// Unknown returns codes from space-separated roles which are not in catalogue
+func (x *RoleDAO) Unknown(roles string) ([]string, error) {
+
+	codes := slices.Compact(slices.Sorted(slices.Values(strings.Fields(roles))))
+
+	if len(codes) == 0 {
+		return nil, nil
+	}
+
+	known := []string{}
+
+	res := x.appService.Repository().Model(&Role{}).Where("code in ?", codes).Pluck("code", &known)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	return slices.DeleteFunc(codes, func(c string) bool {
+		return slices.Contains(known, c)
+	}), nil
+}
+
+// InUse checks if any account or group has role
+func (x *RoleDAO) InUse(code string) (bool, error) {
+
+	var count int64
+
+	repo := x.appService.Repository()
+
+	res := repo.Model(&AccountRole{}).
+		Where("role_code = ?", code).
+		Count(&count)
+
+	if res.Error != nil || count > 0 {
+		return count > 0, res.Error
+	}
+
+	res = repo.Model(&AccountGroupRole{}).
+		Where("role_code = ?", code).
+		Count(&count)
+
+	return count > 0, res.Error
+}
+
+func (x *RoleDAO) Create(data *Role) error {
+
+	data.IsSystem = false // seeded only
+
+	repo := x.appService.Repository()
+	res := repo.Create(data)
+	return res.Error
+}
+
+func (x *RoleDAO) Update(data *Role) error {
+
+	repo := x.appService.Repository()
+	res := repo.Model(data).Select("description", "updated_at").Updates(data)
+	return res.Error
+}
+
+func (x *RoleDAO) Delete(code string) error {
+
+	if code == "" {
+		return nil
+	}
+
+	repo := x.appService.Repository()
+	res := repo.Where("is_system = ?", false).Delete(&Role{Code: code})
+	return res.Error
+}
+
+// seedSystemRoles inserts system roles, marks existing as system
+func (x *RoleDAO) seedSystemRoles() error {
+
+	data := slices.Clone(systemRoles)
+	for i := range data {
+		data[i].IsSystem = true
+	}
+
+	repo := x.appService.Repository()
+	res := repo.Driver().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "code"}},
+		DoUpdates: clause.AssignmentColumns([]string{"is_system"}),
+	}).Create(&data)
+
+	return res.Error
+}
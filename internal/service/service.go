@@ -2,13 +2,17 @@ package service
 
 import (
 	"go-auth-admin/internal/config"
+	"go-auth-admin/internal/config/consts"
 	"go-auth-admin/internal/i18n"
 	"go-auth-admin/internal/messenger"
+	"go-auth-admin/internal/policy"
 	"go-auth-admin/internal/repository"
+	"go-auth-admin/internal/store"
 	xlog "go-auth-admin/internal/util/utillog"
 	"net/http"
 	"os"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -20,26 +24,36 @@ type AppService interface {
 	AuthAdmin() AuthAdminService
 
 	Config() *config.AppConfig
+	ConfigSource() *config.AppConfigSource
 	// Logger() logger.AppLogger
 
 	UserLang(code string) i18n.UserLang
 	HasLang(code string) bool
 	Messenger() messenger.AppMessenger
+	Policy() *policy.Policy
 
 	Vault() VaultService
+	TokenRevocation() TokenRevocationService
 
 	Repository() repository.AppRepository
+	Store() store.Store // shared by replicas with Redis
 }
 
 type defaultAppService struct {
 	accountService AccountService
 	// container      container.AppContainer
-	vaultService VaultService
+	vaultService *defaultVaultService
 
 	configSource *config.AppConfigSource
 	repository   repository.AppRepository
-	lang         i18n.AppLang
-	messenger    messenger.AppMessenger
+	store        store.Store
+
+	tokenRevocationService TokenRevocationService
+
+	mu        sync.RWMutex // lang, messenger, policy are replaced on config reload
+	lang      i18n.AppLang
+	messenger messenger.AppMessenger
+	policy    *policy.Policy
 
 	authService      AuthService
 	authAdminService AuthAdminService
@@ -69,8 +83,19 @@ func (x *defaultAppService) mustBuild() {
 	//
 	x.lang = i18n.NewAppLang(appConfig)
 
+	x.policy = policy.MustLoad(appConfig.ConfigPath)
+
+	if err = CheckConfig(appConfig); err != nil {
+		panic(err)
+	}
+
 	x.repository = repository.MustNewRepository(appConfig) // , appLogger)
 
+	x.store, err = store.New(appConfig.Redis, consts.AppName)
+	if err != nil {
+		panic(err)
+	}
+
 	//
 
 	if appConfig.DB.Migration {
@@ -83,12 +108,84 @@ func (x *defaultAppService) mustBuild() {
 		panic(err)
 	}
 
+	x.messenger.SetSigner(x.vaultService.KeyScopeAuth()) // receiver verifies by pkg/msgsign
+
 	x.authService = newAuthService(x)
 
 	x.authAdminService = newAuthAdminService(x)
 
+	if appConfig.Outbox.Enabled {
+		x.messenger.SetOutbox(x.authAdminService.Outbox()) // delivered by Outbox().Run
+	}
+
 	x.accountService = newAccountService(x)
 
+	x.tokenRevocationService = newTokenRevocationService(x)
+
+	x.configSource.OnReload(x.reload)
+
+}
+
+// CheckConfig service settings which config validate does not know, error is config.ValidationError
+func CheckConfig(appConfig *config.AppConfig) error {
+
+	errs := config.ValidationError{}
+
+	checkApproval(appConfig, &errs)
+	checkOutbox(appConfig, &errs)
+	checkNotify(appConfig, &errs)
+
+	return errs.Err()
+}
+
+// reload builds lang, messenger, policy and vault keys of next config,
+// they replace current ones on commit, listeners and repository need restart
+func (x *defaultAppService) reload(next *config.AppConfig) (commit func(), err error) {
+
+	if err = CheckConfig(next); err != nil {
+		return nil, err
+	}
+
+	appLang, err := i18n.LoadAppLang(next)
+	if err != nil {
+		return nil, err
+	}
+
+	appMessenger, err := messenger.LoadAppMessenger(next)
+	if err != nil {
+		return nil, err
+	}
+
+	appPolicy, err := policy.Load(next.ConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	keychain, err := loadKeychain(x, next.Vault.Keys)
+	if err != nil {
+		return nil, err
+	}
+
+	appMessenger.SetSigner(x.vaultService.KeyScopeAuth())
+
+	if x.Config().Outbox.Enabled { // workers are started once
+		appMessenger.SetOutbox(x.authAdminService.Outbox())
+	}
+
+	commit = func() {
+		if x.vaultService.setKeychain(keychain) {
+			x.vaultService.notifyKeysChanged()
+		}
+
+		x.mu.Lock()
+		defer x.mu.Unlock()
+
+		x.lang = appLang
+		x.messenger = appMessenger
+		x.policy = appPolicy
+	}
+
+	return commit, nil
 }
 
 func mustConfigRuntime(appConfig *config.AppConfig) {
@@ -148,13 +245,38 @@ func (x *defaultAppService) Auth() AuthService           { return x.authService
 func (x *defaultAppService) AuthAdmin() AuthAdminService { return x.authAdminService }
 
 func (x *defaultAppService) Config() *config.AppConfig { return x.configSource.Config() }
+func (x *defaultAppService) ConfigSource() *config.AppConfigSource {
+	return x.configSource
+}
 
 // func (x *appService) Logger() logger.AppLogger       { return x.container.Logger() }
 
-func (x *defaultAppService) UserLang(code string) i18n.UserLang { return x.lang.UserLang(code) }
-func (x *defaultAppService) HasLang(code string) bool           { return x.lang.HasLang(code) }
-func (x *defaultAppService) Messenger() messenger.AppMessenger  { return x.messenger }
+func (x *defaultAppService) UserLang(code string) i18n.UserLang {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.lang.UserLang(code)
+}
+func (x *defaultAppService) HasLang(code string) bool {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.lang.HasLang(code)
+}
+func (x *defaultAppService) Messenger() messenger.AppMessenger {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.messenger
+}
+
+func (x *defaultAppService) Policy() *policy.Policy {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.policy
+}
 
 func (x *defaultAppService) Vault() VaultService { return x.vaultService }
+func (x *defaultAppService) TokenRevocation() TokenRevocationService {
+	return x.tokenRevocationService
+}
 
 func (x *defaultAppService) Repository() repository.AppRepository { return x.repository }
+func (x *defaultAppService) Store() store.Store                   { return x.store }
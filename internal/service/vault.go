@@ -1,10 +1,14 @@
 package service
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
 	"go-auth-admin/internal/config"
 	"go-auth-admin/internal/util/utilcrypto"
+	xlog "go-auth-admin/internal/util/utillog"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,6 +18,10 @@ const (
 
 	// secretKeySize is len of secrets
 	secretKeySize = 64
+
+	// vaultKeysChannel message is sender instance id, keys of db are loaded again,
+	// auth app may publish on new db key
+	vaultKeysChannel = "vault:keys"
 )
 
 type VaultKey struct {
@@ -52,11 +60,18 @@ type VaultService interface {
 
 	KeyScopeAuth() VaultKeyScope
 
+	// WatchKeys loads keys again when other replica reports changed keys, until ctx is done
+	WatchKeys(ctx context.Context)
+
 	// Append(secret ...SecretKey)
 }
 
 type defaultVaultService struct {
-	keychain []SecretKey
+	appService AppService
+	instanceID string // own messages of vaultKeysChannel are skipped
+
+	mu       sync.RWMutex
+	keychain []SecretKey // replaced on config reload, scopes stay valid
 }
 
 func (x *defaultVaultService) KeyScopeAuth() VaultKeyScope {
@@ -133,7 +148,7 @@ type SecretKey struct {
 func (x SecretKey) IsEmpty() bool {
 	return x.ID == "" || len(x.AuthKey) == 0
 }
-func allKeys(appService AppService) (keys []config.AppConfigVaultKey, err error) {
+func allKeys(appService AppService, keysConfig []config.AppConfigVaultKey) (keys []config.AppConfigVaultKey, err error) {
 
 	keys = []config.AppConfigVaultKey{} // nil
 
@@ -141,8 +156,6 @@ func allKeys(appService AppService) (keys []config.AppConfigVaultKey, err error)
 
 		// 1
 
-		keysConfig := appService.Config().Vault.Keys
-
 		keys = append(keys, keysConfig...) // nil is same as empty array for append
 
 	}
@@ -167,29 +180,34 @@ func allKeys(appService AppService) (keys []config.AppConfigVaultKey, err error)
 	return keys, err
 }
 
-func newVaultService(appService AppService) (VaultService, error) {
+func newVaultService(appService AppService) (*defaultVaultService, error) {
 
-	res := &defaultVaultService{
-		keychain: []SecretKey{},
-	}
-
-	keys, err := allKeys(appService)
+	keychain, err := loadKeychain(appService, appService.Config().Vault.Keys)
 	if err != nil {
 		return nil, err
 	}
-	err = res.loadKeys(keys)
+
+	return &defaultVaultService{
+		appService: appService,
+		instanceID: uuid.New().String(),
+		keychain:   keychain,
+	}, nil
+}
+
+// loadKeychain config keys and stored keys, current key is last
+func loadKeychain(appService AppService, keysConfig []config.AppConfigVaultKey) (keychain []SecretKey, err error) {
+
+	keys, err := allKeys(appService, keysConfig)
 	if err != nil {
 		return nil, err
 	}
-	return res, nil
-}
 
-func (x *defaultVaultService) loadKeys(keys []config.AppConfigVaultKey) (err error) {
+	keychain = []SecretKey{}
 
 	for _, itm := range keys {
 
-		// is empty
-		if itm.IsEmpty() {
+		// only auth key is loaded, IsEmpty would skip every db key
+		if !itm.HasAuthKey() {
 			// may be error
 			continue
 		}
@@ -198,16 +216,58 @@ func (x *defaultVaultService) loadKeys(keys []config.AppConfigVaultKey) (err err
 		k.ID = itm.ID
 
 		if k.AuthKey, err = base64.StdEncoding.DecodeString(itm.AuthKey); err != nil {
-			return fmt.Errorf("error on un-base64 key %v :%v", itm.ID, err)
+			return nil, fmt.Errorf("error on un-base64 key %v :%v", itm.ID, err)
 		}
 
-		x.keychain = append(x.keychain, k)
+		keychain = append(keychain, k)
 	}
 
-	return nil
+	return keychain, nil
+}
+
+// setKeychain true if key ids are changed
+func (x *defaultVaultService) setKeychain(keychain []SecretKey) bool {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	changed := !slices.EqualFunc(x.keychain, keychain, func(a, b SecretKey) bool { return a.ID == b.ID })
+
+	x.keychain = keychain
+
+	return changed
+}
+
+// notifyKeysChanged other replicas load keys of db again, config keys are their own
+func (x *defaultVaultService) notifyKeysChanged() {
+	if err := x.appService.Store().Publish(vaultKeysChannel, x.instanceID); err != nil {
+		xlog.Error("error on vault keys notification: %v", err)
+	}
+}
+
+func (x *defaultVaultService) WatchKeys(ctx context.Context) {
+
+	x.appService.Store().Subscribe(ctx, vaultKeysChannel, func(message string) {
+
+		if message == x.instanceID {
+			return
+		}
+
+		keychain, err := loadKeychain(x.appService, x.appService.Config().Vault.Keys)
+		if err != nil {
+			xlog.Error("error on vault keys reload: %v", err) // current keys stay
+			return
+		}
+
+		if x.setKeychain(keychain) {
+			xlog.Info("vault keys reloaded on notification")
+		}
+	})
 }
 
 func (x *defaultVaultService) CurrentKey() (secret *SecretKey, err error) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
 	if len(x.keychain) > 0 {
 		r := &x.keychain[len(x.keychain)-1]
 		return r, nil
@@ -217,6 +277,8 @@ func (x *defaultVaultService) CurrentKey() (secret *SecretKey, err error) {
 }
 
 func (x *defaultVaultService) KeyByID(id string) (secret *SecretKey, err error) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
 
 	// TODO may be use map
 	for _, itm := range x.keychain {
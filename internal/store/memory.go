@@ -0,0 +1,187 @@
+package store
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// sweepInterval expired keys and idle buckets are removed at most once per interval
+const sweepInterval = time.Minute
+
+type memoryItem struct {
+	value     string
+	expiresAt time.Time // zero never
+}
+
+func (x memoryItem) expired(now time.Time) bool {
+	return !x.expiresAt.IsZero() && !now.Before(x.expiresAt)
+}
+
+type memoryBucket struct {
+	limiter *rate.Limiter
+	seenAt  time.Time
+}
+
+// memoryStore state of this instance only, fallback without Redis
+type memoryStore struct {
+	mu        sync.Mutex
+	items     map[string]memoryItem
+	buckets   map[string]*memoryBucket
+	sweptAt   time.Time
+	listeners map[string][]chan string
+}
+
+func NewMemoryStore() Store {
+	return &memoryStore{
+		items:     map[string]memoryItem{},
+		buckets:   map[string]*memoryBucket{},
+		sweptAt:   time.Now(),
+		listeners: map[string][]chan string{},
+	}
+}
+
+// sweep under lock
+func (x *memoryStore) sweep(now time.Time) {
+
+	if now.Sub(x.sweptAt) < sweepInterval {
+		return
+	}
+	x.sweptAt = now
+
+	for k, v := range x.items {
+		if v.expired(now) {
+			delete(x.items, k)
+		}
+	}
+
+	for k, v := range x.buckets {
+		if v.idle(now) {
+			delete(x.buckets, k)
+		}
+	}
+}
+
+// idle bucket is full again, same as new one
+func (x *memoryBucket) idle(now time.Time) bool {
+	refill := float64(x.limiter.Burst()) / float64(x.limiter.Limit())
+	return now.Sub(x.seenAt).Seconds() > math.Max(refill, sweepInterval.Seconds())
+}
+
+func (x *memoryStore) Allow(key string, r float64, burst int) (bool, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	now := time.Now()
+	x.sweep(now)
+
+	bucket := x.buckets[key]
+	if bucket == nil || bucket.limiter.Limit() != rate.Limit(r) || bucket.limiter.Burst() != burst {
+		bucket = &memoryBucket{limiter: rate.NewLimiter(rate.Limit(r), burst)}
+		x.buckets[key] = bucket
+	}
+	bucket.seenAt = now
+
+	return bucket.limiter.AllowN(now, 1), nil
+}
+
+func (x *memoryStore) Set(key string, value string, ttl time.Duration) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	now := time.Now()
+	x.sweep(now)
+
+	x.items[key] = newMemoryItem(value, ttl, now)
+
+	return nil
+}
+
+func (x *memoryStore) SetNX(key string, value string, ttl time.Duration) (bool, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	now := time.Now()
+	x.sweep(now)
+
+	if v, ok := x.items[key]; ok && !v.expired(now) {
+		return false, nil
+	}
+
+	x.items[key] = newMemoryItem(value, ttl, now)
+
+	return true, nil
+}
+
+func (x *memoryStore) Get(key string) (string, bool, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	v, ok := x.items[key]
+	if !ok || v.expired(time.Now()) {
+		return "", false, nil
+	}
+
+	return v.value, true, nil
+}
+
+func newMemoryItem(value string, ttl time.Duration, now time.Time) memoryItem {
+	res := memoryItem{value: value}
+	if ttl > 0 {
+		res.expiresAt = now.Add(ttl)
+	}
+	return res
+}
+
+// Publish to subscribers of this instance, message is dropped for subscriber which is busy
+func (x *memoryStore) Publish(channel string, message string) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	for _, ch := range x.listeners[channel] {
+		select {
+		case ch <- message:
+		default:
+		}
+	}
+
+	return nil
+}
+
+func (x *memoryStore) Subscribe(ctx context.Context, channel string, handler func(message string)) {
+
+	ch := make(chan string, 16)
+
+	x.mu.Lock()
+	x.listeners[channel] = append(x.listeners[channel], ch)
+	x.mu.Unlock()
+
+	defer func() {
+		x.mu.Lock()
+		defer x.mu.Unlock()
+
+		list := x.listeners[channel]
+		for i, v := range list {
+			if v == ch {
+				x.listeners[channel] = append(list[:i:i], list[i+1:]...)
+				break
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case message := <-ch:
+			handler(message)
+		}
+	}
+}
+
+func (x *memoryStore) Shared() bool { return false }
+
+func (x *memoryStore) Close() error { return nil }
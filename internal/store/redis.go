@@ -0,0 +1,177 @@
+package store
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"go-auth-admin/internal/config"
+	xlog "go-auth-admin/internal/util/utillog"
+	"math"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// allowScript token bucket: tokens and refill time in hash, time of caller in ms,
+// key expires when bucket is full again
+var allowScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local v = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(v[1]) or burst
+local ts = tonumber(v[2]) or now
+if now > ts then
+	tokens = math.min(burst, tokens + (now - ts) * rate / 1000)
+end
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", tostring(math.max(now, ts)))
+redis.call("PEXPIRE", KEYS[1], ARGV[4])
+return allowed
+`)
+
+// redisStore state shared by replicas, keys and channels have prefix of app
+type redisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisStore connects to host:port, name is db number, fails if Redis is not reachable
+func NewRedisStore(cfg config.Database, prefix string) (Store, error) {
+
+	db := 0
+	if cfg.Name != "" {
+		var err error
+		if db, err = strconv.Atoi(cfg.Name); err != nil {
+			return nil, fmt.Errorf("error redis db number: %v", cfg.Name)
+		}
+	}
+
+	opts := &redis.Options{
+		Addr:     net.JoinHostPort(cfg.Host, cfg.Port),
+		Username: cfg.User,
+		Password: cfg.Password,
+		DB:       db,
+	}
+
+	if cfg.SSL {
+		opts.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12, ServerName: cfg.Host}
+	}
+
+	if cfg.MaxOpen > 0 {
+		opts.PoolSize = cfg.MaxOpen
+	}
+	if cfg.MaxIdle > 0 {
+		opts.MaxIdleConns = cfg.MaxIdle
+	}
+	if cfg.IdleTime > 0 {
+		opts.ConnMaxIdleTime = time.Duration(cfg.IdleTime) * time.Second
+	}
+
+	client := redis.NewClient(opts)
+
+	ctx, cancel := timeout()
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("error on redis %v: %v", opts.Addr, err)
+	}
+
+	xlog.Info("redis store connected: %v db %v", opts.Addr, db)
+
+	return &redisStore{client: client, prefix: prefix}, nil
+}
+
+func (x *redisStore) key(key string) string {
+	return x.prefix + ":" + key
+}
+
+func (x *redisStore) Allow(key string, rate float64, burst int) (bool, error) {
+
+	ctx, cancel := timeout()
+	defer cancel()
+
+	now := time.Now().UnixMilli()
+	ttl := int64(math.Ceil(float64(burst)*1000/rate)) + 1000
+
+	res, err := allowScript.Run(ctx, x.client, []string{x.key(key)},
+		rate, burst, now, ttl).Int()
+	if err != nil {
+		return false, err
+	}
+
+	return res == 1, nil
+}
+
+func (x *redisStore) Set(key string, value string, ttl time.Duration) error {
+
+	ctx, cancel := timeout()
+	defer cancel()
+
+	return x.client.Set(ctx, x.key(key), value, ttl).Err()
+}
+
+func (x *redisStore) SetNX(key string, value string, ttl time.Duration) (bool, error) {
+
+	ctx, cancel := timeout()
+	defer cancel()
+
+	return x.client.SetNX(ctx, x.key(key), value, ttl).Result()
+}
+
+func (x *redisStore) Get(key string) (string, bool, error) {
+
+	ctx, cancel := timeout()
+	defer cancel()
+
+	res, err := x.client.Get(ctx, x.key(key)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	return res, true, nil
+}
+
+func (x *redisStore) Publish(channel string, message string) error {
+
+	ctx, cancel := timeout()
+	defer cancel()
+
+	return x.client.Publish(ctx, x.key(channel), message).Err()
+}
+
+// Subscribe reconnects on connection loss, messages published meanwhile are lost
+func (x *redisStore) Subscribe(ctx context.Context, channel string, handler func(message string)) {
+
+	sub := x.client.Subscribe(ctx, x.key(channel))
+	defer func() { _ = sub.Close() }()
+
+	ch := sub.Channel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			handler(msg.Payload)
+		}
+	}
+}
+
+func (x *redisStore) Shared() bool { return true }
+
+func (x *redisStore) Close() error { return x.client.Close() }
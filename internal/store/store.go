@@ -0,0 +1,58 @@
+package store
+
+/*
+state shared by replicas: rate limit counters, revoked tokens,
+used passcodes, notifications; Redis or memory of one instance
+*/
+import (
+	"context"
+	"fmt"
+	"go-auth-admin/internal/config"
+	"time"
+)
+
+const (
+	DialectMemory = ""
+	DialectRedis  = "redis"
+)
+
+// opTimeout of one Redis command, callers do not pass context
+const opTimeout = 2 * time.Second
+
+type Store interface {
+	// Allow takes one token of bucket key, bucket holds burst tokens and refills rate per second
+	Allow(key string, rate float64, burst int) (bool, error)
+
+	Set(key string, value string, ttl time.Duration) error
+	// SetNX sets value if key not exists, false if exists, e.g. value used once
+	SetNX(key string, value string, ttl time.Duration) (bool, error)
+	// Get "" and false if key not exists or expired
+	Get(key string) (string, bool, error)
+
+	// Publish message to subscribers of channel in every replica
+	Publish(channel string, message string) error
+	// Subscribe calls handler for each message of channel until ctx is done
+	Subscribe(ctx context.Context, channel string, handler func(message string))
+
+	// Shared state is seen by other replicas
+	Shared() bool
+	Close() error
+}
+
+// New Redis store for redis dialect, memory store for empty dialect
+func New(cfg config.Database, prefix string) (Store, error) {
+
+	switch cfg.Dialect {
+	case DialectMemory:
+		return NewMemoryStore(), nil
+	case DialectRedis:
+		return NewRedisStore(cfg, prefix)
+	default:
+		return nil, fmt.Errorf("error unknown store dialect: %v", cfg.Dialect)
+	}
+}
+
+// timeout context of one command
+func timeout() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), opTimeout)
+}
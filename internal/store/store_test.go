@@ -0,0 +1,155 @@
+package store
+
+import (
+	"context"
+	"go-auth-admin/internal/config"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func newTestRedisStore(t *testing.T) (Store, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+
+	res, err := New(config.Database{
+		Dialect: DialectRedis,
+		Host:    mr.Host(),
+		Port:    mr.Port(),
+		Name:    "0",
+	}, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = res.Close() })
+
+	return res, mr
+}
+
+// testStores same tests for memory and Redis
+func testStores(t *testing.T, f func(t *testing.T, x Store)) {
+
+	t.Run("memory", func(t *testing.T) { f(t, NewMemoryStore()) })
+
+	t.Run("redis", func(t *testing.T) {
+		x, _ := newTestRedisStore(t)
+		f(t, x)
+	})
+}
+
+func TestStoreAllow(t *testing.T) {
+
+	testStores(t, func(t *testing.T, x Store) {
+
+		for i, want := range []bool{true, true, false} {
+			got, err := x.Allow("a", 0.01, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Errorf("Allow() #%v = %v, want %v", i, got, want)
+			}
+		}
+
+		if got, _ := x.Allow("b", 0.01, 2); !got {
+			t.Error("Allow() of other key = false, want true")
+		}
+	})
+}
+
+func TestStoreSetNX(t *testing.T) {
+
+	testStores(t, func(t *testing.T, x Store) {
+
+		if ok, err := x.SetNX("k", "1", time.Minute); err != nil || !ok {
+			t.Fatalf("SetNX() = %v, %v, want true", ok, err)
+		}
+		if ok, _ := x.SetNX("k", "2", time.Minute); ok {
+			t.Error("SetNX() of existing key = true, want false")
+		}
+
+		if v, ok, err := x.Get("k"); err != nil || !ok || v != "1" {
+			t.Errorf("Get() = %q, %v, %v, want 1", v, ok, err)
+		}
+
+		if err := x.Set("k", "3", time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		if v, _, _ := x.Get("k"); v != "3" {
+			t.Errorf("Get() = %q, want 3", v)
+		}
+
+		if _, ok, _ := x.Get("missing"); ok {
+			t.Error("Get() of missing key = true, want false")
+		}
+	})
+}
+
+func TestStoreExpire(t *testing.T) {
+
+	{
+		x := NewMemoryStore()
+		_ = x.Set("k", "1", time.Millisecond)
+		time.Sleep(5 * time.Millisecond)
+		if _, ok, _ := x.Get("k"); ok {
+			t.Error("memory Get() of expired key = true, want false")
+		}
+		if ok, _ := x.SetNX("k", "2", time.Minute); !ok {
+			t.Error("memory SetNX() of expired key = false, want true")
+		}
+	}
+
+	{
+		x, mr := newTestRedisStore(t)
+		_ = x.Set("k", "1", time.Second)
+		mr.FastForward(2 * time.Second)
+		if _, ok, _ := x.Get("k"); ok {
+			t.Error("redis Get() of expired key = true, want false")
+		}
+	}
+}
+
+func TestStorePublish(t *testing.T) {
+
+	testStores(t, func(t *testing.T, x Store) {
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		got := make(chan string, 1)
+		go x.Subscribe(ctx, "ch", func(message string) { got <- message })
+
+		// subscription is made in goroutine, publish until received
+		for deadline := time.Now().Add(2 * time.Second); ; {
+			if err := x.Publish("ch", "hello"); err != nil {
+				t.Fatal(err)
+			}
+
+			select {
+			case v := <-got:
+				if v != "hello" {
+					t.Errorf("message = %q, want hello", v)
+				}
+				return
+			case <-time.After(20 * time.Millisecond):
+			}
+
+			if time.Now().After(deadline) {
+				t.Fatal("message not received")
+			}
+		}
+	})
+}
+
+func TestNewRedisStoreUnreachable(t *testing.T) {
+
+	mr := miniredis.RunT(t)
+	host, port := mr.Host(), mr.Port()
+	mr.Close()
+
+	if _, err := New(config.Database{Dialect: DialectRedis, Host: host, Port: port}, "test"); err == nil {
+		t.Error("New() error = nil, want error for unreachable redis")
+	}
+}
@@ -24,11 +24,17 @@ type TokenPersist interface {
 	RotateAuthToken(forceRotate bool)
 }
 
+// ActorClaims who acts on behalf of token user (RFC 8693 act claim)
+type ActorClaims struct {
+	UserID string `json:"sub"`
+}
+
 type TokenClaimsDTO struct {
 	Tel    string           `json:"tel,omitempty"`
 	UserID string           `json:"user_id,omitempty"`
 	Email  string           `json:"email,omitempty"`
 	Scope  jwt.ClaimStrings `json:"scope,omitempty"` // as []string and as string
+	Act    *ActorClaims     `json:"act,omitempty"`   // set on impersonation
 	jwt.RegisteredClaims
 }
 
@@ -96,6 +102,12 @@ func (x TokenClaimsDTO) IsSignedIn() bool {
 	return x.IsValid() && x.UserID != ""
 }
 
+// IsImpersonated checks if token is used by actor on behalf of user
+func (x TokenClaimsDTO) IsImpersonated() bool {
+
+	return x.Act != nil && x.Act.UserID != ""
+}
+
 func (x TokenClaimsDTO) NeedRotation() bool {
 	now := time.Now().UTC().Unix() // now
 
@@ -103,6 +115,10 @@ func (x TokenClaimsDTO) NeedRotation() bool {
 }
 func (x TokenClaimsDTO) Rotate(forceRotate bool) *TokenClaimsDTO {
 
+	if x.IsImpersonated() {
+		return nil // short-lived, never extended
+	}
+
 	if x.IsValid() && (forceRotate || x.NeedRotation()) {
 
 		claimsNew := x // create a copy
@@ -1,12 +1,14 @@
 package utilaccess
 
 import (
+	"regexp"
 	"slices"
 	"strings"
 )
 
 const (
-	RoleAdmin = "admin"
+	RoleAdmin  = "admin"
+	RoleLocked = "auth_locked" // locked account, refused by policy deny
 )
 
 type PermissionsDTO struct {
@@ -15,6 +17,24 @@ type PermissionsDTO struct {
 	View   bool `json:"view,omitempty"`
 	Edit   bool `json:"edit,omitempty"`
 	Delete bool `json:"delete,omitempty"`
+
+	Fields map[string]FieldPermissionsDTO `json:"fields,omitempty"` // per field, e.g. email, roles
+}
+
+// FieldPermissionsDTO field hidden (masked) if no View, read-only if no Edit
+type FieldPermissionsDTO struct {
+	View bool `json:"view,omitempty"`
+	Edit bool `json:"edit,omitempty"`
+}
+
+// CanViewField unknown field is not visible
+func (x *PermissionsDTO) CanViewField(name string) bool {
+	return x.Fields[name].View
+}
+
+// CanEditField unknown field is not editable
+func (x *PermissionsDTO) CanEditField(name string) bool {
+	return x.Fields[name].Edit
 }
 
 func (x *PermissionsDTO) Fill(userRoles string, prefix string) {
@@ -51,44 +71,91 @@ func IsAdmin(role string) bool {
 	return role == RoleAdmin
 }
 
-// // HasAllRoles checks if the user has all the specified roles.
-// func HasAllRoles(userRoles string, roles ...string) bool {
+var reRoleCode = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// IsRoleCode checks role code format: lowercase letters, digits, underscore
+func IsRoleCode(code string) bool {
+	return reRoleCode.MatchString(code)
+}
+
+// NormalizeRoles space separated roles sorted and without duplicates, so equal sets compare equal
+func NormalizeRoles(roles string) string {
+	return strings.Join(slices.Compact(slices.Sorted(slices.Values(strings.Fields(roles)))), " ")
+}
+
+// ChangedRoles roles granted or revoked: in one of lists only
+func ChangedRoles(before []string, after []string) []string {
 
-// 	// If no roles are provided, return false
-// 	if userRoles == "" || len(roles) == 0 {
-// 		return false
-// 	}
+	res := []string{}
 
-// 	// Split the user's roles into a slice
-// 	userRolesArr := strings.Fields(userRoles)
+	for _, r := range before {
+		if !slices.Contains(after, r) {
+			res = append(res, r)
+		}
+	}
+	for _, r := range after {
+		if !slices.Contains(before, r) {
+			res = append(res, r)
+		}
+	}
 
-// 	for _, r := range userRolesArr {
-// 		if IsAdmin(r) {
-// 			return true
-// 		}
-// 	}
-// 	// Check if the user has all specified roles
+	slices.Sort(res)
 
-// 	for _, r := range roles {
-// 		hasRole := slices.Contains(userRolesArr, r)
-// 		if !hasRole { // !!! "!hasRole"
-// 			return false
-// 		}
-// 	}
+	return slices.Compact(res)
+}
 
-// 	return true // has all roles
-// }
+// NotHeldRoles roles the user does not hold, admin holds all
+func NotHeldRoles(userRoles []string, roles ...string) []string {
+
+	if slices.ContainsFunc(userRoles, IsAdmin) {
+		return nil
+	}
+
+	res := []string{}
+
+	for _, r := range roles {
+		if !slices.Contains(userRoles, r) {
+			res = append(res, r)
+		}
+	}
+
+	return res
+}
+
+// HasAllRoles checks if the user has all the specified roles.
+func HasAllRoles(userRoles []string, roles ...string) bool {
+
+	// If no roles are provided, return false
+	if len(userRoles) == 0 || len(roles) == 0 {
+		return false
+	}
+
+	for _, r := range userRoles {
+		if IsAdmin(r) {
+			return true
+		}
+	}
+	// Check if the user has all specified roles
+
+	for _, r := range roles {
+		hasRole := slices.Contains(userRoles, r)
+		if !hasRole { // !!! "!hasRole"
+			return false
+		}
+	}
+
+	return true // has all roles
+}
 
-// HasAnyOfRoles checks if the user has all the specified roles.
-func HasAnyOfRoles(userRoles string, roles ...string) bool {
+// HasAnyOfRoles checks if the user has any of the specified roles.
+func HasAnyOfRoles(userRoles []string, roles ...string) bool {
 
 	// If no roles are provided, return false
-	if userRoles == "" || len(roles) == 0 {
+	if len(userRoles) == 0 || len(roles) == 0 {
 		return false
 	}
 
-	// Split the user's roles into a slice
-	userRolesArr := strings.Fields(userRoles)
+	userRolesArr := userRoles
 
 	for _, r := range userRolesArr {
 		if IsAdmin(r) {
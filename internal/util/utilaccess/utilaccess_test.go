@@ -64,3 +64,136 @@ func TestPermissionsDTO(t *testing.T) {
 		})
 	}
 }
+
+func TestIsRoleCode(t *testing.T) {
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{"admin", true},
+		{"auth_edit", true},
+		{"auth_2fa", true},
+		{"", false},
+		{"Auth_edit", false},
+		{"auth edit", false},
+		{"_auth", false},
+		{"2fa", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			if got := IsRoleCode(tt.code); got != tt.want {
+				t.Errorf("IsRoleCode(%q) = %v, want %v", tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasAnyOfRoles(t *testing.T) {
+	tests := []struct {
+		name      string
+		userRoles []string
+		roles     []string
+		want      bool
+	}{
+		{"Empty user roles", nil, []string{"user_view"}, false},
+		{"Empty roles", []string{"user_view"}, nil, false},
+		{"Match", []string{"user_access", "user_view"}, []string{"user_edit", "user_view"}, true},
+		{"No match", []string{"user_access"}, []string{"user_edit"}, false},
+		{"Admin", []string{"admin"}, []string{"user_edit"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HasAnyOfRoles(tt.userRoles, tt.roles...); got != tt.want {
+				t.Errorf("HasAnyOfRoles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasAllRoles(t *testing.T) {
+	tests := []struct {
+		name      string
+		userRoles []string
+		roles     []string
+		want      bool
+	}{
+		{"Empty user roles", nil, []string{"user_view"}, false},
+		{"Empty roles", []string{"user_view"}, nil, false},
+		{"All", []string{"user_access", "user_view"}, []string{"user_access", "user_view"}, true},
+		{"Partial", []string{"user_view"}, []string{"user_edit", "user_view"}, false},
+		{"Admin", []string{"admin"}, []string{"user_edit", "user_view"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HasAllRoles(tt.userRoles, tt.roles...); got != tt.want {
+				t.Errorf("HasAllRoles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeRoles(t *testing.T) {
+	tests := []struct {
+		roles string
+		want  string
+	}{
+		{"", ""},
+		{" auth_view ", "auth_view"},
+		{"auth_view admin", "admin auth_view"},
+		{"auth_view  auth_edit auth_view", "auth_edit auth_view"},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeRoles(tt.roles); got != tt.want {
+			t.Errorf("NormalizeRoles(%q) = %q, want %q", tt.roles, got, tt.want)
+		}
+	}
+}
+
+func TestChangedRoles(t *testing.T) {
+	tests := []struct {
+		name   string
+		before []string
+		after  []string
+		want   []string
+	}{
+		{"Empty", nil, nil, []string{}},
+		{"Same", []string{"auth_view", "auth_edit"}, []string{"auth_edit", "auth_view"}, []string{}},
+		{"Grant", []string{"auth_view"}, []string{"auth_view", "admin"}, []string{"admin"}},
+		{"Revoke", []string{"auth_view", "auth_edit"}, []string{"auth_view"}, []string{"auth_edit"}},
+		{"Both", []string{"auth_view"}, []string{"auth_edit"}, []string{"auth_edit", "auth_view"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ChangedRoles(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ChangedRoles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotHeldRoles(t *testing.T) {
+	tests := []struct {
+		name      string
+		userRoles []string
+		roles     []string
+		want      []string
+	}{
+		{"Empty roles", []string{"auth_view"}, nil, []string{}},
+		{"Held", []string{"auth_view", "auth_edit"}, []string{"auth_edit"}, []string{}},
+		{"Not held", []string{"auth_view"}, []string{"auth_edit", "admin"}, []string{"auth_edit", "admin"}},
+		{"Admin", []string{"admin"}, []string{"auth_edit"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NotHeldRoles(tt.userRoles, tt.roles...); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NotHeldRoles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package utilconfig
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"go-auth-admin/internal/util/utilhttp"
@@ -9,10 +10,51 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
 )
 
+// Formats config file extensions in load order, file of later format overrides keys of earlier one
+var Formats = []string{".json", ".yaml", ".toml"}
+
 func LoadConfig(cfgPtr any, dir string, fileName string) error {
+	return loadConfig(cfgPtr, dir, fileName, false)
+}
+
+// LoadConfigFormats loads name.json, name.yaml, name.toml which exist in dir, in Formats order,
+// at least one is required, unknown keys are errors, remote dir has name.json only
+func LoadConfigFormats(cfgPtr any, dir string, name string) error {
+
+	if strings.HasPrefix(dir, "http") {
+		return loadConfig(cfgPtr, dir, name+".json", true)
+	}
+
+	found := false
+
+	for _, ext := range Formats {
+
+		if !HasFile(dir, name+ext) {
+			continue
+		}
+
+		found = true
+
+		if err := loadConfig(cfgPtr, dir, name+ext, true); err != nil {
+			return err
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("error no config file %v%v in: %v", name, strings.Join(Formats, "|"), dir)
+	}
+
+	return nil
+}
+
+func loadConfig(cfgPtr any, dir string, fileName string, strict bool) error {
 
 	xlog.Info("loading config from: %v", dir)
 
@@ -20,13 +62,13 @@ func LoadConfig(cfgPtr any, dir string, fileName string) error {
 
 	if isHTTP {
 
-		err := fromURL(cfgPtr, dir, fileName)
+		err := fromURL(cfgPtr, dir, fileName, strict)
 		if err != nil {
 			return err
 		}
 
 	} else {
-		err := fromFile(cfgPtr, dir, fileName)
+		err := fromFile(cfgPtr, dir, fileName, strict)
 		if err != nil {
 			return err
 		}
@@ -35,15 +77,27 @@ func LoadConfig(cfgPtr any, dir string, fileName string) error {
 	return nil
 }
 
+// HasFile checks if optional config file exists, remote dir is expected to have it
+func HasFile(dir string, fileName string) bool {
+
+	if strings.HasPrefix(dir, "http") {
+		return true
+	}
+
+	_, err := os.Stat(filepath.Join(dir, fileName))
+
+	return err == nil
+}
+
 // fromFile errIfNotExists argument soft binding, no error if file not exists
-func fromFile(cfgPtr any, dir string, file string) error {
+func fromFile(cfgPtr any, dir string, file string, strict bool) error {
 
 	if file == "" {
 		return nil
 	}
 
-	if !strings.HasSuffix(file, ".json") {
-		return fmt.Errorf("error file not match  *.json: %v", file)
+	if !slices.Contains(Formats, filepath.Ext(file)) {
+		return fmt.Errorf("error file not match *.json *.yaml *.toml: %v", file)
 	}
 
 	fullPath, err := filepath.Abs(filepath.Join(dir, file))
@@ -62,24 +116,24 @@ func fromFile(cfgPtr any, dir string, file string) error {
 
 	xlog.Info("loading config from file: %v", fullPath)
 
-	err = fromJSON(cfgPtr, string(data))
+	err = decode(cfgPtr, filepath.Ext(file), string(data), strict)
 
 	if err != nil {
-		return err
+		return fmt.Errorf("error with file %v: %v", fullPath, err)
 	}
 
 	return nil
 }
 
 // fromURL errIfNotExists argument soft binding, no error if file not exists
-func fromURL(cfgPtr any, dir string, file string) error {
+func fromURL(cfgPtr any, dir string, file string, strict bool) error {
 
 	if file == "" {
 		return nil
 	}
 
-	if !strings.HasSuffix(file, ".json") {
-		return fmt.Errorf("error file not match  *.json: %v", file)
+	if !slices.Contains(Formats, filepath.Ext(file)) {
+		return fmt.Errorf("error file not match *.json *.yaml *.toml: %v", file)
 	}
 
 	fullPath := dir + "/" + file
@@ -99,9 +153,9 @@ func fromURL(cfgPtr any, dir string, file string) error {
 
 	xlog.Info("loading config from file: %v", fullPath)
 
-	err = fromJSON(cfgPtr, string(data))
+	err = decode(cfgPtr, filepath.Ext(file), string(data), strict)
 	if err != nil {
-		return err
+		return fmt.Errorf("error with file %v: %v", fullPath, err)
 	}
 
 	return nil
@@ -141,7 +195,48 @@ func expandEnv(data string) string {
 
 }
 
-func fromJSON(cfgPtr any, data string) error {
+// decode expands env vars, yaml and toml are converted to json,
+// so json tags and override of present keys only work same for all formats
+func decode(cfgPtr any, ext string, data string, strict bool) error {
+
+	if ext == ".json" {
+		return fromJSON(cfgPtr, data, strict)
+	}
+
+	if strings.TrimSpace(data) == "" {
+		return nil
+	}
+
+	data = expandEnv(data)
+
+	var values map[string]any
+
+	switch ext {
+	case ".yaml":
+		if err := yaml.Unmarshal([]byte(data), &values); err != nil {
+			return err
+		}
+	case ".toml":
+		if _, err := toml.Decode(data, &values); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("error unknown config format: %v", ext)
+	}
+
+	if values == nil {
+		return nil // comments only
+	}
+
+	res, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+
+	return unmarshalJSON(res, cfgPtr, strict)
+}
+
+func fromJSON(cfgPtr any, data string, strict bool) error {
 
 	if data == "" {
 		return nil
@@ -149,7 +244,7 @@ func fromJSON(cfgPtr any, data string) error {
 
 	data = expandEnv(data)
 
-	err := json.Unmarshal([]byte(data), cfgPtr)
+	err := unmarshalJSON([]byte(data), cfgPtr, strict)
 
 	if err != nil {
 		return err
@@ -157,3 +252,24 @@ func fromJSON(cfgPtr any, data string) error {
 
 	return nil
 }
+
+// unmarshalJSON strict fails on unknown key, typo would silently keep default
+func unmarshalJSON(data []byte, cfgPtr any, strict bool) error {
+
+	if !strict {
+		return json.Unmarshal(data, cfgPtr)
+	}
+
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.DisallowUnknownFields()
+
+	if err := d.Decode(cfgPtr); err != nil {
+		return err
+	}
+
+	if d.More() {
+		return fmt.Errorf("error unexpected data after config object")
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package utilconfig
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type testConfigMod struct {
+	Env string `json:"env"`
+}
+
+type testConfig struct {
+	testConfigMod
+
+	Title  string   `json:"title"`
+	Langs  []string `json:"langs"`
+	Server struct {
+		Listen  string `json:"listen"`
+		Timeout int    `json:"timeout"`
+		Debug   bool   `json:"debug"`
+	} `json:"http_server"`
+}
+
+func writeTestFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestLoadConfigFormats(t *testing.T) {
+
+	t.Setenv("TEST_LISTEN", ":8080")
+
+	same := map[string]string{
+		".json": `{"env":"testing","title":"t","langs":["en","es"],"http_server":{"listen":"${TEST_LISTEN}","timeout":5,"debug":true}}`,
+		".yaml": "env: testing\ntitle: t\nlangs: [en, es]\nhttp_server:\n  listen: ${TEST_LISTEN}\n  timeout: 5\n  debug: true\n",
+		".toml": "env = \"testing\"\ntitle = \"t\"\nlangs = [\"en\", \"es\"]\n[http_server]\nlisten = \"${TEST_LISTEN}\"\ntimeout = 5\ndebug = true\n",
+	}
+
+	for ext, data := range same {
+		t.Run(ext, func(t *testing.T) {
+
+			dir := writeTestFiles(t, map[string]string{"config.testing" + ext: data})
+
+			res := testConfig{}
+			if err := LoadConfigFormats(&res, dir, "config.testing"); err != nil {
+				t.Fatal(err)
+			}
+
+			if res.Env != "testing" || res.Title != "t" || strings.Join(res.Langs, ",") != "en,es" ||
+				res.Server.Listen != ":8080" || res.Server.Timeout != 5 || !res.Server.Debug {
+				t.Errorf("config = %+v", res)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFormatsOverride(t *testing.T) {
+
+	dir := writeTestFiles(t, map[string]string{
+		"config.testing.json": `{"title":"json","http_server":{"listen":":1","timeout":1}}`,
+		"config.testing.yaml": "title: yaml\nhttp_server:\n  timeout: 2\n",
+		"config.testing.toml": "title = \"toml\"\n",
+	})
+
+	res := testConfig{}
+	res.Env = "default"
+
+	if err := LoadConfigFormats(&res, dir, "config.testing"); err != nil {
+		t.Fatal(err)
+	}
+
+	// json, then yaml, then toml, absent keys are kept
+	if res.Title != "toml" || res.Server.Listen != ":1" || res.Server.Timeout != 2 || res.Env != "default" {
+		t.Errorf("config = %+v", res)
+	}
+}
+
+func TestLoadConfigFormatsErrors(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{name: "none", files: map[string]string{}, want: "no config file"},
+		{name: "json unknown key", files: map[string]string{"config.testing.json": `{"titel":"x"}`}, want: "titel"},
+		{name: "yaml unknown key", files: map[string]string{"config.testing.yaml": "http_server:\n  lisen: x\n"}, want: "lisen"},
+		{name: "toml unknown key", files: map[string]string{"config.testing.toml": "[http_servr]\nlisten = \"x\"\n"}, want: "http_servr"},
+		{name: "yaml type", files: map[string]string{"config.testing.yaml": "http_server:\n  timeout: soon\n"}, want: "timeout"},
+		{name: "toml syntax", files: map[string]string{"config.testing.toml": "title = \n"}, want: "config.testing.toml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			dir := writeTestFiles(t, tt.files)
+
+			res := testConfig{}
+			err := LoadConfigFormats(&res, dir, "config.testing")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadConfigFormats() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigLenient(t *testing.T) {
+
+	dir := writeTestFiles(t, map[string]string{"policy.json": `{"title":"x","extra":1}`})
+
+	res := testConfig{}
+	if err := LoadConfig(&res, dir, "policy.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Title != "x" {
+		t.Errorf("title = %v, want x", res.Title)
+	}
+}
@@ -0,0 +1,63 @@
+package utilcrypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+)
+
+// sealKey derives 256-bit AES key of purpose from secret, same secret is used for other purposes
+func sealKey(secret []byte, purpose string) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(purpose))
+	return h.Sum(nil)
+}
+
+// Seal encrypts data with AES-GCM, result is base64 of nonce and ciphertext
+func Seal(secret []byte, purpose string, data []byte) (string, error) {
+
+	block, err := aes.NewCipher(sealKey(secret, purpose))
+	if err != nil {
+		return "", err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+
+	nonce, err := RandomCryptoArray(gcm.NonceSize())
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, data, nil)), nil
+}
+
+// Open decrypts result of Seal
+func Open(secret []byte, purpose string, sealed string) ([]byte, error) {
+
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(sealKey(secret, purpose))
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("error sealed data is too short")
+	}
+
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+}
@@ -0,0 +1,30 @@
+package utilcrypto
+
+import (
+	"testing"
+)
+
+func TestSealOpen(t *testing.T) {
+
+	secret := []byte("secret-key")
+
+	sealed, err := Seal(secret, "outbox", []byte("password123"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Open(secret, "outbox", sealed)
+	if err != nil || string(got) != "password123" {
+		t.Errorf("Open() = %q, %v, want password123", got, err)
+	}
+
+	if _, err := Open(secret, "other", sealed); err == nil {
+		t.Errorf("Open() of other purpose, want error")
+	}
+	if _, err := Open([]byte("other-key"), "outbox", sealed); err == nil {
+		t.Errorf("Open() with other secret, want error")
+	}
+	if _, err := Open(secret, "outbox", "AAAA"); err == nil {
+		t.Errorf("Open() of short data, want error")
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"crypto/rand"
 	"encoding/base32"
 	"encoding/base64"
+	"fmt"
+	"math/big"
 	"strings"
 
 	"golang.org/x/crypto/bcrypt"
@@ -75,3 +77,47 @@ func CompareHashAndPassword(hash, password string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
 }
+
+const (
+	passwordLower   = "abcdefghijkmnopqrstuvwxyz" // no l
+	passwordUpper   = "ABCDEFGHJKLMNPQRSTUVWXYZ"  // no I O
+	passwordDigits  = "23456789"                  // no 0 1
+	passwordSymbols = "!#%*+-=?@_"
+)
+
+// RandomPassword returns a password of n chars with at least one lowercase, uppercase, digit and symbol
+func RandomPassword(n int) (string, error) {
+
+	sets := []string{passwordLower, passwordUpper, passwordDigits, passwordSymbols}
+	all := strings.Join(sets, "")
+
+	if n < len(sets) {
+		return "", fmt.Errorf("password length must be at least %d", len(sets))
+	}
+
+	res := make([]byte, n)
+
+	for i := range res {
+		set := all
+		if i < len(sets) {
+			set = sets[i] // one char from each set
+		}
+
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(len(set))))
+		if err != nil {
+			return "", err
+		}
+		res[i] = set[j.Int64()]
+	}
+
+	// shuffle (Fisher-Yates)
+	for i := len(res) - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", err
+		}
+		res[i], res[j.Int64()] = res[j.Int64()], res[i]
+	}
+
+	return string(res), nil
+}
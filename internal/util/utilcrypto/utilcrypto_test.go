@@ -1,6 +1,9 @@
 package utilcrypto
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestHashPassword(t *testing.T) {
 	tests := []struct {
@@ -88,3 +91,48 @@ func TestCompareHashAndPassword(t *testing.T) {
 		})
 	}
 }
+
+func TestRandomPassword(t *testing.T) {
+	tests := []struct {
+		name    string
+		n       int
+		wantErr bool
+	}{
+		{
+			name:    "Default length",
+			n:       16,
+			wantErr: false,
+		},
+		{
+			name:    "Min length",
+			n:       4,
+			wantErr: false,
+		},
+		{
+			name:    "Too short",
+			n:       3,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RandomPassword(tt.n)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RandomPassword() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != tt.n {
+				t.Errorf("RandomPassword() len = %v, want %v", len(got), tt.n)
+			}
+			for _, set := range []string{passwordLower, passwordUpper, passwordDigits, passwordSymbols} {
+				if !strings.ContainsAny(got, set) {
+					t.Errorf("RandomPassword() = %v, want any of %v", got, set)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package utilhttp
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// OriginAny allows every origin
+const OriginAny = "*"
+
+// originLabels one or more host labels, * of origin pattern
+const originLabels = `[a-z0-9-]+(\.[a-z0-9-]+)*`
+
+// CheckOrigin scheme://host[:port] without path, host may have * for subdomains, e.g. https://*.example.com
+func CheckOrigin(origin string) error {
+
+	if origin == OriginAny {
+		return nil
+	}
+
+	u, err := url.Parse(strings.ReplaceAll(origin, "*", "x"))
+	if err != nil {
+		return err
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("scheme must be http or https: %v", origin)
+	}
+
+	if u.Host == "" || u.User != nil || u.Path != "" || u.RawQuery != "" || u.Fragment != "" || strings.HasSuffix(origin, "?") {
+		return fmt.Errorf("must be scheme://host[:port]: %v", origin)
+	}
+
+	if strings.Contains(u.Port(), "x") {
+		return fmt.Errorf("port can not be pattern: %v", origin)
+	}
+
+	return nil
+}
+
+// OriginMatcher exact origins and patterns of CheckOrigin, case insensitive
+func OriginMatcher(origins []string) (func(origin string) (bool, error), error) {
+
+	exact := map[string]bool{}
+	patterns := []*regexp.Regexp{}
+
+	for _, v := range origins {
+
+		if err := CheckOrigin(v); err != nil {
+			return nil, err
+		}
+
+		v = strings.ToLower(v)
+
+		if !strings.Contains(v, "*") || v == OriginAny {
+			exact[v] = true
+			continue
+		}
+
+		pattern := strings.ReplaceAll(regexp.QuoteMeta(v), `\*`, originLabels)
+		patterns = append(patterns, regexp.MustCompile("^"+pattern+"$"))
+	}
+
+	return func(origin string) (bool, error) {
+
+		origin = strings.ToLower(origin)
+
+		if exact[OriginAny] || exact[origin] {
+			return true, nil
+		}
+
+		for _, re := range patterns {
+			if re.MatchString(origin) {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	}, nil
+}
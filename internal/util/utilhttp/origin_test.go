@@ -0,0 +1,61 @@
+package utilhttp
+
+import "testing"
+
+func TestCheckOrigin(t *testing.T) {
+
+	for origin, valid := range map[string]bool{
+		"*":                              true,
+		"https://admin.example.com":      true,
+		"http://localhost:5173":          true,
+		"https://*.staging.example.com":  true,
+		"https://admin.example.com/":     false,
+		"https://admin.example.com/path": false,
+		"ftp://example.com":              false,
+		"admin.example.com":              false,
+		"https://user@example.com":       false,
+		"https://example.com:*":          false,
+		"https://example.com?":           false,
+	} {
+		if err := CheckOrigin(origin); (err == nil) != valid {
+			t.Errorf("CheckOrigin(%q) error = %v, want valid %v", origin, err, valid)
+		}
+	}
+}
+
+func TestOriginMatcher(t *testing.T) {
+
+	match, err := OriginMatcher([]string{"https://admin.example.com", "https://*.staging.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for origin, want := range map[string]bool{
+		"https://admin.example.com":             true,
+		"https://ADMIN.example.com":             true,
+		"https://a.staging.example.com":         true,
+		"https://a.b.staging.example.com":       true,
+		"https://staging.example.com":           false,
+		"http://admin.example.com":              false,
+		"https://admin.example.com:8443":        false,
+		"https://evil.com/.staging.example.com": false,
+		"https://evilstaging.example.com":       false,
+		"null":                                  false,
+	} {
+		if got, _ := match(origin); got != want {
+			t.Errorf("match(%q) = %v, want %v", origin, got, want)
+		}
+	}
+
+	any, err := OriginMatcher([]string{OriginAny})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := any("https://any.example.org"); !got {
+		t.Error("match of * = false, want true")
+	}
+
+	if _, err = OriginMatcher([]string{"https://example.com/"}); err == nil {
+		t.Error("OriginMatcher() error = nil, want error for path")
+	}
+}
@@ -242,3 +242,38 @@ func AppendURL(path string, args ...string) string {
 
 	return path
 }
+
+// PostBytes posts body as is, for callers which sign exact body bytes
+func PostBytes(baseURL string, headers map[string]string, contentType string, body []byte) ([]byte, error) {
+
+	req, err := http.NewRequest("POST", baseURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(`Content-Type`, contentType)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+
+	if err != nil {
+
+		return nil, fmt.Errorf("error sending request: %v", err)
+	}
+
+	defer resp.Body.Close()
+
+	// Read the response
+	res, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return res, fmt.Errorf("error on http resp check: %v", resp.StatusCode)
+	}
+
+	return res, err
+}
@@ -67,3 +67,28 @@ func IsEmail(str string) bool {
 
 	return strings.Contains(str, "@") || strings.Contains(str, ".")
 }
+
+// MaskEmail keeps first char of local part and domain, j***@example.com
+func MaskEmail(email string) string {
+
+	if email == "" {
+		return ""
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at < 1 {
+		return strings.Repeat("*", len(email))
+	}
+
+	return email[:1] + "***" + email[at:]
+}
+
+// MaskTel keeps first 3 and last 2 chars, +12*****89
+func MaskTel(tel string) string {
+
+	if len(tel) <= 5 {
+		return strings.Repeat("*", len(tel))
+	}
+
+	return tel[:3] + strings.Repeat("*", len(tel)-5) + tel[len(tel)-2:]
+}
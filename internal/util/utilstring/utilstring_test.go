@@ -0,0 +1,39 @@
+package utilstring
+
+import "testing"
+
+func TestMaskEmail(t *testing.T) {
+	tests := []struct {
+		email string
+		want  string
+	}{
+		{"", ""},
+		{"john.doe@example.com", "j***@example.com"},
+		{"a@b.c", "a***@b.c"},
+		{"@b.c", "****"},
+		{"nobody", "******"},
+	}
+
+	for _, tt := range tests {
+		if got := MaskEmail(tt.email); got != tt.want {
+			t.Errorf("MaskEmail(%q) = %q, want %q", tt.email, got, tt.want)
+		}
+	}
+}
+
+func TestMaskTel(t *testing.T) {
+	tests := []struct {
+		tel  string
+		want string
+	}{
+		{"", ""},
+		{"+1234", "*****"},
+		{"+123456789", "+12*****89"},
+	}
+
+	for _, tt := range tests {
+		if got := MaskTel(tt.tel); got != tt.want {
+			t.Errorf("MaskTel(%q) = %q, want %q", tt.tel, got, tt.want)
+		}
+	}
+}
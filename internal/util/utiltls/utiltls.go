@@ -0,0 +1,110 @@
+package utiltls
+
+import (
+	"crypto/tls"
+	"fmt"
+	xlog "go-auth-admin/internal/util/utillog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// CertFile, KeyFile names in cert dir, same as keys of kubernetes tls secret
+const (
+	CertFile = "tls.crt"
+	KeyFile  = "tls.key"
+)
+
+// CertReloader serves key pair of cert dir, changed files are loaded
+// on first handshake after CheckInterval, e.g. renewed by cert-manager
+type CertReloader struct {
+	dir           string
+	CheckInterval time.Duration
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	stamp     string
+	checkedAt time.Time
+}
+
+func NewCertReloader(dir string) (*CertReloader, error) {
+
+	res := &CertReloader{
+		dir:           dir,
+		CheckInterval: 10 * time.Second,
+	}
+
+	if err := res.reload(); err != nil {
+		return nil, err
+	}
+
+	res.checkedAt = time.Now()
+
+	return res, nil
+}
+
+// filesStamp sizes and mod times of cert and key files
+func (x *CertReloader) filesStamp() (string, error) {
+
+	res := ""
+
+	for _, name := range []string{CertFile, KeyFile} {
+		info, err := os.Stat(filepath.Join(x.dir, name))
+		if err != nil {
+			return "", err
+		}
+		res += fmt.Sprintf("%v:%v:%v;", name, info.Size(), info.ModTime().UnixNano())
+	}
+
+	return res, nil
+}
+
+// reload loads files if changed, current pair is kept on error, e.g. cert is written but key is not yet
+func (x *CertReloader) reload() error {
+
+	stamp, err := x.filesStamp()
+	if err != nil {
+		return err
+	}
+
+	if stamp == x.stamp {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(filepath.Join(x.dir, CertFile), filepath.Join(x.dir, KeyFile))
+	if err != nil {
+		return err
+	}
+
+	x.cert = &cert
+	x.stamp = stamp
+
+	xlog.Info("tls certificate loaded from: %v", x.dir)
+
+	return nil
+}
+
+func (x *CertReloader) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if time.Since(x.checkedAt) >= x.CheckInterval {
+		x.checkedAt = time.Now()
+
+		if err := x.reload(); err != nil {
+			xlog.Error("error on tls certificate reload: %v", err) // retried after CheckInterval
+		}
+	}
+
+	return x.cert, nil
+}
+
+// TLSConfig server config with reloaded certificate
+func (x *CertReloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: x.GetCertificate,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}
+}
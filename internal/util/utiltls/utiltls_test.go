@@ -0,0 +1,105 @@
+package utiltls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestCert self-signed pair for name in dir
+func writeTestCert(t *testing.T, dir string, name string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	if err = os.WriteFile(filepath.Join(dir, CertFile), certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, KeyFile), keyPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func certName(t *testing.T, x *CertReloader) string {
+	t.Helper()
+
+	cert, err := x.GetCertificate(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return leaf.Subject.CommonName
+}
+
+func TestCertReloader(t *testing.T) {
+
+	dir := t.TempDir()
+
+	if _, err := NewCertReloader(dir); err == nil {
+		t.Fatal("NewCertReloader() error = nil, want error for empty dir")
+	}
+
+	writeTestCert(t, dir, "one.example.com")
+
+	x, err := NewCertReloader(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x.CheckInterval = 0
+
+	if got := certName(t, x); got != "one.example.com" {
+		t.Errorf("cert = %v, want one.example.com", got)
+	}
+
+	if err = os.WriteFile(filepath.Join(dir, KeyFile), []byte("half written"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := certName(t, x); got != "one.example.com" {
+		t.Errorf("cert = %v, want current kept on bad files", got)
+	}
+
+	writeTestCert(t, dir, "two.example.com")
+
+	if got := certName(t, x); got != "two.example.com" {
+		t.Errorf("cert = %v, want two.example.com", got)
+	}
+}
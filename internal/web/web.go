@@ -8,9 +8,12 @@ validate ExpiresAt,Issuer,Audience
 */
 import (
 	"fmt"
+	"go-auth-admin/internal/config/consts"
+	"go-auth-admin/internal/policy"
 	"go-auth-admin/internal/service"
 	xtoken "go-auth-admin/internal/token"
 	"go-auth-admin/internal/util/utilhttp"
+	xlog "go-auth-admin/internal/util/utillog"
 	"net/http"
 	"strings"
 	"time"
@@ -23,7 +26,13 @@ import (
 )
 
 const (
-	JwtKey = "_auth" // string value "auth"
+	JwtKey      = "_auth"     // string value "auth"
+	JwtActorKey = "_auth_act" // actor token kept during impersonation
+
+	CsrfKey    = "_csrf" // cookie and context key
+	CsrfHeader = "X-CSRF-Token"
+
+	bearerPrefix = "Bearer "
 )
 
 func NewTokenPersist(c echo.Context, appService service.AppService) xtoken.TokenPersist {
@@ -84,24 +93,62 @@ func assetsReqSkipper(c echo.Context) bool {
 	return false
 }
 
-// func CsrfMiddleware(appService service.AppService) echo.MiddlewareFunc {
+// CsrfMiddleware double submit token of unsafe methods under /auth-admin/api,
+// token of _csrf cookie is sent back in X-CSRF-Token header, client reads it from status api;
+// bearer token is not sent by browser on its own, such requests are not checked
+func CsrfMiddleware(_ service.AppService) echo.MiddlewareFunc {
+
+	csrfConfig := middleware.CSRFConfig{
+		Skipper: csrfSkipper,
+
+		TokenLookup: "header:" + CsrfHeader,
+		CookiePath:  consts.PathAuthAdmin,
+		// CookieDomain:   "example.com",
+		CookieHTTPOnly: true,
+		CookieName:     CsrfKey,
+		ContextKey:     CsrfKey,
+		CookieSameSite: http.SameSiteDefaultMode,
+	}
 
-// 	csrfConfig := middleware.CSRFConfig{
-// 		Skipper: assetsReqSkipper,
+	plain := middleware.CSRFWithConfig(csrfConfig)
 
-// 		TokenLookup: "header:X-CSRF-Token,form:_csrf",
-// 		CookiePath:  "/",
-// 		// CookieDomain:   "example.com",
-// 		// CookieSecure:   true, // https only
-// 		CookieHTTPOnly: true,
-// 		CookieName:     "_csrf",
-// 		ContextKey:     "_csrf",
-// 		CookieSameSite: http.SameSiteDefaultMode,
-// 	}
+	csrfConfig.CookieSecure = true
+	secure := middleware.CSRFWithConfig(csrfConfig)
 
-// 	return middleware.CSRFWithConfig(csrfConfig)
+	// Secure same as auth token cookie
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		plainNext, secureNext := plain(next), secure(next)
 
-// }
+		return func(c echo.Context) error {
+			if IsSecureCookie(c) {
+				return secureNext(c)
+			}
+			return plainNext(c)
+		}
+	}
+}
+
+// IsSecureCookie cookies are https-only if request is https, on server or proxy (X-Forwarded-Proto),
+// over plain http browser would not send Secure cookie back
+func IsSecureCookie(c echo.Context) bool {
+	return c.Scheme() == "https"
+}
+
+func csrfSkipper(c echo.Context) bool {
+	return !strings.HasPrefix(c.Request().URL.Path, consts.PathAuthAdminAPI+"/") || HasBearerToken(c)
+}
+
+// HasAuthCookie request is authenticated by _auth cookie, cookie is ignored with bearer header
+func HasAuthCookie(c echo.Context) bool {
+	cookie, err := c.Cookie(JwtKey)
+	return err == nil && cookie.Value != "" && !HasBearerToken(c)
+}
+
+// HasBearerToken request has Authorization: Bearer header
+func HasBearerToken(c echo.Context) bool {
+	auth := c.Request().Header.Get(echo.HeaderAuthorization)
+	return len(auth) > len(bearerPrefix) && strings.EqualFold(auth[:len(bearerPrefix)], bearerPrefix)
+}
 func UserLangMiddleware(appService service.AppService) echo.MiddlewareFunc {
 
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -142,6 +189,27 @@ func UserLangMiddleware(appService service.AppService) echo.MiddlewareFunc {
 	}
 }
 
+// authTokenExtractor bearer of api clients, _auth cookie only without bearer header,
+// so request skipped by csrf is never authenticated by cookie
+func authTokenExtractor() middleware.ValuesExtractor {
+
+	header, err := echojwt.CreateExtractors("header:" + echo.HeaderAuthorization + ":" + bearerPrefix)
+	if err != nil {
+		panic(err)
+	}
+	cookie, err := echojwt.CreateExtractors("cookie:" + JwtKey)
+	if err != nil {
+		panic(err)
+	}
+
+	return func(c echo.Context) ([]string, error) {
+		if HasBearerToken(c) {
+			return header[0](c)
+		}
+		return cookie[0](c)
+	}
+}
+
 func TokenParserMiddleware(appService service.AppService) echo.MiddlewareFunc {
 
 	vaultKeyScopeAuth := appService.Vault().KeyScopeAuth()
@@ -165,10 +233,10 @@ func TokenParserMiddleware(appService service.AppService) echo.MiddlewareFunc {
 
 			return xtoken.JwtSecretSearch(t, vaultKeyScopeAuth)
 		},
-		SuccessHandler:         jwtParseSuccessHandler,
+		SuccessHandler:         jwtParseSuccessHandler(appService),
 		ErrorHandler:           jwtParseErrorHandler,
 		ContinueOnIgnoredError: true,
-		TokenLookup:            "cookie:" + JwtKey,
+		TokenLookupFuncs:       []middleware.ValuesExtractor{authTokenExtractor()},
 		NewClaimsFunc: func(c echo.Context) jwt.Claims {
 			return new(xtoken.TokenClaimsDTO)
 		},
@@ -211,6 +279,7 @@ type AuthorizeMiddlewareConfig struct {
 	Service      service.AppService
 	ReddirectURL string
 	IfAnyOfRoles func(c echo.Context) []string
+	IfPolicy     func(c echo.Context) *policy.Rule // route rule, nil forbids
 	AdminRole    bool
 }
 
@@ -267,7 +336,7 @@ func AuthorizeMiddlewareWithConfig(cfg AuthorizeMiddlewareConfig) echo.Middlewar
 					return err
 				}
 				//
-				success := acc != nil && acc.HasAnyOfRoles(roles...)
+				success := acc != nil && acc.HasAnyOfRoles(roles...) // effective: direct and group roles
 				if success {
 					// ok
 				} else {
@@ -276,6 +345,33 @@ func AuthorizeMiddlewareWithConfig(cfg AuthorizeMiddlewareConfig) echo.Middlewar
 
 			}
 
+			if cfg.IfPolicy != nil {
+
+				rule := cfg.IfPolicy(c)
+				if rule == nil {
+					return c.NoContent(http.StatusForbidden) // 403
+				}
+
+				acc, err := GetAccount(c, cfg.Service)
+				if err != nil {
+					return err
+				}
+
+				var roles []string
+				if acc != nil {
+					roles = acc.EffectiveRoleCodes() // direct and group roles
+				}
+
+				if !rule.Allow(roles) {
+					return c.NoContent(http.StatusForbidden) // 403
+				}
+
+				if claims := AuthTokenClaims(c); !rule.Public && claims != nil && claims.IsImpersonated() {
+					return c.NoContent(http.StatusForbidden) // 403, impersonated session is not for admin work
+				}
+
+			}
+
 			return next(c)
 		}
 	}
@@ -297,13 +393,26 @@ func TokenRotateMiddleware(appService service.AppService) echo.MiddlewareFunc {
 	}
 }
 
-func jwtParseSuccessHandler(c echo.Context) {
+// jwtParseSuccessHandler revoked token is dropped, request is not signed in,
+// token that cannot be checked (store is unavailable) is dropped too
+func jwtParseSuccessHandler(appService service.AppService) func(c echo.Context) {
 
-	// user, _ := c.Get(JwtKey).(*jwt.Token)
-	// claims, _ := user.Claims.(*xtoken.TokenClaimsDTO)
+	return func(c echo.Context) {
 
-	//
+		claims := AuthTokenClaims(c)
+		if claims == nil {
+			return
+		}
 
+		revoked, err := appService.TokenRevocation().IsRevoked(claims)
+		if err != nil {
+			xlog.Error("error on token revocation check: %v", err)
+		}
+
+		if revoked || err != nil {
+			c.Set(JwtKey, nil)
+		}
+	}
 }
 
 func jwtParseErrorHandler(c echo.Context, err error) error {
@@ -378,7 +487,7 @@ func CreateAuthTokenWithClaims(c echo.Context, claims *xtoken.TokenClaimsDTO, se
 
 	{
 
-		cookie := newTokenCookie(JwtKey) // cookie with flag https-only
+		cookie := newTokenCookie(c, JwtKey) // cookie with flag https-only on https
 		cookie.Value = tokenString
 		cookie.Expires = claims.ExpiresAt.Time // get lifetime from claims
 		// cookie.MaxAge = int(lifetime)
@@ -389,13 +498,73 @@ func CreateAuthTokenWithClaims(c echo.Context, claims *xtoken.TokenClaimsDTO, se
 	return nil
 }
 
-func newTokenCookie(name string) *http.Cookie {
+// StartImpersonation keeps actor token aside and sets token of impersonated user
+func StartImpersonation(c echo.Context, claims *xtoken.TokenClaimsDTO, secretSourceAuth xtoken.SecretSourceCurrent) error {
+
+	actor := AuthTokenClaims(c)
+
+	if actor == nil || !HasAuthCookie(c) {
+		return fmt.Errorf("error actor token not found")
+	}
+
+	current, _ := c.Cookie(JwtKey)
+
+	cookie := newTokenCookie(c, JwtActorKey)
+	cookie.Value = current.Value
+	cookie.Expires = actor.ExpiresAt.Time
+	c.SetCookie(cookie)
+
+	return CreateAuthTokenWithClaims(c, claims, secretSourceAuth)
+}
+
+// EndImpersonation restores actor token kept by StartImpersonation, returns actor claims
+func EndImpersonation(c echo.Context, secretSourceByID xtoken.SecretSourceByID) (*xtoken.TokenClaimsDTO, error) {
+
+	claims := AuthTokenClaims(c)
+	if claims == nil || !claims.IsImpersonated() {
+		return nil, fmt.Errorf("error token is not impersonated")
+	}
+
+	defer DeleteActorToken(c)
+
+	saved, err := c.Cookie(JwtActorKey)
+	if err != nil || saved.Value == "" {
+		return nil, fmt.Errorf("error actor token not found")
+	}
+
+	actor, err := xtoken.ParseToken(saved.Value, secretSourceByID)
+	if err != nil {
+		return nil, err
+	}
+
+	if actor == nil || actor.IsImpersonated() || actor.UserID != claims.Act.UserID {
+		return nil, fmt.Errorf("error actor token expired or not match")
+	}
+
+	cookie := newTokenCookie(c, JwtKey)
+	cookie.Value = saved.Value
+	cookie.Expires = actor.ExpiresAt.Time
+	c.SetCookie(cookie)
+
+	return actor, nil
+}
+
+func DeleteActorToken(c echo.Context) {
+
+	cookie := newTokenCookie(c, JwtActorKey)
+	cookie.MaxAge = -1
+	cookie.Expires = time.Unix(0, 0)
+	c.SetCookie(cookie)
+
+}
+
+func newTokenCookie(c echo.Context, name string) *http.Cookie {
 
 	cookie := new(http.Cookie)
 	cookie.Name = name
 	cookie.MaxAge = 0
-	cookie.HttpOnly = true // Prevent JavaScript from accessing the cookie
-	cookie.Secure = true   // Set to true in production when using HTTPS
+	cookie.HttpOnly = true            // Prevent JavaScript from accessing the cookie
+	cookie.Secure = IsSecureCookie(c) // https-only when served by https
 	cookie.Path = "/"
 	// cookie.SameSite = http.SameSiteStrictMode // strict mode same-to-same-only // default is manage by brawser
 	return cookie
@@ -403,7 +572,7 @@ func newTokenCookie(name string) *http.Cookie {
 
 func DeleteAuthToken(c echo.Context) {
 
-	cookie := newTokenCookie(JwtKey)
+	cookie := newTokenCookie(c, JwtKey)
 	cookie.MaxAge = -1
 	cookie.Expires = time.Unix(0, 0)
 	c.SetCookie(cookie)
@@ -0,0 +1,210 @@
+// Package msgsign signs requests of auth admin messenger and verifies them on the receiving service.
+//
+// Signature is HMAC-SHA256 of method, path with query, unix timestamp, random nonce and
+// SHA-256 of body, keyed by DeriveKey of vault auth key. Receiver rejects unknown keys,
+// changed requests, timestamps out of MaxSkew and nonces seen before.
+package msgsign
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// request headers
+const (
+	HeaderKeyID     = "X-Signature-Key"       // vault key id
+	HeaderTimestamp = "X-Signature-Timestamp" // unix seconds
+	HeaderNonce     = "X-Signature-Nonce"     // random, once per request
+	HeaderSignature = "X-Signature"           // hex
+)
+
+// keyPurpose separates messenger key from other uses of vault key
+const keyPurpose = "messenger"
+
+// DefaultMaxSkew accepted difference of request timestamp and receiver clock
+const DefaultMaxSkew = 5 * time.Minute
+
+// DefaultMaxBodySize of signed request, messenger form values are small
+const DefaultMaxBodySize = 1 << 20 // 1 MiB
+
+var (
+	ErrNoSignature  = errors.New("error request is not signed")
+	ErrUnknownKey   = errors.New("error unknown signature key")
+	ErrExpired      = errors.New("error signature timestamp out of range")
+	ErrBadSignature = errors.New("error signature mismatch")
+	ErrReplayed     = errors.New("error signature nonce used before")
+)
+
+// DeriveKey messenger key of vault auth key, receiver may keep derived key only
+func DeriveKey(authKey []byte) []byte {
+	h := hmac.New(sha256.New, authKey)
+	h.Write([]byte(keyPurpose))
+	return h.Sum(nil)
+}
+
+// Signature hex HMAC of request parts, uri is path with query
+func Signature(key []byte, method string, uri string, timestamp string, nonce string, body []byte) string {
+
+	bodyHash := sha256.Sum256(body)
+
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(strings.Join([]string{strings.ToUpper(method), uri, timestamp, nonce, hex.EncodeToString(bodyHash[:])}, "\n")))
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Headers signature headers of request, uri is path with query
+func Headers(keyID string, key []byte, method string, uri string, body []byte, now time.Time) (map[string]string, error) {
+
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	nonce := hex.EncodeToString(random)
+
+	return map[string]string{
+		HeaderKeyID:     keyID,
+		HeaderTimestamp: timestamp,
+		HeaderNonce:     nonce,
+		HeaderSignature: Signature(key, method, uri, timestamp, nonce, body),
+	}, nil
+}
+
+// Verifier checks signed requests
+type Verifier struct {
+	Keys        func(keyID string) ([]byte, bool) // derived key by id
+	MaxSkew     time.Duration                     // DefaultMaxSkew if zero
+	MaxBodySize int64                             // DefaultMaxBodySize if zero
+
+	mu     sync.Mutex
+	seen   map[string]time.Time // nonce -> expiry
+	pruned time.Time            // last removal of expired nonces
+	now    func() time.Time
+}
+
+// NewVerifier verifier of derived keys by id
+func NewVerifier(keys map[string][]byte) *Verifier {
+	return &Verifier{
+		Keys: func(keyID string) ([]byte, bool) {
+			key, ok := keys[keyID]
+			return key, ok
+		},
+	}
+}
+
+func (x *Verifier) clock() time.Time {
+	if x.now != nil {
+		return x.now()
+	}
+	return time.Now()
+}
+
+// Verify checks request signature, body is read and restored for next handlers
+func (x *Verifier) Verify(r *http.Request) error {
+
+	keyID, timestamp, nonce, signature := r.Header.Get(HeaderKeyID), r.Header.Get(HeaderTimestamp),
+		r.Header.Get(HeaderNonce), r.Header.Get(HeaderSignature)
+
+	if keyID == "" || timestamp == "" || nonce == "" || signature == "" {
+		return ErrNoSignature
+	}
+
+	key, ok := x.Keys(keyID)
+	if !ok {
+		return ErrUnknownKey
+	}
+
+	maxSkew := x.MaxSkew
+	if maxSkew <= 0 {
+		maxSkew = DefaultMaxSkew
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrExpired
+	}
+
+	now := x.clock()
+	signedAt := time.Unix(unix, 0)
+
+	if signedAt.Before(now.Add(-maxSkew)) || signedAt.After(now.Add(maxSkew)) {
+		return ErrExpired
+	}
+
+	maxBodySize := x.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = DefaultMaxBodySize
+	}
+
+	var body []byte
+	if r.Body != nil {
+		if body, err = io.ReadAll(http.MaxBytesReader(nil, r.Body, maxBodySize)); err != nil {
+			return err
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	want := Signature(key, r.Method, r.URL.RequestURI(), timestamp, nonce, body)
+
+	if !hmac.Equal([]byte(want), []byte(strings.ToLower(signature))) {
+		return ErrBadSignature
+	}
+
+	return x.remember(nonce, signedAt.Add(maxSkew), now, maxSkew)
+}
+
+// remember rejects nonce seen before, kept until timestamp is out of range,
+// expired nonces are removed once per maxSkew, not on each request
+func (x *Verifier) remember(nonce string, expiry time.Time, now time.Time, maxSkew time.Duration) error {
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if x.seen == nil {
+		x.seen = map[string]time.Time{}
+	}
+
+	if now.Sub(x.pruned) >= maxSkew {
+		for k, v := range x.seen {
+			if !v.After(now) {
+				delete(x.seen, k)
+			}
+		}
+		x.pruned = now
+	}
+
+	if v, ok := x.seen[nonce]; ok && v.After(now) {
+		return ErrReplayed
+	}
+
+	x.seen[nonce] = expiry
+
+	return nil
+}
+
+// Middleware responds 401 to requests which fail Verify, 413 to body over MaxBodySize
+func (x *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := x.Verify(r); err != nil {
+			status := http.StatusUnauthorized
+			if tooLarge := (*http.MaxBytesError)(nil); errors.As(err, &tooLarge) {
+				status = http.StatusRequestEntityTooLarge
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
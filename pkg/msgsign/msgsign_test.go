@@ -0,0 +1,140 @@
+package msgsign
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func signedRequest(t *testing.T, key []byte, body string, now time.Time) *http.Request {
+	t.Helper()
+
+	r := httptest.NewRequest(http.MethodPost, "/sys/api/messenger/sms-passcode?x=1", strings.NewReader(body))
+
+	headers, err := Headers("k1", key, r.Method, r.URL.RequestURI(), []byte(body), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+
+	return r
+}
+
+func TestVerify(t *testing.T) {
+
+	key := DeriveKey([]byte("vault-auth-key"))
+	now := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name    string
+		request func() *http.Request
+		want    error
+	}{
+		{"valid", func() *http.Request {
+			return signedRequest(t, key, "passcode=1234", now)
+		}, nil},
+		{"not signed", func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/", nil)
+		}, ErrNoSignature},
+		{"unknown key", func() *http.Request {
+			r := signedRequest(t, key, "passcode=1234", now)
+			r.Header.Set(HeaderKeyID, "k2")
+			return r
+		}, ErrUnknownKey},
+		{"tampered body", func() *http.Request {
+			r := signedRequest(t, key, "passcode=1234", now)
+			r.Body = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("passcode=9999")).Body
+			return r
+		}, ErrBadSignature},
+		{"tampered path", func() *http.Request {
+			r := signedRequest(t, key, "passcode=1234", now)
+			r.URL.Path = "/sys/api/messenger/email-passcode"
+			return r
+		}, ErrBadSignature},
+		{"other key", func() *http.Request {
+			return signedRequest(t, DeriveKey([]byte("other")), "passcode=1234", now)
+		}, ErrBadSignature},
+		{"old", func() *http.Request {
+			return signedRequest(t, key, "passcode=1234", now.Add(-DefaultMaxSkew-time.Second))
+		}, ErrExpired},
+		{"future", func() *http.Request {
+			return signedRequest(t, key, "passcode=1234", now.Add(DefaultMaxSkew+time.Second))
+		}, ErrExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			verifier := NewVerifier(map[string][]byte{"k1": key})
+			verifier.now = func() time.Time { return now }
+
+			if err := verifier.Verify(tt.request()); !errors.Is(err, tt.want) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyReplay(t *testing.T) {
+
+	key := DeriveKey([]byte("vault-auth-key"))
+	now := time.Unix(1700000000, 0)
+
+	verifier := NewVerifier(map[string][]byte{"k1": key})
+	verifier.now = func() time.Time { return now }
+
+	r := signedRequest(t, key, "passcode=1234", now)
+
+	replay := httptest.NewRequest(http.MethodPost, r.URL.String(), strings.NewReader("passcode=1234"))
+	replay.Header = r.Header.Clone()
+
+	if err := verifier.Verify(r); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifier.Verify(replay); !errors.Is(err, ErrReplayed) {
+		t.Errorf("Verify() of replay error = %v, want %v", err, ErrReplayed)
+	}
+
+	// same body, new request
+	if err := verifier.Verify(signedRequest(t, key, "passcode=1234", now)); err != nil {
+		t.Errorf("Verify() of resend error = %v, want nil", err)
+	}
+
+	// nonce is forgotten when timestamp is out of range anyway
+	now = now.Add(DefaultMaxSkew + time.Second)
+	if err := verifier.Verify(replay); !errors.Is(err, ErrExpired) {
+		t.Errorf("Verify() of late replay error = %v, want %v", err, ErrExpired)
+	}
+	if err := verifier.Verify(signedRequest(t, key, "passcode=1234", now)); err != nil || len(verifier.seen) != 1 {
+		t.Errorf("Verify() error = %v seen = %v, want expired nonces removed", err, len(verifier.seen))
+	}
+}
+
+func TestVerifyBodySize(t *testing.T) {
+
+	key := DeriveKey([]byte("vault-auth-key"))
+	now := time.Unix(1700000000, 0)
+
+	verifier := NewVerifier(map[string][]byte{"k1": key})
+	verifier.MaxBodySize = 16
+	verifier.now = func() time.Time { return now }
+
+	if err := verifier.Verify(signedRequest(t, key, "passcode=1234", now)); err != nil {
+		t.Errorf("Verify() error = %v, want nil", err)
+	}
+
+	handler := verifier.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, signedRequest(t, key, "passcode=1234&link="+strings.Repeat("x", 64), now))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %v, want %v", w.Code, http.StatusRequestEntityTooLarge)
+	}
+	if len(verifier.seen) != 1 {
+		t.Errorf("seen = %v, want nonce of rejected body not remembered", len(verifier.seen))
+	}
+}